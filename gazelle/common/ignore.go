@@ -0,0 +1,99 @@
+package gazelle
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"strings"
+
+	"aspect.build/cli/gazelle/common/git"
+	BazelLog "aspect.build/cli/pkg/logger"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// The gazelle directive excluding files and directories from the walk.
+const Directive_Exclude = "exclude"
+
+// Internal
+const excludesExt = "__aspect:excludes"
+const bazelignoreExt = "__aspect:bazelignore"
+
+// CollectExcludes records the `exclude` directives of the BUILD file of a package,
+// inherited by sub-packages, so paths excluded from the gazelle walk can also be
+// excluded when languages read files from disk.
+func CollectExcludes(c *config.Config, rel string, f *rule.File) {
+	if _, loaded := c.Exts[bazelignoreExt]; !loaded {
+		c.Exts[bazelignoreExt] = readBazelignore(c.RepoRoot)
+	}
+
+	if f == nil {
+		return
+	}
+
+	var excludes []string
+	for _, d := range f.Directives {
+		if d.Key == Directive_Exclude {
+			if excludes == nil {
+				// Copy the parent excludes before modifying.
+				parentExcludes, _ := c.Exts[excludesExt].([]string)
+				excludes = append([]string{}, parentExcludes...)
+			}
+			excludes = append(excludes, path.Join(rel, strings.TrimPrefix(strings.TrimSpace(d.Value), "/")))
+		}
+	}
+
+	if excludes != nil {
+		c.Exts[excludesExt] = excludes
+	}
+}
+
+// IsIgnored returns whether the workspace relative path is excluded by an `exclude`
+// directive, the .bazelignore file or a .gitignore file when gitignore is enabled.
+func IsIgnored(c *config.Config, p string) bool {
+	excludes, _ := c.Exts[excludesExt].([]string)
+	for _, exclude := range excludes {
+		if matched, _ := doublestar.Match(exclude, p); matched {
+			return true
+		}
+	}
+
+	ignoredDirs, _ := c.Exts[bazelignoreExt].([]string)
+	for _, dir := range ignoredDirs {
+		if p == dir || strings.HasPrefix(p, dir+"/") {
+			return true
+		}
+	}
+
+	if isGitIgnored, hasGitIgnore := c.Exts[git.ASPECT_GITIGNORE].(func(string) bool); hasGitIgnore && isGitIgnored != nil {
+		return isGitIgnored(p)
+	}
+
+	return false
+}
+
+// The directories listed in the .bazelignore file of the workspace.
+func readBazelignore(repoRoot string) []string {
+	dirs := make([]string, 0)
+
+	ignoreFile, err := os.Open(path.Join(repoRoot, ".bazelignore"))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			BazelLog.Errorf("Failed to open .bazelignore: %v", err)
+		}
+		return dirs
+	}
+	defer ignoreFile.Close()
+
+	scanner := bufio.NewScanner(ignoreFile)
+	for scanner.Scan() {
+		dir := strings.TrimSpace(scanner.Text())
+		if dir == "" || strings.HasPrefix(dir, "#") {
+			continue
+		}
+		dirs = append(dirs, path.Clean(strings.TrimPrefix(dir, "./")))
+	}
+
+	return dirs
+}
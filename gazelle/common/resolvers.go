@@ -0,0 +1,48 @@
+package gazelle
+
+import (
+	"sync"
+)
+
+// The kind of the rules_jvm Maven resolver of a maven_install.json file.
+const RulesJvmMavenResolverKind = "rules_jvm_maven"
+
+// The key of a shared resolver, such as the Maven resolver of a
+// maven_install.json file.
+type sharedResolverKey struct {
+	kind string
+	key  string
+}
+
+// A shared resolver, created once.
+type sharedResolver struct {
+	once     sync.Once
+	resolver interface{}
+	err      error
+}
+
+// The resolvers shared within the process.
+var sharedResolvers = make(map[sharedResolverKey]*sharedResolver)
+var sharedResolversLock sync.Mutex
+
+// GetSharedResolver returns the resolver of the kind for the key, such as the path of a
+// maven_install.json file, created once using create by the first caller requesting it.
+// Only the lookups of the kotlin extension go through the registry, deduplicating the
+// resolvers of a lockfile across kotlin directories and runs within the same process.
+// The rules_jvm java extension creates its own resolvers and does not share them.
+// Errors creating the resolver are returned to every caller requesting it.
+func GetSharedResolver(kind, key string, create func() (interface{}, error)) (interface{}, error) {
+	sharedResolversLock.Lock()
+	shared, exists := sharedResolvers[sharedResolverKey{kind: kind, key: key}]
+	if !exists {
+		shared = &sharedResolver{}
+		sharedResolvers[sharedResolverKey{kind: kind, key: key}] = shared
+	}
+	sharedResolversLock.Unlock()
+
+	shared.once.Do(func() {
+		shared.resolver, shared.err = create()
+	})
+
+	return shared.resolver, shared.err
+}
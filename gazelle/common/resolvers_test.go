@@ -0,0 +1,31 @@
+package gazelle
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGetSharedResolver(t *testing.T) {
+	created := 0
+	create := func() (interface{}, error) {
+		created++
+		return &created, nil
+	}
+
+	a, _ := GetSharedResolver("test", "maven_install.json", create)
+	b, _ := GetSharedResolver("test", "maven_install.json", create)
+	if a != b || created != 1 {
+		t.Errorf("expected the resolver to be created once and shared, created %d times", created)
+	}
+
+	if _, err := GetSharedResolver("other", "maven_install.json", create); err != nil || created != 2 {
+		t.Errorf("expected a resolver of another kind to be created, created %d times", created)
+	}
+
+	failure := errors.New("invalid lockfile")
+	for i := 0; i < 2; i++ {
+		if _, err := GetSharedResolver("test", "invalid.json", func() (interface{}, error) { return nil, failure }); err != failure {
+			t.Errorf("expected the creation error, got %v", err)
+		}
+	}
+}
@@ -36,6 +36,10 @@ func (s *LabelSet) Add(l *label.Label) {
 	s.labels.Add(relL)
 }
 
+func (s *LabelSet) Remove(l *label.Label) {
+	s.labels.Remove(l.Rel(s.from.Repo, s.from.Pkg))
+}
+
 func (s *LabelSet) Empty() bool {
 	return s.labels.Empty()
 }
@@ -0,0 +1,95 @@
+package gazelle
+
+import (
+	"fmt"
+	"path"
+	"sync"
+
+	BazelLog "aspect.build/cli/pkg/logger"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/emirpasic/gods/sets/treeset"
+)
+
+// SourceOwner is the rule claiming a source file.
+type SourceOwner struct {
+	Kind  string
+	Label label.Label
+}
+
+func (o SourceOwner) String() string {
+	return fmt.Sprintf("%s %s", o.Kind, o.Label.String())
+}
+
+// The source files claimed by rules of all languages, by workspace relative path.
+// Shared across languages so a source file is never in the srcs of multiple rules.
+var sourceOwners = make(map[string]SourceOwner)
+var sourceOwnersLock sync.Mutex
+
+// ResetSourceOwners removes all claimed source files. Languages must reset the
+// claims before generating rules so claims of previous runs within the same
+// process are not retained.
+func ResetSourceOwners() {
+	sourceOwnersLock.Lock()
+	defer sourceOwnersLock.Unlock()
+
+	sourceOwners = make(map[string]SourceOwner)
+}
+
+// ClaimSources claims the srcs of a rule. An error is returned for each file
+// already claimed by a different rule, those files must not be added to the rule.
+func ClaimSources(kind string, owner label.Label, srcs []string) []error {
+	sourceOwnersLock.Lock()
+	defer sourceOwnersLock.Unlock()
+
+	errs := make([]error, 0)
+
+	for _, src := range srcs {
+		srcPath := path.Join(owner.Pkg, src)
+
+		if existing, claimed := sourceOwners[srcPath]; claimed && existing.Label != owner {
+			errs = append(errs, fmt.Errorf("source file %q is claimed by both %s and %s", srcPath, existing, SourceOwner{Kind: kind, Label: owner}))
+			continue
+		}
+
+		sourceOwners[srcPath] = SourceOwner{Kind: kind, Label: owner}
+	}
+
+	return errs
+}
+
+// GetSourceOwner returns the rule claiming a source file within a package, if any.
+func GetSourceOwner(pkg, src string) (SourceOwner, bool) {
+	sourceOwnersLock.Lock()
+	defer sourceOwnersLock.Unlock()
+
+	owner, claimed := sourceOwners[path.Join(pkg, src)]
+	return owner, claimed
+}
+
+// ClaimOtherRuleSources claims the srcs of the rules not generated by the calling
+// language: existing rules in the BUILD file and rules generated by languages run
+// previously in the package. Only rules of the ownerKinds, including mapped kinds,
+// claim their srcs. Rules such as a filegroup or genrule only reference their srcs
+// and never claim them.
+func ClaimOtherRuleSources(args language.GenerateArgs, ownerKinds *treeset.Set) {
+	if args.File != nil {
+		for _, r := range args.File.Rules {
+			if containsMappedKind(args, ownerKinds, r.Kind()) {
+				claimOtherRuleSources(r.Kind(), label.New("", args.Rel, r.Name()), r.AttrStrings("srcs"))
+			}
+		}
+	}
+
+	for _, r := range args.OtherGen {
+		if containsMappedKind(args, ownerKinds, r.Kind()) {
+			claimOtherRuleSources(r.Kind(), label.New("", args.Rel, r.Name()), r.AttrStrings("srcs"))
+		}
+	}
+}
+
+func claimOtherRuleSources(kind string, owner label.Label, srcs []string) {
+	for _, err := range ClaimSources(kind, owner, srcs) {
+		BazelLog.Debugf("%v", err)
+	}
+}
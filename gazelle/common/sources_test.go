@@ -0,0 +1,72 @@
+package gazelle
+
+import (
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/emirpasic/gods/sets/treeset"
+)
+
+func TestClaimSources(t *testing.T) {
+	ResetSourceOwners()
+
+	lib := label.New("", "pkg", "lib")
+	if errs := ClaimSources("kt_jvm_library", lib, []string{"a.kt", "b.kt"}); len(errs) != 0 {
+		t.Errorf("unexpected errors claiming unclaimed sources: %v", errs)
+	}
+
+	// Claiming again by the same rule is not a conflict
+	if errs := ClaimSources("kt_jvm_library", lib, []string{"a.kt"}); len(errs) != 0 {
+		t.Errorf("unexpected errors reclaiming sources: %v", errs)
+	}
+
+	other := label.New("", "pkg", "other")
+	if errs := ClaimSources("java_library", other, []string{"b.kt", "c.java"}); len(errs) != 1 {
+		t.Errorf("expected 1 conflict, got: %v", errs)
+	}
+
+	if owner, claimed := GetSourceOwner("pkg", "b.kt"); !claimed || owner.Label != lib {
+		t.Errorf("conflicting claim must not change the owner, got: %v", owner)
+	}
+
+	if owner, claimed := GetSourceOwner("pkg", "c.java"); !claimed || owner.Label != other {
+		t.Errorf("expected c.java to be claimed by %v, got: %v", other, owner)
+	}
+
+	ResetSourceOwners()
+
+	if _, claimed := GetSourceOwner("pkg", "a.kt"); claimed {
+		t.Error("expected no claims after reset")
+	}
+}
+
+func TestClaimOtherRuleSources(t *testing.T) {
+	ResetSourceOwners()
+
+	f := rule.EmptyFile("pkg/BUILD", "pkg")
+
+	javaLib := rule.NewRule("java_library", "java")
+	javaLib.SetAttr("srcs", []string{"Claimed.java"})
+	javaLib.Insert(f)
+
+	files := rule.NewRule("filegroup", "files")
+	files.SetAttr("srcs", []string{"script.kts"})
+	files.Insert(f)
+
+	ClaimOtherRuleSources(language.GenerateArgs{
+		Config: config.New(),
+		Rel:    "pkg",
+		File:   f,
+	}, treeset.NewWithStringComparator("java_library"))
+
+	if owner, claimed := GetSourceOwner("pkg", "Claimed.java"); !claimed || owner.Kind != "java_library" {
+		t.Errorf("expected Claimed.java to be claimed by the java_library, got: %v", owner)
+	}
+
+	if owner, claimed := GetSourceOwner("pkg", "script.kts"); claimed {
+		t.Errorf("filegroup srcs must not be claimed, got: %v", owner)
+	}
+}
@@ -0,0 +1,144 @@
+package treesitter
+
+import (
+	"iter"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// A position within the source code, zero-based.
+type Point struct {
+	Row    uint32 `json:"row"`
+	Column uint32 `json:"column"`
+}
+
+// A node captured by a query.
+type Capture struct {
+	// The name of the capture such as `ref` for `(user_type) @ref`
+	Name string `json:"name"`
+
+	// The source code of the captured node
+	Text string `json:"text"`
+
+	// The range of the captured node within the source code
+	StartByte  uint32 `json:"startByte"`
+	EndByte    uint32 `json:"endByte"`
+	StartPoint Point  `json:"startPoint"`
+	EndPoint   Point  `json:"endPoint"`
+}
+
+// The captures of a single match of a named query.
+type CaptureMatch struct {
+	// The name of the query registered using RegisterQuery
+	Query string `json:"query"`
+
+	Captures []Capture `json:"captures"`
+}
+
+// The capture of the match by capture name, if captured.
+func (m CaptureMatch) Get(name string) (Capture, bool) {
+	for _, c := range m.Captures {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Capture{}, false
+}
+
+// The source code captured by capture name, or empty if not captured.
+func (m CaptureMatch) Text(name string) string {
+	c, _ := m.Get(name)
+	return c.Text
+}
+
+// The matches of a set of named queries against a tree, in query order and then
+// in the order of the matches within the source code.
+type Captures []CaptureMatch
+
+// Iterate over the matches of a query.
+func (c Captures) Matches(query string) iter.Seq[CaptureMatch] {
+	return func(yield func(CaptureMatch) bool) {
+		for _, m := range c {
+			if m.Query == query && !yield(m) {
+				return
+			}
+		}
+	}
+}
+
+// Iterate over the captures of a name across all matches of a query.
+func (c Captures) All(query, name string) iter.Seq[Capture] {
+	return func(yield func(Capture) bool) {
+		for m := range c.Matches(query) {
+			for _, capture := range m.Captures {
+				if capture.Name == name && !yield(capture) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// The source code captured by a name across all matches of a query.
+func (c Captures) Texts(query, name string) []string {
+	texts := make([]string, 0)
+	for capture := range c.All(query, name) {
+		texts = append(texts, capture.Text)
+	}
+	return texts
+}
+
+// Parse the source code and collect the matches of the named queries registered
+// for the language using RegisterQuery.
+func ExtractCaptures(lang LanguageGrammar, filePath string, sourceCode []byte, queries ...string) (Captures, error) {
+	tree, err := ParseSourceCode(lang, filePath, sourceCode)
+	if err != nil {
+		return nil, err
+	}
+	defer tree.Close()
+
+	return tree.(TreeAst).Captures(queries...), nil
+}
+
+// Collect the matches of the named queries registered for the language of the tree
+// using RegisterQuery.
+func (tree TreeAst) Captures(queries ...string) Captures {
+	rootNode := tree.SitterTree.RootNode()
+	result := make(Captures, 0)
+
+	for _, name := range queries {
+		q := mustNamedQuery(tree.lang, name)
+
+		qc := sitter.NewQueryCursor()
+		qc.Exec(q, rootNode)
+
+		for {
+			m, ok := qc.NextMatch()
+			if !ok {
+				break
+			}
+
+			// Apply predicates to filter results.
+			if !matchesAllPredicates(q, m, qc, tree.sourceCode) {
+				continue
+			}
+
+			match := CaptureMatch{Query: name, Captures: make([]Capture, 0, len(m.Captures))}
+			for _, c := range m.Captures {
+				match.Captures = append(match.Captures, Capture{
+					Name:       q.CaptureNameForId(c.Index),
+					Text:       c.Node.Content(tree.sourceCode),
+					StartByte:  c.Node.StartByte(),
+					EndByte:    c.Node.EndByte(),
+					StartPoint: Point{Row: c.Node.StartPoint().Row, Column: c.Node.StartPoint().Column},
+					EndPoint:   Point{Row: c.Node.EndPoint().Row, Column: c.Node.EndPoint().Column},
+				})
+			}
+			result = append(result, match)
+		}
+
+		qc.Close()
+	}
+
+	return result
+}
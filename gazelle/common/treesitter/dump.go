@@ -0,0 +1,122 @@
+package treesitter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// A node of a dumped tree, for inspecting the shape of a tree in tools and tests.
+type DumpNode struct {
+	// The type of the node such as `class_declaration`, or the source of anonymous
+	// nodes such as `(`
+	Type string `json:"type"`
+
+	// The name of the field of the node within its parent, if any
+	Field string `json:"field,omitempty"`
+
+	Named   bool `json:"named"`
+	Missing bool `json:"missing,omitempty"`
+
+	StartByte  uint32 `json:"startByte"`
+	EndByte    uint32 `json:"endByte"`
+	StartPoint Point  `json:"startPoint"`
+	EndPoint   Point  `json:"endPoint"`
+
+	// The source code of leaf nodes
+	Text string `json:"text,omitempty"`
+
+	Children []*DumpNode `json:"children,omitempty"`
+}
+
+// Dump the tree, excluding the anonymous nodes such as punctuation if namedOnly.
+func (tree TreeAst) Dump(namedOnly bool) *DumpNode {
+	cursor := sitter.NewTreeCursor(tree.SitterTree.RootNode())
+	defer cursor.Close()
+
+	return dumpNode(cursor, tree.sourceCode, namedOnly)
+}
+
+func dumpNode(cursor *sitter.TreeCursor, sourceCode []byte, namedOnly bool) *DumpNode {
+	node := cursor.CurrentNode()
+
+	d := &DumpNode{
+		Type:       node.Type(),
+		Field:      cursor.CurrentFieldName(),
+		Named:      node.IsNamed(),
+		Missing:    node.IsMissing(),
+		StartByte:  node.StartByte(),
+		EndByte:    node.EndByte(),
+		StartPoint: Point{Row: node.StartPoint().Row, Column: node.StartPoint().Column},
+		EndPoint:   Point{Row: node.EndPoint().Row, Column: node.EndPoint().Column},
+	}
+
+	if cursor.GoToFirstChild() {
+		for {
+			if !namedOnly || cursor.CurrentNode().IsNamed() {
+				d.Children = append(d.Children, dumpNode(cursor, sourceCode, namedOnly))
+			}
+			if !cursor.GoToNextSibling() {
+				break
+			}
+		}
+		cursor.GoToParent()
+	}
+
+	if node.ChildCount() == 0 {
+		d.Text = node.Content(sourceCode)
+	}
+
+	return d
+}
+
+// Render the node as an indented S-expression such as:
+//
+//	(source_file [0:0-1:0]
+//	  (package_header [0:0-0:11]
+//	    (identifier [0:8-0:11]
+//	      (simple_identifier [0:8-0:11] "foo"))))
+func (d *DumpNode) SExpression() string {
+	var s strings.Builder
+	d.writeSExpression(&s, 0)
+	return s.String()
+}
+
+func (d *DumpNode) writeSExpression(s *strings.Builder, depth int) {
+	if depth > 0 {
+		s.WriteString("\n")
+		s.WriteString(strings.Repeat("  ", depth))
+	}
+	if d.Field != "" {
+		s.WriteString(d.Field)
+		s.WriteString(": ")
+	}
+
+	switch {
+	case d.Missing:
+		fmt.Fprintf(s, "(MISSING %s", d.Type)
+	case d.Named:
+		fmt.Fprintf(s, "(%s", d.Type)
+	default:
+		fmt.Fprintf(s, "(%q", d.Type)
+	}
+
+	fmt.Fprintf(s, " [%d:%d-%d:%d]", d.StartPoint.Row, d.StartPoint.Column, d.EndPoint.Row, d.EndPoint.Column)
+
+	if d.Named && d.Text != "" {
+		fmt.Fprintf(s, " %q", d.Text)
+	}
+
+	for _, child := range d.Children {
+		child.writeSExpression(s, depth+1)
+	}
+
+	s.WriteString(")")
+}
+
+// Render the node as indented JSON.
+func (d *DumpNode) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
@@ -0,0 +1,110 @@
+package treesitter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+// A source file which is not valid UTF-8 and was normalized before being parsed.
+type EncodingError struct {
+	File string
+
+	// What was normalized such as "transcoded from UTF-16LE to UTF-8"
+	Message string
+}
+
+var _ error = (*EncodingError)(nil)
+
+func (e *EncodingError) Error() string {
+	return fmt.Sprintf("%s: %s", e.File, e.Message)
+}
+
+// Normalize source code to UTF-8 before parsing, so a badly encoded file does not
+// produce bogus ERROR nodes for the whole file:
+//   - a UTF-8 byte order mark is removed
+//   - UTF-16 with a byte order mark is transcoded to UTF-8
+//   - invalid UTF-8 byte sequences are replaced with U+FFFD
+//
+// Returns an EncodingError describing each normalization other than removing a
+// UTF-8 byte order mark.
+func NormalizeEncoding(filePath string, sourceCode []byte) ([]byte, []error) {
+	var errs []error
+
+	switch {
+	case bytes.HasPrefix(sourceCode, utf8BOM):
+		sourceCode = sourceCode[len(utf8BOM):]
+	case bytes.HasPrefix(sourceCode, utf16LEBOM):
+		sourceCode = decodeUTF16(sourceCode[len(utf16LEBOM):], binary.LittleEndian)
+		errs = append(errs, &EncodingError{File: filePath, Message: "transcoded from UTF-16LE to UTF-8"})
+	case bytes.HasPrefix(sourceCode, utf16BEBOM):
+		sourceCode = decodeUTF16(sourceCode[len(utf16BEBOM):], binary.BigEndian)
+		errs = append(errs, &EncodingError{File: filePath, Message: "transcoded from UTF-16BE to UTF-8"})
+	}
+
+	if !utf8.Valid(sourceCode) {
+		var first, count int
+		sourceCode, first, count = replaceInvalidUTF8(sourceCode)
+		errs = append(errs, &EncodingError{
+			File:    filePath,
+			Message: fmt.Sprintf("replaced %d invalid UTF-8 byte sequence(s), the first at byte %d", count, first),
+		})
+	}
+
+	return sourceCode, errs
+}
+
+func decodeUTF16(b []byte, order binary.ByteOrder) []byte {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = order.Uint16(b[2*i:])
+	}
+
+	// A trailing odd byte can not be decoded.
+	decoded := string(utf16.Decode(units))
+	if len(b)%2 != 0 {
+		decoded += string(utf8.RuneError)
+	}
+
+	return []byte(decoded)
+}
+
+// Replace each invalid UTF-8 byte sequence with U+FFFD, returning the offset of the
+// first invalid sequence and the number of sequences replaced.
+func replaceInvalidUTF8(b []byte) ([]byte, int, int) {
+	result := make([]byte, 0, len(b))
+	first, count := -1, 0
+
+	for i := 0; i < len(b); {
+		r, size := utf8.DecodeRune(b[i:])
+		if r == utf8.RuneError && size == 1 {
+			if first == -1 {
+				first = i
+			}
+			count++
+
+			// Replace the whole invalid sequence with a single replacement character.
+			for i < len(b) {
+				if r, size := utf8.DecodeRune(b[i:]); r != utf8.RuneError || size != 1 {
+					break
+				}
+				i++
+			}
+			result = utf8.AppendRune(result, utf8.RuneError)
+			continue
+		}
+
+		result = append(result, b[i:i+size]...)
+		i += size
+	}
+
+	return result, first, count
+}
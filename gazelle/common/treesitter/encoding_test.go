@@ -0,0 +1,65 @@
+package treesitter
+
+import (
+	"testing"
+)
+
+func TestNormalizeEncoding(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		source   []byte
+		expected string
+		errors   []string
+	}{
+		{
+			name:     "utf-8",
+			source:   []byte("val s = \"é\"\n"),
+			expected: "val s = \"é\"\n",
+		},
+		{
+			name:     "utf-8 bom",
+			source:   []byte("\xEF\xBB\xBFpackage a\n"),
+			expected: "package a\n",
+		},
+		{
+			name:     "utf-16le",
+			source:   []byte("\xFF\xFEa\x00=\x00\xE9\x00"),
+			expected: "a=é",
+			errors:   []string{"a.kt: transcoded from UTF-16LE to UTF-8"},
+		},
+		{
+			name:     "utf-16be",
+			source:   []byte("\xFE\xFF\x00a\x00=\x00\xE9"),
+			expected: "a=é",
+			errors:   []string{"a.kt: transcoded from UTF-16BE to UTF-8"},
+		},
+		{
+			name:     "utf-16le odd length",
+			source:   []byte("\xFF\xFEa\x00b"),
+			expected: "a�",
+			errors:   []string{"a.kt: transcoded from UTF-16LE to UTF-8"},
+		},
+		{
+			name:     "invalid bytes",
+			source:   []byte("a\xFF\xFEb\xC3c"),
+			expected: "a�b�c",
+			errors:   []string{"a.kt: replaced 2 invalid UTF-8 byte sequence(s), the first at byte 1"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, errs := NormalizeEncoding("a.kt", tc.source)
+			if string(actual) != tc.expected {
+				t.Errorf("NormalizeEncoding(%q): expected %q, got %q", tc.source, tc.expected, actual)
+			}
+
+			if len(errs) != len(tc.errors) {
+				t.Fatalf("NormalizeEncoding(%q): expected errors %v, got %v", tc.source, tc.errors, errs)
+			}
+			for i, err := range errs {
+				if err.Error() != tc.errors[i] {
+					t.Errorf("NormalizeEncoding(%q): expected error %q, got %q", tc.source, tc.errors[i], err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package treesitter
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// A parse error of a source file, rendered as the source line of the error
+// with a caret pointing at the column of the error.
+type ParseError struct {
+	File string
+
+	// The one-based line and column of the error
+	Line   int
+	Column int
+
+	// The source code of the line of the error
+	SourceLine string
+}
+
+var _ error = (*ParseError)(nil)
+
+// Create the error of a parse error at a point within the source code, such as the
+// start of an ERROR node.
+func NewParseError(filePath string, sourceCode []byte, at sitter.Point) *ParseError {
+	line := ""
+	if lines := bytes.Split(sourceCode, []byte("\n")); int(at.Row) < len(lines) {
+		line = string(bytes.TrimSuffix(lines[at.Row], []byte("\r")))
+	}
+
+	return &ParseError{
+		File:       filePath,
+		Line:       int(at.Row) + 1,
+		Column:     int(at.Column) + 1,
+		SourceLine: line,
+	}
+}
+
+// The position of the error such as `foo/bar.kt:3:14`.
+func (e *ParseError) Position() string {
+	return fmt.Sprintf("%s:%d:%d", e.File, e.Line, e.Column)
+}
+
+func (e *ParseError) Error() string {
+	pre := fmt.Sprintf("     %d: ", e.Line)
+	arw := strings.Repeat(" ", len(pre)+e.Column-1) + "^"
+	return pre + e.SourceLine + "\n" + arw
+}
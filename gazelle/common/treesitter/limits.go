@@ -0,0 +1,50 @@
+package treesitter
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Limits of the source files parsed by ParseSourceCode, guarding against enormous or
+// pathological files hanging or exhausting the memory of a run.
+type ParseLimits struct {
+	// The maximum size of a source file in bytes, unlimited if 0
+	MaxFileSize int
+
+	// The maximum duration of parsing a source file, unlimited if 0
+	Timeout time.Duration
+}
+
+var parseLimits ParseLimits
+var parseLimitsMutex sync.RWMutex
+
+// Set the limits of the source files parsed by ParseSourceCode.
+func SetParseLimits(limits ParseLimits) {
+	parseLimitsMutex.Lock()
+	defer parseLimitsMutex.Unlock()
+
+	parseLimits = limits
+}
+
+func currentParseLimits() ParseLimits {
+	parseLimitsMutex.RLock()
+	defer parseLimitsMutex.RUnlock()
+
+	return parseLimits
+}
+
+// A source file skipped without being parsed, or whose parsing was abandoned, because
+// it exceeds the ParseLimits.
+type SkippedFileError struct {
+	File string
+
+	// Why the file was skipped such as "size of 12000000 bytes exceeds the limit of 10000000 bytes"
+	Reason string
+}
+
+var _ error = (*SkippedFileError)(nil)
+
+func (e *SkippedFileError) Error() string {
+	return fmt.Sprintf("skipped %s: %s", e.File, e.Reason)
+}
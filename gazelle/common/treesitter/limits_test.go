@@ -0,0 +1,44 @@
+package treesitter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLimits(t *testing.T) {
+	defer SetParseLimits(ParseLimits{})
+
+	source := []byte("package a\n\nclass A\n")
+	large := []byte("package a\n\n" + strings.Repeat("val x = listOf(1, 2, 3)\n", 100000))
+
+	for _, tc := range []struct {
+		name    string
+		limits  ParseLimits
+		source  []byte
+		skipped string
+	}{
+		{name: "unlimited", limits: ParseLimits{}, source: source},
+		{name: "within size", limits: ParseLimits{MaxFileSize: len(source)}, source: source},
+		{name: "exceeding size", limits: ParseLimits{MaxFileSize: len(source) - 1}, source: source, skipped: "exceeds the limit of"},
+		{name: "within timeout", limits: ParseLimits{Timeout: time.Minute}, source: source},
+		{name: "exceeding timeout", limits: ParseLimits{Timeout: time.Microsecond}, source: large, skipped: "exceeded the timeout of"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			SetParseLimits(tc.limits)
+
+			tree, err := ParseSourceCode(Kotlin, "a.kt", tc.source)
+
+			var skippedErr *SkippedFileError
+			if tc.skipped == "" {
+				if err != nil {
+					t.Fatalf("ParseSourceCode(): unexpected error: %v", err)
+				}
+				tree.Close()
+			} else if !errors.As(err, &skippedErr) || skippedErr.File != "a.kt" || !strings.Contains(skippedErr.Reason, tc.skipped) {
+				t.Errorf("ParseSourceCode(): expected a SkippedFileError of a.kt containing %q, got: %v", tc.skipped, err)
+			}
+		})
+	}
+}
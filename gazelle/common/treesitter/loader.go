@@ -0,0 +1,111 @@
+package treesitter
+
+// #cgo linux android LDFLAGS: -ldl
+// #include <dlfcn.h>
+// #include <stdlib.h>
+//
+// typedef const void *(*language_fn)(void);
+//
+// static const void *call_language_fn(void *fn) {
+//   return ((language_fn)fn)();
+// }
+import "C"
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"unsafe"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// The grammars registered at runtime, by name.
+var registeredGrammars = make(map[LanguageGrammar]*sitter.Language)
+var registeredGrammarVersions = make(map[LanguageGrammar]string)
+var registeredGrammarsMutex sync.RWMutex
+
+// Register a grammar by name, used for the files of the extensions such as "proto".
+// The version of the grammar must change whenever the grammar changes, see GrammarVersion.
+// Grammars must be registered before parsing any source code, such as while
+// configuring an extension.
+func RegisterGrammar(name LanguageGrammar, version string, lang *sitter.Language, extensions ...string) error {
+	registeredGrammarsMutex.Lock()
+	defer registeredGrammarsMutex.Unlock()
+
+	if _, isRegistered := registeredGrammars[name]; isRegistered || isBuiltinGrammar(name) {
+		return fmt.Errorf("grammar %q is already registered", name)
+	}
+
+	for _, ext := range extensions {
+		ext = strings.TrimPrefix(ext, ".")
+		if existing, found := EXT_LANGUAGES[ext]; found {
+			return fmt.Errorf("extension %q of grammar %q is already registered by grammar %q", ext, name, existing)
+		}
+	}
+
+	registeredGrammars[name] = lang
+	registeredGrammarVersions[name] = version
+	for _, ext := range extensions {
+		EXT_LANGUAGES[strings.TrimPrefix(ext, ".")] = name
+	}
+
+	return nil
+}
+
+// Load a grammar compiled as a shared object such as `libtree-sitter-proto.so` and
+// register it by name, as RegisterGrammar. The shared object must export the
+// `tree_sitter_<name>` function of the grammar, such as `tree_sitter_proto`.
+//
+// Only shared objects of the platform are supported, not WASM grammars.
+func LoadGrammar(name LanguageGrammar, sharedObjectPath string, extensions ...string) error {
+	cPath := C.CString(sharedObjectPath)
+	defer C.free(unsafe.Pointer(cPath))
+
+	// The shared object is never closed, its language is used until exiting.
+	handle := C.dlopen(cPath, C.RTLD_NOW|C.RTLD_LOCAL)
+	if handle == nil {
+		return fmt.Errorf("failed to load grammar %q from %q: %s", name, sharedObjectPath, C.GoString(C.dlerror()))
+	}
+
+	symbol := "tree_sitter_" + strings.ReplaceAll(string(name), "-", "_")
+	cSymbol := C.CString(symbol)
+	defer C.free(unsafe.Pointer(cSymbol))
+
+	fn := C.dlsym(handle, cSymbol)
+	if fn == nil {
+		C.dlclose(handle)
+		return fmt.Errorf("failed to load grammar %q: %q does not export %s()", name, path.Base(sharedObjectPath), symbol)
+	}
+
+	ptr := C.call_language_fn(fn)
+	if ptr == nil {
+		C.dlclose(handle)
+		return fmt.Errorf("failed to load grammar %q: %s() of %q returned no language", name, symbol, path.Base(sharedObjectPath))
+	}
+
+	content, err := os.ReadFile(sharedObjectPath)
+	if err != nil {
+		return fmt.Errorf("failed to load grammar %q: %w", name, err)
+	}
+	version := fmt.Sprintf("sha256:%x", sha256.Sum256(content))
+
+	return RegisterGrammar(name, version, sitter.NewLanguage(unsafe.Pointer(ptr)), extensions...)
+}
+
+// The grammar registered at runtime by name, if registered.
+func registeredGrammar(name LanguageGrammar) (*sitter.Language, bool) {
+	registeredGrammarsMutex.RLock()
+	defer registeredGrammarsMutex.RUnlock()
+
+	lang, found := registeredGrammars[name]
+	return lang, found
+}
+
+func isBuiltinGrammar(name LanguageGrammar) bool {
+	_, isBuiltin := grammarVersions[name]
+	return isBuiltin
+}
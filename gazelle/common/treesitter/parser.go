@@ -17,10 +17,14 @@
 package treesitter
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"path"
+	"strings"
+	"sync"
 
 	"aspect.build/cli/gazelle/common/treesitter/grammars/json"
 	"aspect.build/cli/gazelle/common/treesitter/grammars/kotlin"
@@ -28,6 +32,10 @@ import (
 	"aspect.build/cli/gazelle/common/treesitter/grammars/tsx"
 	"aspect.build/cli/gazelle/common/treesitter/grammars/typescript"
 	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/groovy"
+	"github.com/smacker/go-tree-sitter/java"
+	"github.com/smacker/go-tree-sitter/scala"
+	"github.com/smacker/go-tree-sitter/swift"
 )
 
 type LanguageGrammar string
@@ -38,6 +46,10 @@ const (
 	Typescript                  = "typescript"
 	TypescriptX                 = "tsx"
 	JSON                        = "json"
+	Java                        = "java"
+	Scala                       = "scala"
+	Groovy                      = "groovy"
+	Swift                       = "swift"
 )
 
 type ASTQueryResult interface {
@@ -48,6 +60,18 @@ type AST interface {
 	Query(query string) <-chan ASTQueryResult
 	QueryErrors() []error
 
+	// Queries registered by name using RegisterQuery
+	QueryNamed(name string) <-chan ASTQueryResult
+	QueryNamedStrings(name, returnVar string) []string
+	Captures(queries ...string) Captures
+
+	// A structured dump of the tree, excluding anonymous nodes if namedOnly
+	Dump(namedOnly bool) *DumpNode
+
+	// Release the native memory of the tree. The tree and its nodes must not be used
+	// once closed.
+	Close()
+
 	// Wrapper utils
 	// TODO: delete
 	QueryStrings(query, returnVar string) []string
@@ -59,10 +83,23 @@ type TreeAst struct {
 	filePath   string
 	sourceCode []byte
 
+	// The normalizations of the encoding of the source code
+	encodingErrors []error
+
 	// TODO: don't make public
 	SitterTree *sitter.Tree
 }
 
+// The source code of the tree, normalized to UTF-8 using NormalizeEncoding. The
+// content of the nodes of the tree must be read from this source code.
+func (tree TreeAst) SourceCode() []byte {
+	return tree.sourceCode
+}
+
+func (tree TreeAst) Close() {
+	tree.SitterTree.Close()
+}
+
 func (tree TreeAst) String() string {
 	return fmt.Sprintf("TreeAst{\n lang: %q,\n filePath: %q,\n AST:\n  %v\n}", tree.lang, tree.filePath, tree.SitterTree.RootNode().String())
 }
@@ -71,16 +108,28 @@ func toSitterLanguage(lang LanguageGrammar) *sitter.Language {
 	switch lang {
 	case JSON:
 		return json.GetLanguage()
+	case Groovy:
+		return groovy.GetLanguage()
+	case Java:
+		return java.GetLanguage()
 	case Kotlin:
 		return kotlin.GetLanguage()
+	case Scala:
+		return scala.GetLanguage()
 	case Starlark:
 		return starlark.GetLanguage()
+	case Swift:
+		return swift.GetLanguage()
 	case Typescript:
 		return typescript.GetLanguage()
 	case TypescriptX:
 		return tsx.GetLanguage()
 	}
 
+	if registered, found := registeredGrammar(lang); found {
+		return registered
+	}
+
 	log.Panicf("Unknown LanguageGrammar %q", lang)
 	return nil
 }
@@ -90,10 +139,12 @@ func PathToLanguage(p string) LanguageGrammar {
 }
 
 var EXT_LANGUAGES = map[string]LanguageGrammar{
+	// Including Gradle Kotlin DSL files such as build.gradle.kts
 	"kt":  Kotlin,
 	"kts": Kotlin,
 
-	"bzl": Starlark,
+	"bzl":   Starlark,
+	"bazel": Starlark,
 
 	"ts":  Typescript,
 	"cts": Typescript,
@@ -106,6 +157,90 @@ var EXT_LANGUAGES = map[string]LanguageGrammar{
 	"jsx": TypescriptX,
 
 	"json": JSON,
+
+	"java": Java,
+
+	"scala": Scala,
+	"sc":    Scala,
+
+	// Including Gradle files such as build.gradle and settings.gradle
+	"groovy": Groovy,
+	"gradle": Groovy,
+
+	"swift": Swift,
+}
+
+// The languages of files by base name, for files without a language extension.
+var FILENAME_LANGUAGES = map[string]LanguageGrammar{
+	"BUILD":     Starlark,
+	"WORKSPACE": Starlark,
+}
+
+// The languages of scripts by the interpreter of their shebang such as
+// `#!/usr/bin/env kotlin`.
+var INTERPRETER_LANGUAGES = map[string]LanguageGrammar{
+	"kotlin":  Kotlin,
+	"kscript": Kotlin,
+	"java":    Java,
+	"scala":   Scala,
+	"groovy":  Groovy,
+	"swift":   Swift,
+	"node":    Typescript,
+	"deno":    Typescript,
+	"bun":     Typescript,
+	"ts-node": Typescript,
+	"tsx":     Typescript,
+}
+
+// Detect the language of a file by its extension, its name such as `BUILD`,
+// or for scripts without an extension the interpreter of its shebang.
+func DetectLanguage(filePath string, sourceCode []byte) (LanguageGrammar, bool) {
+	base := path.Base(filePath)
+
+	if ext := path.Ext(base); ext != "" {
+		if lang, found := EXT_LANGUAGES[ext[1:]]; found {
+			return lang, true
+		}
+	}
+
+	if lang, found := FILENAME_LANGUAGES[base]; found {
+		return lang, true
+	}
+
+	if interpreter := shebangInterpreter(sourceCode); interpreter != "" {
+		lang, found := INTERPRETER_LANGUAGES[interpreter]
+		return lang, found
+	}
+
+	return "", false
+}
+
+// The base name of the interpreter of a shebang such as `kotlin` for `#!/usr/bin/kotlin`
+// or `#!/usr/bin/env -S kotlin -J-Xmx1g`, or empty without a shebang.
+func shebangInterpreter(sourceCode []byte) string {
+	if !bytes.HasPrefix(sourceCode, []byte("#!")) {
+		return ""
+	}
+
+	line, _, _ := bytes.Cut(sourceCode[2:], []byte("\n"))
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return ""
+	}
+
+	interpreter := path.Base(fields[0])
+	if interpreter == "env" {
+		interpreter = ""
+		for _, arg := range fields[1:] {
+			// Skip the options and variable assignments of env
+			if !strings.HasPrefix(arg, "-") && !strings.Contains(arg, "=") {
+				interpreter = path.Base(arg)
+				break
+			}
+		}
+	}
+
+	return interpreter
 }
 
 // In theory, this is a mirror of
@@ -121,16 +256,104 @@ func extensionToLanguage(ext string) LanguageGrammar {
 	return lang
 }
 
+// Pools of parsers per language, reused across calls to ParseSourceCode to avoid
+// constructing a parser and binding its language per file.
+var parserPools = make(map[LanguageGrammar]*sync.Pool)
+var parserPoolsMutex sync.Mutex
+
+func parserPool(lang LanguageGrammar) *sync.Pool {
+	parserPoolsMutex.Lock()
+	defer parserPoolsMutex.Unlock()
+
+	pool := parserPools[lang]
+	if pool == nil {
+		sitterLang := toSitterLanguage(lang)
+		pool = &sync.Pool{
+			New: func() any {
+				parser := sitter.NewParser()
+				parser.SetLanguage(sitterLang)
+				return parser
+			},
+		}
+		parserPools[lang] = pool
+	}
+	return pool
+}
+
 func ParseSourceCode(lang LanguageGrammar, filePath string, sourceCode []byte) (AST, error) {
+	return parseSourceCode(lang, filePath, nil, sourceCode)
+}
+
+// An edit of the source code of a parsed tree, as the byte offsets and points of the
+// start of the edit and the end of the edit before and after the edit.
+type SourceEdit struct {
+	StartByte, OldEndByte, NewEndByte    uint32
+	StartPoint, OldEndPoint, NewEndPoint Point
+}
+
+// Reparse the edited source code of a previously parsed tree, reusing the regions of
+// the previous tree not affected by the edits.
+//
+// The edits are applied to a copy of the previous tree, which remains unchanged and
+// owned by the caller.
+func ReparseSourceCode(previous AST, edits []SourceEdit, sourceCode []byte) (AST, error) {
+	prev, ok := previous.(TreeAst)
+	if !ok {
+		return nil, fmt.Errorf("cannot reparse a tree of type %T", previous)
+	}
+
+	edited := prev.SitterTree.Copy()
+	defer edited.Close()
+
+	for _, edit := range edits {
+		edited.Edit(sitter.EditInput{
+			StartIndex:  edit.StartByte,
+			OldEndIndex: edit.OldEndByte,
+			NewEndIndex: edit.NewEndByte,
+			StartPoint:  sitter.Point(edit.StartPoint),
+			OldEndPoint: sitter.Point(edit.OldEndPoint),
+			NewEndPoint: sitter.Point(edit.NewEndPoint),
+		})
+	}
+
+	return parseSourceCode(prev.lang, prev.filePath, edited, sourceCode)
+}
+
+func parseSourceCode(lang LanguageGrammar, filePath string, previous *sitter.Tree, sourceCode []byte) (AST, error) {
+	limits := currentParseLimits()
+	if limits.MaxFileSize > 0 && len(sourceCode) > limits.MaxFileSize {
+		return nil, &SkippedFileError{
+			File:   filePath,
+			Reason: fmt.Sprintf("size of %d bytes exceeds the limit of %d bytes", len(sourceCode), limits.MaxFileSize),
+		}
+	}
+
 	ctx := context.Background()
+	if limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, limits.Timeout)
+		defer cancel()
+	}
+
+	sourceCode, encodingErrors := NormalizeEncoding(filePath, sourceCode)
 
-	parser := sitter.NewParser()
-	parser.SetLanguage(toSitterLanguage(lang))
+	pool := parserPool(lang)
+	parser := pool.Get().(*sitter.Parser)
+	defer pool.Put(parser)
 
-	tree, err := parser.ParseCtx(ctx, nil, sourceCode)
+	tree, err := parser.ParseCtx(ctx, previous, sourceCode)
 	if err != nil {
+		// Discard the state of the failed parse before the parser is reused.
+		parser.Reset()
+
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, &SkippedFileError{
+				File:   filePath,
+				Reason: fmt.Sprintf("parsing exceeded the timeout of %s", limits.Timeout),
+			}
+		}
 		return nil, err
 	}
 
-	return TreeAst{lang: lang, filePath: filePath, sourceCode: sourceCode, SitterTree: tree}, nil
+	return TreeAst{lang: lang, filePath: filePath, sourceCode: sourceCode, encodingErrors: encodingErrors, SitterTree: tree}, nil
 }
@@ -0,0 +1,100 @@
+package treesitter
+
+import (
+	"testing"
+)
+
+func TestReparseSourceCode(t *testing.T) {
+	before := []byte("package a\n\nval x = 1\n")
+	after := []byte("package a\n\nval xyz = 1\n")
+
+	previous, err := ParseSourceCode(Kotlin, "a.kt", before)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer previous.Close()
+	previousTree := previous.(TreeAst).SitterTree.RootNode().String()
+
+	// The insertion of "yz" after "val x"
+	edit := SourceEdit{
+		StartByte:   16,
+		OldEndByte:  16,
+		NewEndByte:  18,
+		StartPoint:  Point{Row: 2, Column: 5},
+		OldEndPoint: Point{Row: 2, Column: 5},
+		NewEndPoint: Point{Row: 2, Column: 7},
+	}
+
+	reparsed, err := ReparseSourceCode(previous, []SourceEdit{edit}, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reparsed.Close()
+
+	parsed, err := ParseSourceCode(Kotlin, "a.kt", after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer parsed.Close()
+
+	if actual, expected := reparsed.(TreeAst).SitterTree.RootNode().String(), parsed.(TreeAst).SitterTree.RootNode().String(); actual != expected {
+		t.Errorf("ReparseSourceCode(): expected the tree of a full parse\n%s\ngot\n%s", expected, actual)
+	}
+	if actual := previous.(TreeAst).SitterTree.RootNode().String(); actual != previousTree {
+		t.Errorf("ReparseSourceCode(): expected the previous tree to be unchanged, got\n%s", actual)
+	}
+	if actual := reparsed.QueryStrings("(variable_declaration (simple_identifier) @id)", "id"); len(actual) != 1 || actual[0] != "xyz" {
+		t.Errorf("ReparseSourceCode(): expected the identifier xyz, got %v", actual)
+	}
+}
+
+func TestShebangInterpreter(t *testing.T) {
+	for _, tc := range []struct {
+		source      string
+		interpreter string
+	}{
+		{source: "#!/usr/bin/kotlin\nprintln()", interpreter: "kotlin"},
+		{source: "#!/usr/bin/env kotlin\nprintln()", interpreter: "kotlin"},
+		{source: "#! /usr/bin/env kscript", interpreter: "kscript"},
+		{source: "#!/usr/bin/env -S kotlin -J-Xmx1g\n", interpreter: "kotlin"},
+		{source: "#!/usr/bin/env -i JAVA_OPTS=-Xmx1g /opt/bin/kotlin\n", interpreter: "kotlin"},
+		{source: "#!/usr/bin/env -S\n", interpreter: ""},
+		{source: "#!\nprintln()", interpreter: ""},
+		{source: "// #!/usr/bin/kotlin\n", interpreter: ""},
+		{source: "", interpreter: ""},
+	} {
+		if actual := shebangInterpreter([]byte(tc.source)); actual != tc.interpreter {
+			t.Errorf("shebangInterpreter(%q): expected %q, got %q", tc.source, tc.interpreter, actual)
+		}
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	for _, tc := range []struct {
+		filePath string
+		source   string
+		lang     LanguageGrammar
+		found    bool
+	}{
+		{filePath: "src/Foo.kt", lang: Kotlin, found: true},
+		{filePath: "build.gradle.kts", lang: Kotlin, found: true},
+		{filePath: "pkg/BUILD", lang: Starlark, found: true},
+		{filePath: "scripts/run", source: "#!/usr/bin/env -S kotlin -J-Xmx1g\n", lang: Kotlin, found: true},
+		{filePath: "scripts/run", source: "#!/bin/sh\n", found: false},
+		{filePath: "README", found: false},
+	} {
+		if lang, found := DetectLanguage(tc.filePath, []byte(tc.source)); lang != tc.lang || found != tc.found {
+			t.Errorf("DetectLanguage(%q): expected %q, %v, got %q, %v", tc.filePath, tc.lang, tc.found, lang, found)
+		}
+	}
+}
+
+type notTreeAst struct {
+	AST
+}
+
+func TestReparseSourceCodeOfOtherTrees(t *testing.T) {
+	if _, err := ReparseSourceCode(notTreeAst{}, nil, []byte("package a\n")); err == nil {
+		t.Errorf("ReparseSourceCode(): expected an error reparsing a tree not parsed by ParseSourceCode")
+	}
+}
@@ -30,13 +30,94 @@ func parseQuery(lang LanguageGrammar, queryStr string) *sitter.Query {
 	return queryCache[lang][queryStr]
 }
 
+// The queries registered by name per language, compiled once when registered.
+var namedQueries = make(map[LanguageGrammar]map[string]*sitter.Query)
+var namedQuerySources = make(map[LanguageGrammar]map[string]string)
+var namedQueryMutex sync.RWMutex
+
+// Register a query by name for a language. The query is compiled once and shared by
+// all trees of the language, which can then run it using QueryNamed or QueryNamedStrings.
+//
+// Registering the same query again under a name is a no-op, while registering a
+// different query under an existing name or an invalid query is an error.
+func RegisterQuery(lang LanguageGrammar, name, queryStr string) error {
+	namedQueryMutex.Lock()
+	defer namedQueryMutex.Unlock()
+
+	if namedQueries[lang] == nil {
+		namedQueries[lang] = make(map[string]*sitter.Query)
+		namedQuerySources[lang] = make(map[string]string)
+	}
+
+	if existing, isRegistered := namedQuerySources[lang][name]; isRegistered {
+		if existing != queryStr {
+			return fmt.Errorf("a different %s query is already registered as %q", lang, name)
+		}
+		return nil
+	}
+
+	q, err := sitter.NewQuery([]byte(queryStr), toSitterLanguage(lang))
+	if err != nil {
+		return fmt.Errorf("failed to create %s query %q: %w", lang, name, err)
+	}
+
+	// Share the compiled query with the cache of queries by string.
+	queryMutex.Lock()
+	if queryCache[lang] == nil {
+		queryCache[lang] = make(map[string]*sitter.Query)
+	}
+	if cached := queryCache[lang][queryStr]; cached != nil {
+		q = cached
+	} else {
+		queryCache[lang][queryStr] = q
+	}
+	queryMutex.Unlock()
+
+	namedQueries[lang][name] = q
+	namedQuerySources[lang][name] = queryStr
+	return nil
+}
+
+// Register a query by name for a language, failing if it can not be registered.
+// Intended for registering the constant queries of a parser when initialized.
+func MustRegisterQuery(lang LanguageGrammar, name, queryStr string) {
+	if err := RegisterQuery(lang, name, queryStr); err != nil {
+		BazelLog.Fatalf("Failed to register query: %v", err)
+	}
+}
+
+// The compiled query registered by name for a language, for running a query
+// directly against a sitter.Node.
+func NamedQuery(lang LanguageGrammar, name string) (*sitter.Query, bool) {
+	namedQueryMutex.RLock()
+	defer namedQueryMutex.RUnlock()
+
+	q, found := namedQueries[lang][name]
+	return q, found
+}
+
+func mustNamedQuery(lang LanguageGrammar, name string) *sitter.Query {
+	q, found := NamedQuery(lang, name)
+	if !found {
+		BazelLog.Fatalf("No %s query registered as %q", lang, name)
+	}
+	return q
+}
+
 // Run a query finding string query matches.
 func (tree TreeAst) QueryStrings(query, returnVar string) []string {
+	return tree.queryStrings(parseQuery(tree.lang, query), returnVar)
+}
+
+// Run a query registered with RegisterQuery finding string query matches.
+func (tree TreeAst) QueryNamedStrings(name, returnVar string) []string {
+	return tree.queryStrings(mustNamedQuery(tree.lang, name), returnVar)
+}
+
+func (tree TreeAst) queryStrings(sitterQuery *sitter.Query, returnVar string) []string {
 	rootNode := tree.SitterTree.RootNode()
 	results := make([]string, 0, 5)
 
-	sitterQuery := parseQuery(tree.lang, query)
-
 	// Execute the query.
 	qc := sitter.NewQueryCursor()
 	qc.Exec(sitterQuery, rootNode)
@@ -74,7 +155,15 @@ func (qr queryResult) Captures() map[string]string {
 }
 
 func (tree TreeAst) Query(query string) <-chan ASTQueryResult {
-	q := parseQuery(tree.lang, query)
+	return tree.query(parseQuery(tree.lang, query))
+}
+
+// Run a query registered with RegisterQuery.
+func (tree TreeAst) QueryNamed(name string) <-chan ASTQueryResult {
+	return tree.query(mustNamedQuery(tree.lang, name))
+}
+
+func (tree TreeAst) query(q *sitter.Query) <-chan ASTQueryResult {
 	rootNode := tree.SitterTree.RootNode()
 
 	out := make(chan ASTQueryResult)
@@ -153,14 +242,16 @@ func mustNewQuery(lang LanguageGrammar, queryStr string) *sitter.Query {
 	return treeQ
 }
 
-// Create an error for each parse error.
+// Create an error for each parse error, preceded by the normalizations of the
+// encoding of the source code.
 func (tree TreeAst) QueryErrors() []error {
 	node := tree.SitterTree.RootNode()
 	if !node.HasError() {
-		return nil
+		return tree.encodingErrors
 	}
 
-	errors := make([]error, 0)
+	errors := make([]error, 0, len(tree.encodingErrors))
+	errors = append(errors, tree.encodingErrors...)
 
 	query := parseQuery(tree.lang, ErrorsQuery)
 
@@ -181,27 +272,7 @@ func (tree TreeAst) QueryErrors() []error {
 		}
 
 		for _, c := range m.Captures {
-			at := c.Node
-			atStart := at.StartPoint()
-			show := c.Node
-
-			// Navigate up the AST to include the full source line
-			if atStart.Column > 0 {
-				for show.StartPoint().Row > 0 && show.StartPoint().Row == atStart.Row {
-					show = show.Parent()
-				}
-			}
-
-			// Extract only that line from the parent Node
-			lineI := int(atStart.Row - show.StartPoint().Row)
-			colI := int(atStart.Column)
-			line := strings.Split(show.Content(tree.sourceCode), "\n")[lineI]
-
-			pre := fmt.Sprintf("     %d: ", atStart.Row+1)
-			msg := pre + line
-			arw := strings.Repeat(" ", len(pre)+colI) + "^"
-
-			errors = append(errors, fmt.Errorf(msg+"\n"+arw))
+			errors = append(errors, NewParseError(tree.filePath, tree.sourceCode, c.Node.StartPoint()))
 		}
 	}
 
@@ -0,0 +1,79 @@
+// Package treesittertest provides utilities for unit testing tree-sitter queries
+// against inline source snippets.
+package treesittertest
+
+import (
+	"slices"
+	"testing"
+
+	treeutils "aspect.build/cli/gazelle/common/treesitter"
+)
+
+// Run a query against a source snippet, failing the test if the query is invalid
+// or the snippet has parse errors. The matches are those of the query named by
+// its own source.
+func RunQuery(t testing.TB, lang treeutils.LanguageGrammar, query, source string) treeutils.Captures {
+	t.Helper()
+
+	if err := treeutils.RegisterQuery(lang, query, query); err != nil {
+		t.Fatalf("Invalid query: %v", err)
+	}
+
+	return RunNamedQueries(t, lang, source, query)
+}
+
+// Run queries registered with RegisterQuery against a source snippet, failing the
+// test if a query is not registered or the snippet has parse errors.
+func RunNamedQueries(t testing.TB, lang treeutils.LanguageGrammar, source string, queries ...string) treeutils.Captures {
+	t.Helper()
+
+	for _, name := range queries {
+		if _, found := treeutils.NamedQuery(lang, name); !found {
+			t.Fatalf("No %s query registered as %q", lang, name)
+		}
+	}
+
+	tree, err := treeutils.ParseSourceCode(lang, "snippet", []byte(source))
+	if err != nil {
+		t.Fatalf("Failed to parse snippet: %v\n%s", err, source)
+	}
+	defer tree.Close()
+
+	if errs := tree.QueryErrors(); len(errs) > 0 {
+		t.Fatalf("Snippet has parse errors: %v\n%s", errs, source)
+	}
+
+	return tree.Captures(queries...)
+}
+
+// Assert the source code captured by a name across all matches, in order.
+func AssertCaptured(t testing.TB, captures treeutils.Captures, name string, expected ...string) {
+	t.Helper()
+
+	actual := make([]string, 0)
+	for _, m := range captures {
+		for _, c := range m.Captures {
+			if c.Name == name {
+				actual = append(actual, c.Text)
+			}
+		}
+	}
+
+	if !slices.Equal(actual, expected) {
+		t.Errorf("Captured @%s...\nactual:   %#v\nexpected: %#v", name, actual, expected)
+	}
+}
+
+// Assert the number of matches of a query.
+func AssertMatchCount(t testing.TB, captures treeutils.Captures, query string, expected int) {
+	t.Helper()
+
+	actual := 0
+	for range captures.Matches(query) {
+		actual++
+	}
+
+	if actual != expected {
+		t.Errorf("Matches of %q...\nactual:   %d\nexpected: %d", query, actual, expected)
+	}
+}
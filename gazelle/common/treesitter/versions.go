@@ -0,0 +1,44 @@
+package treesitter
+
+import "maps"
+
+// The versions of the builtin grammars.
+// Must be updated along with grammars.bzl and the go-tree-sitter version of go.mod.
+var grammarVersions = map[LanguageGrammar]string{
+	JSON:        "tree-sitter-json@0.21.0",
+	Kotlin:      "tree-sitter-kotlin@0.3.5",
+	Starlark:    "tree-sitter-starlark@1.0.0",
+	Typescript:  "tree-sitter-typescript@0.20.6",
+	TypescriptX: "tree-sitter-typescript@0.20.6",
+	Java:        "go-tree-sitter@v0.0.0-20240827094217-dd81d9e9be82",
+	Scala:       "go-tree-sitter@v0.0.0-20240827094217-dd81d9e9be82",
+	Groovy:      "go-tree-sitter@v0.0.0-20240827094217-dd81d9e9be82",
+	Swift:       "go-tree-sitter@v0.0.0-20240827094217-dd81d9e9be82",
+}
+
+// The version of a grammar such as "tree-sitter-kotlin@0.3.5", changing whenever the
+// grammar is upgraded so caches of parse results can be invalidated. Grammars loaded
+// at runtime are versioned by the digest of their shared object.
+//
+// Empty for unknown grammars.
+func GrammarVersion(lang LanguageGrammar) string {
+	if version, isBuiltin := grammarVersions[lang]; isBuiltin {
+		return version
+	}
+
+	registeredGrammarsMutex.RLock()
+	defer registeredGrammarsMutex.RUnlock()
+
+	return registeredGrammarVersions[lang]
+}
+
+// The versions of all builtin and registered grammars, by grammar.
+func GrammarVersions() map[LanguageGrammar]string {
+	versions := maps.Clone(grammarVersions)
+
+	registeredGrammarsMutex.RLock()
+	defer registeredGrammarsMutex.RUnlock()
+
+	maps.Copy(versions, registeredGrammarVersions)
+	return versions
+}
@@ -1,6 +1,9 @@
 package gazelle
 
 import (
+	"io/fs"
+	"os"
+	"path"
 	"path/filepath"
 
 	BazelLog "aspect.build/cli/pkg/logger"
@@ -34,3 +37,127 @@ func GazelleWalkDir(args language.GenerateArgs, walkFunc GazelleWalkFunc) error
 
 	return nil
 }
+
+// Walk the files of the package of the language.GenerateArgs on disk including files
+// in sub-directories which are not Bazel packages. Paths excluded by `exclude`
+// directives, .bazelignore and .gitignore files are skipped, see IsIgnored.
+// Paths passed to the walkFunc are relative to the package.
+func GazelleWalkPackageFiles(args language.GenerateArgs, walkFunc GazelleWalkFunc) error {
+	BazelLog.Tracef("GazelleWalkPackageFiles: %s", args.Rel)
+
+	return filepath.WalkDir(args.Dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if p == args.Dir {
+			return nil
+		}
+
+		rel, err := filepath.Rel(args.Dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if IsIgnored(args.Config, path.Join(args.Rel, rel)) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if d.IsDir() {
+			if IsBazelPackage(args.Config, p) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if args.Config.IsValidBuildFileName(rel) {
+			return nil
+		}
+
+		walkErr := walkFunc(rel)
+		if walkErr == filepath.SkipDir {
+			return nil
+		}
+		return walkErr
+	})
+}
+
+// Walk the files of the package of the language.GenerateArgs like GazelleWalkDir, or like
+// GazelleWalkPackageFiles when recursive, also walking the files of symlinked directories
+// as if they were sub-directories of the package. Symlinked directories which are Bazel
+// packages or were already visited, such as symlinks to a parent directory, are skipped.
+// Paths passed to the walkFunc are relative to the package.
+func GazelleWalkFollowingSymlinks(args language.GenerateArgs, recursive bool, walkFunc GazelleWalkFunc) error {
+	BazelLog.Tracef("GazelleWalkFollowingSymlinks: %s", args.Rel)
+
+	err := walkFollowingSymlinks(args, args.Dir, "", recursive, make(map[string]bool), walkFunc)
+	if err == filepath.SkipDir {
+		return nil
+	}
+	return err
+}
+
+// Walk the files of a directory of the package, rel to the package, recursing into
+// symlinked directories and, if recursive, all sub-directories.
+func walkFollowingSymlinks(args language.GenerateArgs, dir, rel string, recursive bool, visited map[string]bool, walkFunc GazelleWalkFunc) error {
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	if visited[realDir] {
+		BazelLog.Tracef("GazelleWalkFollowingSymlinks skipping visited directory: %s", dir)
+		return nil
+	}
+	visited[realDir] = true
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		p := filepath.Join(dir, entry.Name())
+		entryRel := path.Join(rel, entry.Name())
+
+		if IsIgnored(args.Config, path.Join(args.Rel, entryRel)) {
+			continue
+		}
+
+		isSymlink := entry.Type()&fs.ModeSymlink != 0
+		isDir := entry.IsDir()
+		if isSymlink {
+			info, err := os.Stat(p)
+			if err != nil {
+				BazelLog.Tracef("GazelleWalkFollowingSymlinks skipping broken symlink: %s", p)
+				continue
+			}
+			isDir = info.IsDir()
+		}
+
+		if isDir {
+			if (!recursive && !isSymlink) || IsBazelPackage(args.Config, p) {
+				continue
+			}
+
+			// All sub-directories of symlinked directories are part of the package.
+			if err := walkFollowingSymlinks(args, p, entryRel, true, visited, walkFunc); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if args.Config.IsValidBuildFileName(entryRel) {
+			continue
+		}
+
+		if walkErr := walkFunc(entryRel); walkErr != nil && walkErr != filepath.SkipDir {
+			return walkErr
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package gazelle
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/language"
+)
+
+func TestGazelleWalkFollowingSymlinks(t *testing.T) {
+	root := t.TempDir()
+	for _, f := range []string{"pkg/A.kt", "pkg/sub/B.kt", "vendor/C.kt", "vendor/nested/D.kt", "other/BUILD.bazel", "other/E.kt"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(root, f)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, f), []byte{}, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for link, target := range map[string]string{
+		"pkg/vendored": "../vendor",
+		"pkg/loop":     ".",
+		"pkg/other":    "../other",
+		"pkg/broken":   "../missing",
+	} {
+		if err := os.Symlink(target, filepath.Join(root, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	args := language.GenerateArgs{
+		Config: config.New(),
+		Dir:    filepath.Join(root, "pkg"),
+		Rel:    "pkg",
+	}
+
+	walk := func(recursive bool) string {
+		files := make([]string, 0)
+		err := GazelleWalkFollowingSymlinks(args, recursive, func(f string) error {
+			files = append(files, f)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(files)
+		return strings.Join(files, ", ")
+	}
+
+	if actual, expected := walk(false), "A.kt, vendored/C.kt, vendored/nested/D.kt"; actual != expected {
+		t.Errorf("non-recursive walk...\nactual:  %q;\nexpected: %q", actual, expected)
+	}
+	if actual, expected := walk(true), "A.kt, sub/B.kt, vendored/C.kt, vendored/nested/D.kt"; actual != expected {
+		t.Errorf("recursive walk...\nactual:  %q;\nexpected: %q", actual, expected)
+	}
+}
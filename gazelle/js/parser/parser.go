@@ -70,6 +70,11 @@ func ParseSource(filePath, sourceCodeStr string) (ParseResult, []error) {
 	}
 
 	if tree != nil {
+		defer tree.Close()
+
+		// The source code normalized to UTF-8
+		sourceCode = tree.(treeutils.TreeAst).SourceCode()
+
 		rootNode := tree.(treeutils.TreeAst).SitterTree.RootNode()
 
 		// Quick pass over root nodes to find top level imports and modules
@@ -0,0 +1,179 @@
+package gazelle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"aspect.build/cli/gazelle/kotlin/kotlinconfig"
+	"aspect.build/cli/gazelle/kotlin/maven"
+	BazelLog "aspect.build/cli/pkg/logger"
+	"github.com/bazelbuild/bazel-gazelle/label"
+)
+
+// The version of the resolution cache file format, caches of other versions are discarded.
+const resolutionCacheVersion = 1
+
+// resolutionCache persists the maven resolutions of imported packages between runs.
+//
+// Resolutions are grouped by a fingerprint of the configuration used to resolve them,
+// including the digest of the maven_install.json file, so any change to the lock file
+// or the configuration invalidates the cached resolutions.
+type resolutionCache struct {
+	Version int `json:"version"`
+
+	// The label resolved for each package by fingerprint, empty if not provided by any artifact
+	Entries map[string]map[string]string `json:"entries"`
+
+	// The fingerprints used in this run, other fingerprints are dropped when saving
+	used map[string]bool
+
+	dirty bool
+}
+
+func newResolutionCache() *resolutionCache {
+	return &resolutionCache{
+		Version: resolutionCacheVersion,
+		Entries: make(map[string]map[string]string),
+		used:    make(map[string]bool),
+	}
+}
+
+// Load the resolution cache file, starting with an empty cache if the file
+// does not exist, can not be read or is of another version.
+func loadResolutionCache(cacheFile string) *resolutionCache {
+	cache := newResolutionCache()
+
+	content, err := os.ReadFile(cacheFile)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			BazelLog.Warnf("failed to read kotlin resolution cache %q: %v", cacheFile, err)
+		}
+		return cache
+	}
+
+	loaded := newResolutionCache()
+	if err := json.Unmarshal(content, loaded); err != nil {
+		BazelLog.Warnf("failed to parse kotlin resolution cache %q, discarding: %v", cacheFile, err)
+		return cache
+	}
+
+	if loaded.Version != resolutionCacheVersion || loaded.Entries == nil {
+		BazelLog.Debugf("discarding kotlin resolution cache %q of version %d", cacheFile, loaded.Version)
+		return cache
+	}
+
+	return loaded
+}
+
+// Get the label cached for the package, an empty string if the package is not
+// provided by any artifact. Returns false if the package is not cached.
+func (c *resolutionCache) get(fingerprint, pkg string) (string, bool) {
+	c.used[fingerprint] = true
+	l, found := c.Entries[fingerprint][pkg]
+	return l, found
+}
+
+func (c *resolutionCache) put(fingerprint, pkg, l string) {
+	if c.Entries[fingerprint] == nil {
+		c.Entries[fingerprint] = make(map[string]string)
+	}
+
+	c.used[fingerprint] = true
+	c.Entries[fingerprint][pkg] = l
+	c.dirty = true
+}
+
+// Write the resolutions of the fingerprints used in this run to the cache file.
+func (c *resolutionCache) save(cacheFile string) {
+	for fingerprint := range c.Entries {
+		if !c.used[fingerprint] {
+			delete(c.Entries, fingerprint)
+			c.dirty = true
+		}
+	}
+
+	if !c.dirty {
+		return
+	}
+
+	content, err := json.Marshal(c)
+	if err != nil {
+		BazelLog.Fatalf("failed to encode kotlin resolution cache: %v", err)
+	}
+
+	if err := os.WriteFile(cacheFile, content, 0644); err != nil {
+		BazelLog.Warnf("failed to write kotlin resolution cache %q: %v", cacheFile, err)
+	}
+}
+
+// The fingerprint of the configuration resolving imports against a maven repository.
+func (kt *kotlinLang) mavenFingerprint(cfg *kotlinconfig.KotlinConfig, repository kotlinconfig.MavenRepository) string {
+	artifacts := excludedArtifacts(cfg, repository)
+	excluded := make([]string, 0, len(artifacts))
+	for artifact := range artifacts {
+		excluded = append(excluded, artifact)
+	}
+	sort.Strings(excluded)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\n%s\n%s\n%s\n", cfg.MavenResolver(), repository.Name, kt.lockFileDigest(repository.InstallFile), strings.Join(excluded, ","))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// The digest of the content of a maven_install.json file, computed once per run.
+func (kt *kotlinLang) lockFileDigest(installFile string) string {
+	if digest, found := kt.lockFileDigests[installFile]; found {
+		return digest
+	}
+
+	digest := ""
+	if f, err := os.Open(installFile); err == nil {
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err == nil {
+			digest = hex.EncodeToString(h.Sum(nil))
+		}
+		f.Close()
+	}
+
+	if kt.lockFileDigests == nil {
+		kt.lockFileDigests = make(map[string]string)
+	}
+	kt.lockFileDigests[installFile] = digest
+	return digest
+}
+
+// Resolve a package against a maven repository using the resolution cache, if enabled.
+func (kt *kotlinLang) cachedMavenResolve(cfg *kotlinconfig.KotlinConfig, repository kotlinconfig.MavenRepository, pkg string, resolveFn func() (label.Label, error)) (label.Label, error) {
+	if kt.resolutionCache == nil {
+		return resolveFn()
+	}
+
+	fingerprint := kt.mavenFingerprint(cfg, repository)
+	if cached, found := kt.resolutionCache.get(fingerprint, pkg); found {
+		if cached == "" {
+			return label.NoLabel, fmt.Errorf("package %q not found in @%s (cached)", pkg, repository.Name)
+		}
+		if l, err := label.Parse(cached); err == nil {
+			return l, nil
+		}
+	}
+
+	l, err := resolveFn()
+
+	// Ambiguous packages are not cached so the candidates are reported on every run
+	var ambiguous *maven.AmbiguousPackageError
+	if err == nil {
+		kt.resolutionCache.put(fingerprint, pkg, l.String())
+	} else if !errors.As(err, &ambiguous) {
+		kt.resolutionCache.put(fingerprint, pkg, "")
+	}
+
+	return l, err
+}
@@ -0,0 +1,55 @@
+package gazelle
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolutionCache(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		cacheFile := filepath.Join(t.TempDir(), "cache.json")
+
+		cache := loadResolutionCache(cacheFile)
+		cache.put("a", "com.google.common.base", "@maven//:com_google_guava_guava")
+		cache.put("a", "org.unknown", "")
+		cache.save(cacheFile)
+
+		loaded := loadResolutionCache(cacheFile)
+		l, found := loaded.get("a", "com.google.common.base")
+		assertTrue(t, found && l == "@maven//:com_google_guava_guava", "resolved package should be cached")
+
+		l, found = loaded.get("a", "org.unknown")
+		assertTrue(t, found && l == "", "package not found should be cached")
+
+		_, found = loaded.get("b", "com.google.common.base")
+		assertTrue(t, !found, "other fingerprints should not be cached")
+	})
+
+	t.Run("unused fingerprints are dropped", func(t *testing.T) {
+		cacheFile := filepath.Join(t.TempDir(), "cache.json")
+
+		cache := loadResolutionCache(cacheFile)
+		cache.put("old", "com.google.common.base", "@maven//:com_google_guava_guava")
+		cache.save(cacheFile)
+
+		cache = loadResolutionCache(cacheFile)
+		cache.put("new", "com.google.common.base", "@maven//:com_google_guava_guava")
+		cache.save(cacheFile)
+
+		loaded := loadResolutionCache(cacheFile)
+		_, found := loaded.Entries["old"]
+		assertTrue(t, !found, "fingerprints not used in the run should be dropped")
+	})
+
+	t.Run("other versions are discarded", func(t *testing.T) {
+		cacheFile := filepath.Join(t.TempDir(), "cache.json")
+		content := `{"version": 0, "entries": {"a": {"com.google.common.base": "@maven//:old"}}}`
+		if err := os.WriteFile(cacheFile, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		_, found := loadResolutionCache(cacheFile).get("a", "com.google.common.base")
+		assertTrue(t, !found, "caches of other versions should be discarded")
+	})
+}
@@ -0,0 +1,241 @@
+package gazelle
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+
+	gazelle "aspect.build/cli/gazelle/common"
+	BazelLog "aspect.build/cli/pkg/logger"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	bzl "github.com/bazelbuild/buildtools/build"
+	"github.com/emirpasic/gods/sets/treeset"
+)
+
+type RuleChangeAction string
+
+const (
+	RuleChangeAdd    RuleChangeAction = "add"
+	RuleChangeUpdate RuleChangeAction = "update"
+	RuleChangeRemove RuleChangeAction = "remove"
+)
+
+// RuleChange is a change gazelle would apply to a rule in a BUILD file.
+//
+// Changes include the attributes generated by GenerateRules and the attributes
+// such as `deps` computed when resolving dependencies. Values marked `# keep`
+// in the existing rule are never reported as removed.
+type RuleChange struct {
+	Package string           `json:"package"`
+	Name    string           `json:"name"`
+	Kind    string           `json:"kind"`
+	Action  RuleChangeAction `json:"action"`
+
+	// Attribute values added or removed, by attribute name.
+	Added   map[string][]string `json:"added,omitempty"`
+	Removed map[string][]string `json:"removed,omitempty"`
+}
+
+// existingResolveAttrsKey is the name of a private attribute set on generated rules
+// containing the values of the resolved attributes of the existing rule, by attribute name.
+const existingResolveAttrsKey = "_kotlin_existing_resolve_attrs"
+
+// Record the changes the language.GenerateResult would apply to the existing BUILD file.
+// Changes of the resolved attributes are recorded once resolved by recordResolvedChanges.
+func (kt *kotlinLang) recordChanges(args language.GenerateArgs, result *language.GenerateResult) {
+	if kt.changes == nil {
+		kt.changes = make(map[label.Label]*RuleChange)
+	}
+
+	for _, r := range result.Gen {
+		existing := gazelle.GetFileRuleByName(args, r.Name())
+		if existing != nil && existing.Kind() != gazelle.MapKind(args, r.Kind()) {
+			existing = nil
+		}
+
+		change := &RuleChange{
+			Package: args.Rel,
+			Name:    r.Name(),
+			Kind:    r.Kind(),
+			Action:  RuleChangeUpdate,
+			Added:   make(map[string][]string),
+			Removed: make(map[string][]string),
+		}
+
+		if existing == nil {
+			change.Action = RuleChangeAdd
+		}
+
+		resolveAttrs := kotlinKinds[r.Kind()].ResolveAttrs
+
+		for _, attr := range r.AttrKeys() {
+			if attr == "name" || resolveAttrs[attr] {
+				continue
+			}
+
+			var oldValues []string
+			if existing != nil {
+				oldValues = attrValues(existing, attr)
+			}
+
+			change.diff(attr, oldValues, attrValues(r, attr))
+		}
+
+		// The resolved attributes of the existing rule, compared once resolved.
+		existingResolved := make(map[string][]string, len(resolveAttrs))
+		if existing != nil {
+			for attr := range resolveAttrs {
+				existingResolved[attr] = unkeptAttrValues(existing, attr)
+			}
+		}
+		r.SetPrivateAttr(existingResolveAttrsKey, existingResolved)
+
+		kt.changes[label.New("", args.Rel, r.Name())] = change
+	}
+
+	for _, r := range result.Empty {
+		if existing := gazelle.GetFileRuleByName(args, r.Name()); existing != nil && existing.Kind() == gazelle.MapKind(args, r.Kind()) {
+			kt.changes[label.New("", args.Rel, r.Name())] = &RuleChange{
+				Package: args.Rel,
+				Name:    r.Name(),
+				Kind:    existing.Kind(),
+				Action:  RuleChangeRemove,
+			}
+		}
+	}
+}
+
+// Record the changes of the resolved attributes of a rule generated in this run.
+func (kt *kotlinLang) recordResolvedChanges(r *rule.Rule, from label.Label) {
+	change := kt.changes[label.New("", from.Pkg, from.Name)]
+	if change == nil {
+		return
+	}
+
+	existingResolved, _ := r.PrivateAttr(existingResolveAttrsKey).(map[string][]string)
+
+	for attr := range kotlinKinds[r.Kind()].ResolveAttrs {
+		oldValues := normalizeLabels(existingResolved[attr], from)
+		newValues := normalizeLabels(attrValues(r, attr), from)
+
+		change.diff(attr, oldValues, newValues)
+	}
+}
+
+// Record the values of an attribute added and removed.
+func (change *RuleChange) diff(attr string, oldValues, newValues []string) {
+	oldSet, newSet := toStringSet(oldValues), toStringSet(newValues)
+
+	if added := newSet.Difference(oldSet); !added.Empty() {
+		change.Added[attr] = toStrings(added)
+	}
+	if removed := oldSet.Difference(newSet); !removed.Empty() {
+		change.Removed[attr] = toStrings(removed)
+	}
+}
+
+// Write the recorded changes to the change report file, if enabled.
+func (kt *kotlinLang) writeChangeReport() {
+	if kt.changeReportFile == "" {
+		return
+	}
+
+	changes := make([]RuleChange, 0, len(kt.changes))
+	for _, change := range kt.changes {
+		if change.Action != RuleChangeUpdate || len(change.Added) > 0 || len(change.Removed) > 0 {
+			changes = append(changes, *change)
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		return a.Name < b.Name
+	})
+
+	content, err := json.MarshalIndent(changes, "", "  ")
+	if err != nil {
+		BazelLog.Fatalf("failed to encode kotlin change report: %v", err)
+	}
+
+	if err := os.WriteFile(kt.changeReportFile, append(content, '\n'), 0644); err != nil {
+		BazelLog.Fatalf("failed to write kotlin change report %q: %v", kt.changeReportFile, err)
+	}
+}
+
+// The values of a rule attribute as strings. Non-string values are formatted as starlark.
+func attrValues(r *rule.Rule, attr string) []string {
+	expr := r.Attr(attr)
+	if expr == nil {
+		return nil
+	}
+
+	list, isList := expr.(*bzl.ListExpr)
+	if !isList {
+		return []string{exprString(expr)}
+	}
+
+	values := make([]string, 0, len(list.List))
+	for _, e := range list.List {
+		values = append(values, exprString(e))
+	}
+	return values
+}
+
+// The values of a rule attribute as strings, excluding values marked `# keep`.
+func unkeptAttrValues(r *rule.Rule, attr string) []string {
+	if list, isList := r.Attr(attr).(*bzl.ListExpr); isList {
+		values := make([]string, 0, len(list.List))
+		for _, e := range list.List {
+			if !rule.ShouldKeep(e) {
+				values = append(values, exprString(e))
+			}
+		}
+		return values
+	}
+
+	return attrValues(r, attr)
+}
+
+// Convert label values to absolute labels so equal labels written differently are compared
+// equally. Values which are not labels, such as `True`, are returned as is.
+func normalizeLabels(values []string, from label.Label) []string {
+	normalized := make([]string, 0, len(values))
+	for _, v := range values {
+		if strings.HasPrefix(v, ":") || strings.HasPrefix(v, "//") || strings.HasPrefix(v, "@") {
+			if l, err := label.Parse(v); err == nil {
+				v = l.Abs("", from.Pkg).String()
+			}
+		}
+		normalized = append(normalized, v)
+	}
+	return normalized
+}
+
+func exprString(expr bzl.Expr) string {
+	if str, isStr := expr.(*bzl.StringExpr); isStr {
+		return str.Value
+	}
+	return bzl.FormatString(expr)
+}
+
+func toStringSet(values []string) *treeset.Set {
+	set := treeset.NewWithStringComparator()
+	for _, v := range values {
+		set.Add(v)
+	}
+	return set
+}
+
+func toStrings(set *treeset.Set) []string {
+	values := make([]string, 0, set.Size())
+	for _, v := range set.Values() {
+		values = append(values, v.(string))
+	}
+	return values
+}
@@ -0,0 +1,112 @@
+// depgraph runs the kotlin gazelle extension over a tree of kotlin sources without
+// writing BUILD files, and exports the graph of the generated rules and the imports
+// resolved to their dependencies as a DOT digraph or JSON, for visualizing the
+// dependencies and auditing the architecture of a repository.
+//
+// Usage:
+//
+//	depgraph [-root dir] [-format dot|json] [-o file] [gazelle flag ...] [dir ...]
+//
+// Imports are resolved as when running gazelle, using the directives of the BUILD
+// files and the maven_install.json files of the repository. The gazelle flags and
+// directories following the depgraph flags, such as `-kotlin-directive`, are passed
+// to gazelle, all directories of the repository are visited by default.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	kotlin "aspect.build/cli/gazelle/kotlin"
+	"aspect.build/cli/gazelle/kotlin/cmd/internal/profiling"
+	"aspect.build/cli/gazelle/kotlin/cmd/internal/rungazelle"
+)
+
+func main() {
+	root := flag.String("root", defaultRoot(), "The directory of the Bazel workspace containing the sources, by default the workspace of `bazel run`.")
+	format := flag.String("format", kotlin.DepGraphFormat_DOT, fmt.Sprintf("The format of the graph, %q or %q.", kotlin.DepGraphFormat_DOT, kotlin.DepGraphFormat_JSON))
+	output := flag.String("o", "", "Path of the file to write the graph to instead of printing it.")
+	profiling.RegisterFlags()
+	flag.Parse()
+
+	if *format != kotlin.DepGraphFormat_DOT && *format != kotlin.DepGraphFormat_JSON {
+		fmt.Fprintf(os.Stderr, "Invalid -format: %q, must be %q or %q\n", *format, kotlin.DepGraphFormat_DOT, kotlin.DepGraphFormat_JSON)
+		os.Exit(2)
+	}
+
+	if err := profiling.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to start profiling: %v\n", err)
+		os.Exit(2)
+	}
+	defer profiling.Stop()
+
+	graphFile, err := graphPath(*output)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create the graph file: %v\n", err)
+		profiling.Exit(1)
+	}
+	if *output == "" {
+		defer os.Remove(graphFile)
+	}
+
+	// gazelle resolves the repository and the output paths relative to the working directory.
+	if err := os.Chdir(*root); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to change to the root %s: %v\n", *root, err)
+		profiling.Exit(2)
+	}
+
+	if err := rungazelle.ExportDepGraph(graphFile, *format, flag.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to export the kotlin dependency graph: %v\n", err)
+		profiling.Exit(1)
+	}
+
+	if *output == "" {
+		if err := printFile(os.Stdout, graphFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read the graph: %v\n", err)
+			profiling.Exit(1)
+		}
+	}
+}
+
+// The absolute path of the file to write the graph to, a temporary file if printed.
+func graphPath(output string) (string, error) {
+	if output == "" {
+		f, err := os.CreateTemp("", "depgraph-*")
+		if err != nil {
+			return "", err
+		}
+		return f.Name(), f.Close()
+	}
+
+	// Relative to the working directory, before changing to the root.
+	if filepath.IsAbs(output) {
+		return output, nil
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(wd, output), nil
+}
+
+func printFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// The workspace directory when run using `bazel run`, otherwise the working directory.
+func defaultRoot() string {
+	if dir := os.Getenv("BUILD_WORKSPACE_DIRECTORY"); dir != "" {
+		return dir
+	}
+	return "."
+}
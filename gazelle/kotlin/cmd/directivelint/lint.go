@@ -0,0 +1,470 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	common "aspect.build/cli/gazelle/common"
+	"aspect.build/cli/gazelle/common/git"
+	kotlin "aspect.build/cli/gazelle/kotlin"
+	"aspect.build/cli/gazelle/kotlin/kotlinconfig"
+	jvm_javaconfig "github.com/bazel-contrib/rules_jvm/java/gazelle/javaconfig"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	bzl "github.com/bazelbuild/buildtools/build"
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// The names of BUILD files, in order of precedence.
+var buildFileNames = []string{"BUILD.bazel", "BUILD"}
+
+// The prefixes of the keys of the linted directives.
+var lintedPrefixes = []string{"kotlin_", "java_"}
+
+// The directives of the java extension, also known when not read by the kotlin extension.
+var javaDirectives = []string{
+	jvm_javaconfig.JavaExcludeArtifact,
+	jvm_javaconfig.JavaExtensionDirective,
+	jvm_javaconfig.JavaMavenInstallFile,
+	jvm_javaconfig.JavaModuleGranularityDirective,
+	jvm_javaconfig.JavaTestFileSuffixes,
+	jvm_javaconfig.JavaTestMode,
+	jvm_javaconfig.JavaGenerateProto,
+	jvm_javaconfig.JavaMavenRepositoryName,
+	jvm_javaconfig.JavaAnnotationProcessorPlugin,
+}
+
+// The directives setting a single value inherited by sub-directories, which a later
+// directive of the same BUILD file or of a sub-directory replaces.
+var settingDirectives = map[string]bool{
+	kotlinconfig.Directive_KotlinExtension:          true,
+	kotlinconfig.Directive_JavaSources:              true,
+	kotlinconfig.Directive_Tags:                     true,
+	kotlinconfig.Directive_ModuleName:               true,
+	kotlinconfig.Directive_Associates:               true,
+	kotlinconfig.Directive_Lint:                     true,
+	kotlinconfig.Directive_LintConfig:               true,
+	kotlinconfig.Directive_Cleanup:                  true,
+	kotlinconfig.Directive_ResourceStripPrefix:      true,
+	kotlinconfig.Directive_NameCollision:            true,
+	kotlinconfig.Directive_GenerateTests:            true,
+	kotlinconfig.Directive_GenerateLibraries:        true,
+	kotlinconfig.Directive_TestSources:              true,
+	kotlinconfig.Directive_Testonly:                 true,
+	kotlinconfig.Directive_GenerateBinaries:         true,
+	kotlinconfig.Directive_InferTestonly:            true,
+	kotlinconfig.Directive_StrictDeps:               true,
+	kotlinconfig.Directive_ValidateImportStatements: true,
+	kotlinconfig.Directive_ValidateTestonly:         true,
+	kotlinconfig.Directive_MavenInstallFile:         true,
+	kotlinconfig.Directive_MavenRepositoryName:      true,
+	kotlinconfig.Directive_MavenResolver:            true,
+	kotlinconfig.Directive_PackageFallbackDepth:     true,
+	kotlinconfig.Directive_FallbackDep:              true,
+	kotlinconfig.Directive_ResolutionTrace:          true,
+	kotlinconfig.Directive_FollowSymlinks:           true,
+	kotlinconfig.Directive_TestFileSuffixes:         true,
+	kotlinconfig.Directive_GenerationMode:           true,
+	kotlinconfig.Directive_LanguageVersion:          true,
+	kotlinconfig.Directive_ApiVersion:               true,
+	kotlinconfig.Directive_ModuleRootMarkers:        true,
+	jvm_javaconfig.JavaMavenInstallFile:             true,
+	jvm_javaconfig.JavaMavenRepositoryName:          true,
+}
+
+// A directive comment, as parsed by gazelle.
+var directiveRegexp = regexp.MustCompile(`^#\s*gazelle:(\w+)\s*(.*?)\s*$`)
+
+// The extensions of the sources of a module root.
+var sourceExtensions = []string{".kt", ".kts", ".java"}
+
+// A directive of a BUILD file.
+type directive struct {
+	rule.Directive
+
+	// The path of the BUILD file relative to the root, and the line of the directive
+	file string
+	line int
+}
+
+func (d directive) String() string {
+	return fmt.Sprintf("%s:%d", d.file, d.line)
+}
+
+// A BUILD file and its directives.
+type buildFile struct {
+	pkg        string
+	directives []directive
+}
+
+// A problem of a directive.
+type issue struct {
+	directive directive
+	message   string
+}
+
+func (i issue) String() string {
+	return fmt.Sprintf("%s: %s: %s", i.directive, i.directive.Key, i.message)
+}
+
+// The BUILD files and other files of a tree, relative to the root.
+type tree struct {
+	buildFiles []*buildFile
+
+	// The files of each directory, by directory
+	files map[string][]string
+}
+
+// Read the BUILD files of the tree of the root and their directives, sorted by package.
+// Files and directories ignored by the .bazelignore file or a .gitignore file are
+// skipped, as in the gazelle walk.
+func readTree(root string) (*tree, error) {
+	c := config.New()
+	c.RepoRoot = root
+	git.EnableGitignore(c, true)
+	common.CollectExcludes(c, "", nil)
+
+	t := &tree{files: make(map[string][]string)}
+
+	err := fs.WalkDir(os.DirFS(root), ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if p == "." {
+				git.CollectIgnoreFiles(c, "")
+				return nil
+			}
+			if common.IsIgnored(c, p) {
+				return fs.SkipDir
+			}
+			git.CollectIgnoreFiles(c, p)
+			return nil
+		}
+
+		if !d.Type().IsRegular() || common.IsIgnored(c, p) {
+			return nil
+		}
+
+		dir := path.Dir(p)
+		if dir == "." {
+			dir = ""
+		}
+		t.files[dir] = append(t.files[dir], path.Base(p))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for dir, files := range t.files {
+		for _, name := range buildFileNames {
+			if !contains(files, name) {
+				continue
+			}
+
+			f, err := readBuildFile(root, path.Join(dir, name))
+			if err != nil {
+				return nil, err
+			}
+			f.pkg = dir
+			t.buildFiles = append(t.buildFiles, f)
+			break
+		}
+	}
+
+	sort.Slice(t.buildFiles, func(i, j int) bool {
+		return t.buildFiles[i].pkg < t.buildFiles[j].pkg
+	})
+
+	return t, nil
+}
+
+// Read the directives of a BUILD file relative to the root, as gazelle parses them.
+func readBuildFile(root, file string) (*buildFile, error) {
+	content, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(file)))
+	if err != nil {
+		return nil, err
+	}
+
+	ast, err := bzl.ParseBuild(file, content)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &buildFile{}
+	addComment := func(com bzl.Comment) {
+		if match := directiveRegexp.FindStringSubmatch(com.Token); match != nil {
+			f.directives = append(f.directives, directive{
+				Directive: rule.Directive{Key: match[1], Value: match[2]},
+				file:      file,
+				line:      com.Start.Line,
+			})
+		}
+	}
+	for _, stmt := range ast.Stmt {
+		for _, com := range stmt.Comment().Before {
+			addComment(com)
+		}
+		for _, com := range stmt.Comment().After {
+			addComment(com)
+		}
+	}
+
+	return f, nil
+}
+
+// Lint the directives of the BUILD files of a tree, reporting unknown directives,
+// invalid values, settings overridden within a BUILD file or redundant with the
+// inherited value, and directives referencing files which do not exist.
+func lintTree(root string, t *tree, known []string) []issue {
+	knownDirectives := make(map[string]bool)
+	for _, key := range append(append([]string{}, known...), javaDirectives...) {
+		knownDirectives[key] = true
+	}
+
+	issues := make([]issue, 0)
+
+	// The settings of each package, including the inherited settings
+	settings := make(map[string]map[string]directive)
+
+	for _, f := range t.buildFiles {
+		inherited := inheritedSettings(settings, f.pkg)
+		pkgSettings := make(map[string]directive, len(inherited))
+		for key, d := range inherited {
+			pkgSettings[key] = d
+		}
+
+		for i, d := range f.directives {
+			if !isLinted(d.Key) && !knownDirectives[d.Key] {
+				continue
+			}
+
+			if !knownDirectives[d.Key] {
+				message := "unknown directive"
+				if suggestion := closestDirective(d.Key, knownDirectives); suggestion != "" {
+					message += fmt.Sprintf(", did you mean %q?", suggestion)
+				}
+				issues = append(issues, issue{d, message})
+				continue
+			}
+
+			if err := kotlin.ValidateDirective(d.Directive); err != nil {
+				issues = append(issues, issue{d, err.Error()})
+				continue
+			}
+
+			if settingDirectives[d.Key] {
+				if later := laterDirective(f.directives[i+1:], d.Key); later != nil {
+					issues = append(issues, issue{d, fmt.Sprintf("overridden by the directive of line %d", later.line)})
+					continue
+				}
+				if parent, isInherited := inherited[d.Key]; isInherited && strings.TrimSpace(parent.Value) == strings.TrimSpace(d.Value) {
+					issues = append(issues, issue{d, fmt.Sprintf("redundant, %q is inherited from %s", strings.TrimSpace(d.Value), parent)})
+				}
+				pkgSettings[d.Key] = d
+			}
+
+			if message := t.staleDirective(root, f.pkg, d); message != "" {
+				issues = append(issues, issue{d, message})
+			}
+		}
+
+		settings[f.pkg] = pkgSettings
+	}
+
+	return issues
+}
+
+func isLinted(key string) bool {
+	for _, prefix := range lintedPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// The settings inherited by a package from the closest parent package.
+func inheritedSettings(settings map[string]map[string]directive, pkg string) map[string]directive {
+	for pkg != "" {
+		pkg = path.Dir(pkg)
+		if pkg == "." {
+			pkg = ""
+		}
+		if s, exists := settings[pkg]; exists {
+			return s
+		}
+	}
+	return nil
+}
+
+func laterDirective(directives []directive, key string) *directive {
+	for i := len(directives) - 1; i >= 0; i-- {
+		if directives[i].Key == key {
+			return &directives[i]
+		}
+	}
+	return nil
+}
+
+// The reason a directive no longer applies to any file, empty if it does.
+func (t *tree) staleDirective(root, pkg string, d directive) string {
+	value := strings.TrimSpace(d.Value)
+	parts := strings.Fields(d.Value)
+
+	switch d.Key {
+	case kotlinconfig.Directive_MainClass:
+		if !t.exists(path.Join(pkg, parts[0])) {
+			return fmt.Sprintf("the file %s does not exist", parts[0])
+		}
+
+	case kotlinconfig.Directive_Data, kotlinconfig.Directive_Resources:
+		if value != "" && !t.matchesAny(pkg, value) {
+			return fmt.Sprintf("the pattern %s matches no files", value)
+		}
+
+	case kotlinconfig.Directive_ResourceStripPrefix:
+		if value != "" && !isDir(root, path.Join(pkg, value)) {
+			return fmt.Sprintf("the directory %s does not exist", value)
+		}
+
+	case kotlinconfig.Directive_ModuleRoot:
+		if !t.containsSources(pkg) {
+			return "the module contains no kotlin or java sources"
+		}
+
+	case kotlinconfig.Directive_MavenInstallFile, kotlinconfig.Directive_SymbolIndex, jvm_javaconfig.JavaMavenInstallFile:
+		if value != "" && !t.exists(value) {
+			return fmt.Sprintf("the file %s does not exist", value)
+		}
+
+	case kotlinconfig.Directive_MavenRepository:
+		if len(parts) == 2 && !t.exists(parts[1]) {
+			return fmt.Sprintf("the file %s does not exist", parts[1])
+		}
+	}
+
+	return ""
+}
+
+// Whether a file relative to the root exists and is not ignored.
+func (t *tree) exists(file string) bool {
+	dir := path.Dir(file)
+	if dir == "." {
+		dir = ""
+	}
+	return contains(t.files[dir], path.Base(file))
+}
+
+// Whether a glob pattern relative to each BUILD file matches a file of the directory or
+// its sub-directories, relative to the directory or to a sub-directory inheriting it.
+func (t *tree) matchesAny(dir, pattern string) bool {
+	for fileDir, files := range t.files {
+		if !isWithin(fileDir, dir) {
+			continue
+		}
+
+		for _, name := range files {
+			file := path.Join(fileDir, name)
+			for base := fileDir; ; base = parentDir(base) {
+				if matched, _ := doublestar.Match(pattern, relativeTo(file, base)); matched {
+					return true
+				}
+				if base == dir {
+					break
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Whether the directory or its sub-directories contain kotlin or java sources.
+func (t *tree) containsSources(dir string) bool {
+	for fileDir, files := range t.files {
+		if !isWithin(fileDir, dir) {
+			continue
+		}
+		for _, name := range files {
+			if contains(sourceExtensions, path.Ext(name)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Whether a directory relative to the root is the directory or a sub-directory of another.
+func isWithin(dir, parent string) bool {
+	return parent == "" || dir == parent || strings.HasPrefix(dir, parent+"/")
+}
+
+func parentDir(dir string) string {
+	if parent := path.Dir(dir); parent != "." {
+		return parent
+	}
+	return ""
+}
+
+func relativeTo(file, dir string) string {
+	if dir == "" {
+		return file
+	}
+	return strings.TrimPrefix(file, dir+"/")
+}
+
+func isDir(root, dir string) bool {
+	info, err := os.Stat(filepath.Join(root, filepath.FromSlash(dir)))
+	return err == nil && info.IsDir()
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// The known directive closest to a misspelled key, empty if none is close.
+func closestDirective(key string, known map[string]bool) string {
+	closest, closestDistance := "", 4
+	for k := range known {
+		if d := editDistance(key, k); d < closestDistance || (d == closestDistance && k < closest) {
+			closest, closestDistance = k, d
+		}
+	}
+	return closest
+}
+
+// The Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLintTree(t *testing.T) {
+	root := t.TempDir()
+
+	writeFile(t, root, "BUILD.bazel", `# gazelle:kotlin_generate_tests enabled
+# gazelle:kotlin_generation_mode package
+# gazelle:kotlin_generation_mode directory
+# gazelle:kotlin_generate_test enabled
+# gazelle:kotlin_strict_deps maybe
+# gazelle:java_test_mode file
+# gazelle:resolve kotlin kotlin com.example //lib
+`)
+	writeFile(t, root, "app/BUILD.bazel", `# gazelle:kotlin_generate_tests enabled
+# gazelle:kotlin_main_class Gone.kt com.example.GoneKt
+# gazelle:kotlin_main_class Main.kt com.example.MainKt
+# gazelle:kotlin_data testdata/**
+# gazelle:kotlin_resources **/*.properties
+
+kt_jvm_library(name = "app")
+`)
+	writeFile(t, root, "app/Main.kt", "package com.example")
+	writeFile(t, root, "app/sub/app.properties", "")
+	writeFile(t, root, "empty/BUILD", "# gazelle:kotlin_module_root\n# gazelle:kotlin_maven_install_file missing_install.json\n")
+	writeFile(t, root, "empty/README.md", "")
+	writeFile(t, root, "ignored/BUILD.bazel", "# gazelle:kotlin_unknown\n")
+	writeFile(t, root, ".bazelignore", "ignored\n")
+
+	tr, err := readTree(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	known := []string{
+		"kotlin_generate_tests",
+		"kotlin_generation_mode",
+		"kotlin_strict_deps",
+		"kotlin_main_class",
+		"kotlin_data",
+		"kotlin_resources",
+		"kotlin_module_root",
+		"kotlin_maven_install_file",
+	}
+
+	actual := make([]string, 0)
+	for _, i := range lintTree(root, tr, known) {
+		actual = append(actual, i.String())
+	}
+
+	expected := []string{
+		`BUILD.bazel:2: kotlin_generation_mode: overridden by the directive of line 3`,
+		`BUILD.bazel:4: kotlin_generate_test: unknown directive, did you mean "kotlin_generate_tests"?`,
+		`BUILD.bazel:5: kotlin_strict_deps: invalid value for directive "kotlin_strict_deps": maybe: expected enabled or disabled`,
+		`app/BUILD.bazel:1: kotlin_generate_tests: redundant, "enabled" is inherited from BUILD.bazel:1`,
+		`app/BUILD.bazel:2: kotlin_main_class: the file Gone.kt does not exist`,
+		`app/BUILD.bazel:4: kotlin_data: the pattern testdata/** matches no files`,
+		`empty/BUILD:1: kotlin_module_root: the module contains no kotlin or java sources`,
+		`empty/BUILD:2: kotlin_maven_install_file: the file missing_install.json does not exist`,
+	}
+	if !slices.Equal(actual, expected) {
+		t.Errorf("lintTree()...\nactual:\n%v\nexpected:\n%v", actual, expected)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	for _, c := range []struct {
+		a, b     string
+		expected int
+	}{
+		{"kotlin_tags", "kotlin_tags", 0},
+		{"kotlin_tag", "kotlin_tags", 1},
+		{"kotlin_gnerate_tsts", "kotlin_generate_tests", 2},
+		{"", "abc", 3},
+	} {
+		if actual := editDistance(c.a, c.b); actual != c.expected {
+			t.Errorf("editDistance(%q, %q): %d, expected %d", c.a, c.b, actual, c.expected)
+		}
+	}
+}
+
+func writeFile(t *testing.T, root, file, content string) {
+	t.Helper()
+
+	p := filepath.Join(root, file)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
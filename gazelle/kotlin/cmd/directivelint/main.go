@@ -0,0 +1,61 @@
+// directivelint checks the kotlin and java directives of the BUILD files of a repository,
+// reporting unknown directives such as misspellings, invalid values gazelle would fail
+// with, settings overridden within a BUILD file or redundant with the value inherited from
+// a parent directory, and directives referencing files which no longer exist such as
+// `kotlin_main_class` files, `kotlin_data` patterns matching no files and module roots
+// without sources.
+//
+// Usage:
+//
+//	directivelint [-root dir]
+//
+// The issues are printed with the BUILD file and line of each directive, and the exit
+// code is 1 if there are any. Files and directories ignored by the .bazelignore file or
+// a .gitignore file are skipped.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	kotlin "aspect.build/cli/gazelle/kotlin"
+	"aspect.build/cli/gazelle/kotlin/cmd/internal/profiling"
+)
+
+func main() {
+	root := flag.String("root", defaultRoot(), "The directory of the Bazel workspace, by default the workspace of `bazel run`.")
+	profiling.RegisterFlags()
+	flag.Parse()
+
+	if err := profiling.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to start profiling: %v\n", err)
+		os.Exit(2)
+	}
+	defer profiling.Stop()
+
+	t, err := readTree(*root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read the BUILD files: %v\n", err)
+		profiling.Exit(2)
+	}
+
+	issues := lintTree(*root, t, kotlin.NewLanguage().KnownDirectives())
+	for _, i := range issues {
+		fmt.Println(i)
+	}
+
+	if len(issues) > 0 {
+		fmt.Fprintf(os.Stderr, "%d issue(s) in the directives of %d BUILD file(s)\n", len(issues), len(t.buildFiles))
+		profiling.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "No issues in the directives of %d BUILD file(s)\n", len(t.buildFiles))
+}
+
+// The workspace directory when run using `bazel run`, otherwise the working directory.
+func defaultRoot() string {
+	if dir := os.Getenv("BUILD_WORKSPACE_DIRECTORY"); dir != "" {
+		return dir
+	}
+	return "."
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"strings"
+
+	treeutils "aspect.build/cli/gazelle/common/treesitter"
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// A method call or assignment of a Groovy or Kotlin Gradle script, independent of the
+// grammar of the script, such as `implementation("com.google.guava:guava:33.0.0-jre")`
+// within a `dependencies { ... }` block.
+type gradleCall struct {
+	// The name of the called method such as `implementation` or `kotlin.srcDir`, or the
+	// assigned property followed by `=` such as `srcDirs=`.
+	name string
+
+	// The string literal arguments, or the string literals of an assigned list
+	args []string
+
+	// The named string arguments such as `group: "junit"`
+	namedArgs map[string]string
+
+	// The method call arguments such as `project(":lib")`, or the assigned call
+	callArgs []*gradleCall
+
+	// The other arguments such as `libs.okhttp`, as written
+	otherArgs []string
+
+	// The calls of the closure or lambda argument
+	block []*gradleCall
+}
+
+// The strings of the arguments of the call and of the calls of its arguments.
+func (c *gradleCall) allArgs() []string {
+	args := append([]string{}, c.args...)
+	for _, callArg := range c.callArgs {
+		args = append(args, callArg.allArgs()...)
+	}
+	return args
+}
+
+// Parse the top-level calls of a Gradle script, `.kts` scripts as Kotlin and other
+// scripts as Groovy. Calls within unsupported constructs such as conditionals are
+// included as if they were top-level calls of the enclosing block.
+func parseGradleScript(filePath string, content []byte) ([]*gradleCall, error) {
+	var lang treeutils.LanguageGrammar = treeutils.Groovy
+	if strings.HasSuffix(filePath, ".kts") {
+		lang = treeutils.Kotlin
+	}
+
+	tree, err := treeutils.ParseSourceCode(lang, filePath, content)
+	if tree == nil {
+		return nil, err
+	}
+	defer tree.Close()
+
+	s := &gradleScript{
+		lang:       lang,
+		sourceCode: tree.(treeutils.TreeAst).SourceCode(),
+	}
+	return s.calls(tree.(treeutils.TreeAst).SitterTree.RootNode()), err
+}
+
+type gradleScript struct {
+	lang       treeutils.LanguageGrammar
+	sourceCode []byte
+}
+
+func (s *gradleScript) text(node *sitter.Node) string {
+	return node.Content(s.sourceCode)
+}
+
+// The calls of the node and its descendants not within another call.
+func (s *gradleScript) calls(node *sitter.Node) []*gradleCall {
+	calls := make([]*gradleCall, 0)
+
+	for i := 0; i < int(node.NamedChildCount()); i++ {
+		child := node.NamedChild(i)
+
+		var call *gradleCall
+		if s.lang == treeutils.Kotlin {
+			call = s.kotlinCall(child)
+		} else {
+			call = s.groovyCall(child)
+		}
+
+		if call != nil {
+			calls = append(calls, call)
+		} else {
+			calls = append(calls, s.calls(child)...)
+		}
+	}
+
+	return calls
+}
+
+// The call of a Groovy `function_call`, `juxt_function_call` or `assignment` node.
+func (s *gradleScript) groovyCall(node *sitter.Node) *gradleCall {
+	switch node.Type() {
+	case "function_call", "juxt_function_call":
+		function := node.ChildByFieldName("function")
+		args := node.ChildByFieldName("args")
+		if function == nil {
+			return nil
+		}
+
+		call := &gradleCall{name: s.text(function), namedArgs: make(map[string]string)}
+		if args != nil {
+			for i := 0; i < int(args.NamedChildCount()); i++ {
+				s.addGroovyArg(call, args.NamedChild(i))
+			}
+		}
+		return call
+
+	case "assignment":
+		if node.NamedChildCount() != 2 {
+			return nil
+		}
+
+		call := &gradleCall{name: compact(s.text(node.NamedChild(0))) + "=", namedArgs: make(map[string]string)}
+		s.addGroovyArg(call, node.NamedChild(1))
+		return call
+	}
+
+	return nil
+}
+
+func (s *gradleScript) addGroovyArg(call *gradleCall, arg *sitter.Node) {
+	switch arg.Type() {
+	case "string":
+		if value, ok := s.stringValue(arg); ok {
+			call.args = append(call.args, value)
+			return
+		}
+	case "list":
+		for i := 0; i < int(arg.NamedChildCount()); i++ {
+			s.addGroovyArg(call, arg.NamedChild(i))
+		}
+		return
+	case "map_item":
+		key, value := arg.ChildByFieldName("key"), arg.ChildByFieldName("value")
+		if key != nil && value != nil && value.Type() == "string" {
+			if v, ok := s.stringValue(value); ok {
+				call.namedArgs[s.text(key)] = v
+				return
+			}
+		}
+	case "closure":
+		call.block = append(call.block, s.calls(arg)...)
+		return
+	case "function_call", "juxt_function_call":
+		call.callArgs = append(call.callArgs, s.groovyCall(arg))
+		return
+	}
+
+	call.otherArgs = append(call.otherArgs, s.text(arg))
+}
+
+// The value of a Groovy or Kotlin string literal, if not interpolated.
+func (s *gradleScript) stringValue(node *sitter.Node) (string, bool) {
+	var value strings.Builder
+	for i := 0; i < int(node.NamedChildCount()); i++ {
+		child := node.NamedChild(i)
+		if child.Type() != "string_content" {
+			return "", false
+		}
+		value.WriteString(s.text(child))
+	}
+	return value.String(), true
+}
+
+// The call of a Kotlin `call_expression` or `assignment` node.
+func (s *gradleScript) kotlinCall(node *sitter.Node) *gradleCall {
+	switch node.Type() {
+	case "call_expression":
+		if node.NamedChildCount() < 2 {
+			return nil
+		}
+
+		callee := node.NamedChild(0)
+		suffix := node.NamedChild(int(node.NamedChildCount()) - 1)
+		if suffix.Type() != "call_suffix" {
+			return nil
+		}
+
+		// A call of the result of a call such as `getByName("test") { ... }` is
+		// considered a call of the inner call with the trailing lambda.
+		var call *gradleCall
+		if callee.Type() == "call_expression" {
+			call = s.kotlinCall(callee)
+		}
+		if call == nil {
+			call = &gradleCall{name: compact(s.text(callee)), namedArgs: make(map[string]string)}
+		}
+
+		for i := 0; i < int(suffix.NamedChildCount()); i++ {
+			part := suffix.NamedChild(i)
+			switch part.Type() {
+			case "value_arguments":
+				for j := 0; j < int(part.NamedChildCount()); j++ {
+					s.addKotlinArg(call, part.NamedChild(j))
+				}
+			case "annotated_lambda":
+				if lambda := treeutils.GetNodeChildByType(part, "lambda_literal"); lambda != nil {
+					call.block = append(call.block, s.calls(lambda)...)
+				}
+			}
+		}
+		return call
+
+	case "assignment":
+		if node.NamedChildCount() != 2 {
+			return nil
+		}
+
+		call := &gradleCall{name: compact(s.text(node.NamedChild(0))) + "=", namedArgs: make(map[string]string)}
+		s.addKotlinValue(call, "", node.NamedChild(1))
+		return call
+	}
+
+	return nil
+}
+
+// Add a Kotlin `value_argument` node, such as `"value"` or `name = "value"`.
+func (s *gradleScript) addKotlinArg(call *gradleCall, arg *sitter.Node) {
+	if arg.Type() != "value_argument" || arg.NamedChildCount() == 0 {
+		return
+	}
+
+	name := ""
+	value := arg.NamedChild(int(arg.NamedChildCount()) - 1)
+	if arg.NamedChildCount() == 2 && arg.NamedChild(0).Type() == "simple_identifier" {
+		name = s.text(arg.NamedChild(0))
+	}
+
+	s.addKotlinValue(call, name, value)
+}
+
+func (s *gradleScript) addKotlinValue(call *gradleCall, name string, value *sitter.Node) {
+	switch value.Type() {
+	case "string_literal":
+		if v, ok := s.stringValue(value); ok {
+			if name != "" {
+				call.namedArgs[name] = v
+			} else {
+				call.args = append(call.args, v)
+			}
+			return
+		}
+	case "call_expression":
+		if callArg := s.kotlinCall(value); callArg != nil {
+			// The strings of collection literals such as `listOf("a", "b")` are arguments
+			if strings.HasSuffix(callArg.name, "Of") && len(callArg.callArgs) == 0 && len(callArg.otherArgs) == 0 {
+				call.args = append(call.args, callArg.args...)
+			} else {
+				call.callArgs = append(call.callArgs, callArg)
+			}
+			return
+		}
+	case "collection_literal":
+		for i := 0; i < int(value.NamedChildCount()); i++ {
+			s.addKotlinValue(call, "", value.NamedChild(i))
+		}
+		return
+	}
+
+	call.otherArgs = append(call.otherArgs, s.text(value))
+}
+
+// The text without whitespace, such as `project(":a").projectDir` for a multi-line
+// expression.
+func compact(text string) string {
+	return strings.Join(strings.Fields(text), "")
+}
@@ -0,0 +1,184 @@
+// gradleimport reads the settings and build scripts of a Gradle build to bootstrap
+// generating kotlin rules with gazelle. It prints the directives generating a library
+// per source set of each project, and the maven artifacts the projects depend upon
+// as a maven_install `artifacts` list.
+//
+// Usage:
+//
+//	gradleimport [-root dir] [-write]
+//
+// The projects are those included by the settings.gradle.kts or settings.gradle file
+// of the root, along with the root project. The source sets are the directories of
+// the conventional layout such as `src/main` and the source directories declared by
+// the `sourceSets` block of the build.gradle.kts or build.gradle file of each project.
+// Test source sets such as `src/test` are marked as test sources.
+//
+// With -write, the directives are instead added to the BUILD files of the source set
+// directories, created if they do not exist.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"aspect.build/cli/gazelle/kotlin/cmd/internal/profiling"
+)
+
+func main() {
+	root := flag.String("root", defaultRoot(), "The directory of the Gradle build, by default the workspace of `bazel run`.")
+	write := flag.Bool("write", false, "Add the directives to the BUILD files of the source set directories instead of printing them.")
+	profiling.RegisterFlags()
+	flag.Parse()
+
+	if err := profiling.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to start profiling: %v\n", err)
+		os.Exit(2)
+	}
+	defer profiling.Stop()
+
+	projects, err := readProjects(*root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read the Gradle build: %v\n", err)
+		profiling.Exit(1)
+	}
+
+	var coordinates, boms []string
+	for _, p := range projects {
+		coordinates = append(coordinates, p.artifacts...)
+		boms = append(boms, p.boms...)
+
+		for _, notation := range p.unsupported {
+			fmt.Fprintf(os.Stderr, "Skipped the dependency %s of project %s, not maven coordinates\n", notation, p.path)
+		}
+	}
+
+	artifacts, conflicts := seedArtifacts(coordinates)
+	for _, c := range conflicts {
+		fmt.Fprintf(os.Stderr, "Conflicting versions of %s\n", c)
+	}
+	boms, _ = seedArtifacts(boms)
+
+	directives := projectDirectives(*root, projects)
+
+	if *write {
+		added, err := writeDirectives(*root, directives)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write the directives: %v\n", err)
+			profiling.Exit(1)
+		}
+		fmt.Printf("Added %d directive(s) to the BUILD files of %d project(s)\n\n", added, len(projects))
+	} else {
+		printDirectives(os.Stdout, directives)
+	}
+
+	printStarlarkList(os.Stdout, "artifacts", artifacts)
+	if len(boms) > 0 {
+		printStarlarkList(os.Stdout, "boms", boms)
+	}
+}
+
+// The directories of the directives, sorted.
+func sortedDirs(directives map[string][]string) []string {
+	dirs := make([]string, 0, len(directives))
+	for dir := range directives {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// Print the directives of each BUILD file.
+func printDirectives(w io.Writer, directives map[string][]string) {
+	for _, dir := range sortedDirs(directives) {
+		fmt.Fprintf(w, "%s:\n", path.Join(dir, "BUILD.bazel"))
+		for _, d := range directives[dir] {
+			fmt.Fprintln(w, d)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// Print a list of strings as a starlark attribute.
+func printStarlarkList(w io.Writer, name string, values []string) {
+	fmt.Fprintf(w, "%s = [\n", name)
+	for _, v := range values {
+		fmt.Fprintf(w, "    %q,\n", v)
+	}
+	fmt.Fprintln(w, "]")
+}
+
+// Add the directives missing from the BUILD file of each directory, creating
+// BUILD.bazel files where there are none. Returns the number of directives added.
+func writeDirectives(root string, directives map[string][]string) (int, error) {
+	added := 0
+
+	for _, dir := range sortedDirs(directives) {
+		buildFile := findFile(root, dir, []string{"BUILD.bazel", "BUILD"})
+		if buildFile == "" {
+			buildFile = path.Join(dir, "BUILD.bazel")
+		}
+
+		filePath := path.Join(root, buildFile)
+		content, err := os.ReadFile(filePath)
+		if err != nil && !os.IsNotExist(err) {
+			return added, err
+		}
+
+		merged, n := mergeDirectives(string(content), directives[dir])
+		if n == 0 {
+			continue
+		}
+		if err := os.WriteFile(filePath, []byte(merged), 0644); err != nil {
+			return added, err
+		}
+		added += n
+	}
+
+	return added, nil
+}
+
+// Append the directives missing from the content, returning the merged content and
+// the number of directives added.
+func mergeDirectives(content string, directives []string) (string, int) {
+	existing := make(map[string]bool)
+	for _, line := range strings.Split(content, "\n") {
+		existing[strings.TrimSpace(line)] = true
+	}
+
+	var s strings.Builder
+	s.WriteString(content)
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		s.WriteString("\n")
+	}
+
+	added := 0
+	for _, d := range directives {
+		if existing[d] {
+			continue
+		}
+
+		if added == 0 && content != "" {
+			s.WriteString("\n")
+		}
+		s.WriteString(d)
+		s.WriteString("\n")
+
+		existing[d] = true
+		added++
+	}
+
+	return s.String(), added
+}
+
+// The workspace directory when run using `bazel run`, otherwise the working directory.
+func defaultRoot() string {
+	if dir := os.Getenv("BUILD_WORKSPACE_DIRECTORY"); dir != "" {
+		return dir
+	}
+	return "."
+}
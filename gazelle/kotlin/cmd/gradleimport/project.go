@@ -0,0 +1,433 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The settings and build scripts, in order of precedence.
+var (
+	settingsFileNames = []string{"settings.gradle.kts", "settings.gradle"}
+	buildFileNames    = []string{"build.gradle.kts", "build.gradle"}
+)
+
+// The source sets of the conventional layout contain sources of these languages.
+var sourceSetLanguages = []string{"kotlin", "java"}
+
+// The methods of the sourceSets container returning the source set of their argument,
+// such as `getByName("test") { ... }`.
+var sourceSetLookups = []string{"getByName", "named", "create", "register", "maybeCreate"}
+
+// An assignment of the directory of a project in a settings script, such as
+// `project(":other").projectDir = file("libs/other")`.
+var projectDirRegexp = regexp.MustCompile(`^project\(["']([^"']+)["']\)\.projectDir=$`)
+
+// A project of a Gradle build, such as the `:lib:core` project in the `lib/core` directory.
+type gradleProject struct {
+	// The project path such as `:lib:core`, `:` for the root project
+	path string
+
+	// The directory of the project relative to the root, "" for the root project
+	dir string
+
+	// The source directories of each source set relative to the root, such as
+	// `lib/core/src/main/kotlin` for `main`, as declared by the build script
+	sourceSets map[string][]string
+
+	// The maven coordinates of the dependencies declared by the build script, and of
+	// the platforms such as `platform("org.jetbrains.kotlin:kotlin-bom:1.9.0")`
+	artifacts []string
+	boms      []string
+
+	// The dependency notations which are not maven coordinates or projects, such as
+	// version catalog references
+	unsupported []string
+}
+
+// Read the projects included by the settings script of the root and their build
+// scripts. Only the root project is returned if there is no settings script.
+func readProjects(root string) ([]*gradleProject, error) {
+	projects := []*gradleProject{{path: ":", dir: ""}}
+
+	settingsFile := findFile(root, "", settingsFileNames)
+	if settingsFile != "" {
+		included, err := readSettings(root, settingsFile)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, included...)
+	}
+
+	for _, p := range projects {
+		if err := p.readBuildScript(root); err != nil {
+			return nil, err
+		}
+	}
+
+	return projects, nil
+}
+
+// Read the projects included by a settings script.
+func readSettings(root, settingsFile string) ([]*gradleProject, error) {
+	content, err := os.ReadFile(filepath.Join(root, settingsFile))
+	if err != nil {
+		return nil, err
+	}
+
+	calls, err := parseGradleScript(settingsFile, content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", settingsFile, err)
+	}
+
+	projects := make([]*gradleProject, 0)
+	projectDirs := make(map[string]string)
+
+	walkCalls(calls, func(call *gradleCall) {
+		if call.name == "include" {
+			for _, arg := range call.args {
+				projects = append(projects, &gradleProject{path: projectPath(arg)})
+			}
+			return
+		}
+
+		if m := projectDirRegexp.FindStringSubmatch(call.name); m != nil {
+			for _, dir := range call.callArgs {
+				if dir.name == "file" && len(dir.args) == 1 {
+					projectDirs[projectPath(m[1])] = path.Clean(dir.args[0])
+				}
+			}
+		}
+	})
+
+	for _, p := range projects {
+		p.dir = strings.ReplaceAll(strings.TrimPrefix(p.path, ":"), ":", "/")
+		if dir, found := projectDirs[p.path]; found {
+			p.dir = dir
+		}
+	}
+
+	return projects, nil
+}
+
+// The absolute path of a project such as `:lib:core` for `lib:core`.
+func projectPath(p string) string {
+	if !strings.HasPrefix(p, ":") {
+		return ":" + p
+	}
+	return p
+}
+
+// Read the source sets and dependencies of the build script of the project, if any.
+func (p *gradleProject) readBuildScript(root string) error {
+	p.sourceSets = make(map[string][]string)
+
+	buildFile := findFile(root, p.dir, buildFileNames)
+	if buildFile == "" {
+		return nil
+	}
+
+	content, err := os.ReadFile(filepath.Join(root, buildFile))
+	if err != nil {
+		return err
+	}
+
+	calls, err := parseGradleScript(buildFile, content)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", buildFile, err)
+	}
+
+	walkCalls(calls, func(call *gradleCall) {
+		switch {
+		case call.name == "dependencies":
+			for _, dependency := range call.block {
+				p.addDependency(dependency)
+			}
+
+		case call.name == "sourceSets":
+			for _, sourceSet := range call.block {
+				if name := sourceSetName(sourceSet); name != "" {
+					p.addSourceDirs(name, sourceSet.block)
+				}
+			}
+
+		case strings.HasPrefix(call.name, "sourceSets."):
+			// Properties of a source set such as `sourceSets.main.java.srcDirs = [...]`
+			if name := strings.Split(call.name, ".")[1]; isSrcDirs(call) {
+				p.addSourceDirs(name, []*gradleCall{call})
+			}
+		}
+	})
+
+	return nil
+}
+
+// The name of the source set configured by a call of a sourceSets block, such as
+// `main { ... }` or `getByName("test") { ... }`.
+func sourceSetName(call *gradleCall) string {
+	if len(call.block) == 0 {
+		return ""
+	}
+	if slices.Contains(sourceSetLookups, call.name) {
+		if len(call.args) == 1 {
+			return call.args[0]
+		}
+		return ""
+	}
+	if strings.ContainsAny(call.name, ".=()") {
+		return ""
+	}
+	return call.name
+}
+
+// Whether a call sets or adds the source directories of a source set, such as
+// `kotlin.srcDir("src/main/kt")` or `srcDirs = ["src"]`.
+func isSrcDirs(call *gradleCall) bool {
+	name := strings.TrimSuffix(call.name, "=")
+	name = name[strings.LastIndex(name, ".")+1:]
+	return name == "srcDir" || name == "srcDirs" || name == "setSrcDirs"
+}
+
+// Add the source directories set by the calls of a source set and their blocks.
+func (p *gradleProject) addSourceDirs(sourceSet string, calls []*gradleCall) {
+	walkCalls(calls, func(call *gradleCall) {
+		if !isSrcDirs(call) {
+			return
+		}
+		for _, dir := range call.allArgs() {
+			dir = path.Join(p.dir, dir)
+			if !slices.Contains(p.sourceSets[sourceSet], dir) {
+				p.sourceSets[sourceSet] = append(p.sourceSets[sourceSet], dir)
+			}
+		}
+	})
+}
+
+// Add a dependency declaration of a dependencies block, such as
+// `implementation("com.google.guava:guava:33.0.0-jre")`.
+func (p *gradleProject) addDependency(call *gradleCall) {
+	// The plugins of the build script are not dependencies of the sources
+	if call.name == "classpath" {
+		return
+	}
+
+	for _, arg := range call.args {
+		if isCoordinates(arg) {
+			p.artifacts = append(p.artifacts, arg)
+		} else {
+			p.unsupported = append(p.unsupported, strconv.Quote(arg))
+		}
+	}
+
+	// Map notation such as `group: "junit", name: "junit", version: "4.13.2"`
+	if group, name := call.namedArgs["group"], call.namedArgs["name"]; group != "" && name != "" {
+		coordinates := group + ":" + name
+		if version := call.namedArgs["version"]; version != "" {
+			coordinates += ":" + version
+		}
+		p.artifacts = append(p.artifacts, coordinates)
+	}
+
+	for _, arg := range call.callArgs {
+		switch {
+		case arg.name == "project" || arg.name == "files" || arg.name == "fileTree":
+			// Dependencies upon projects are resolved from the imports by gazelle, and
+			// dependencies upon local files are not maven artifacts
+		case arg.name == "platform" || arg.name == "enforcedPlatform":
+			for _, coordinates := range arg.args {
+				if isCoordinates(coordinates) {
+					p.boms = append(p.boms, coordinates)
+				}
+			}
+		case arg.name == "kotlin" && len(arg.args) > 0:
+			// The kotlin("test") notation of the kotlin plugin
+			coordinates := "org.jetbrains.kotlin:kotlin-" + arg.args[0]
+			if len(arg.args) > 1 {
+				coordinates += ":" + arg.args[1]
+			}
+			p.artifacts = append(p.artifacts, coordinates)
+		default:
+			p.unsupported = append(p.unsupported, arg.name+"(...)")
+		}
+	}
+
+	p.unsupported = append(p.unsupported, call.otherArgs...)
+}
+
+// Whether a dependency notation is maven coordinates such as `group:artifact:version`.
+func isCoordinates(s string) bool {
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 4 || strings.ContainsAny(s, " \t$") {
+		return false
+	}
+	for _, part := range parts {
+		if part == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// The source directories of the project relative to the root, by source set, each
+// the directory of a source set of the conventional layout such as `src/main` if it
+// exists, and the existing directories declared by the build script not within it.
+func (p *gradleProject) sourceSetDirs(root string) map[string][]string {
+	dirs := make(map[string][]string)
+
+	srcDir := path.Join(p.dir, "src")
+	if entries, err := os.ReadDir(filepath.Join(root, filepath.FromSlash(srcDir))); err == nil {
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			for _, lang := range sourceSetLanguages {
+				if isDir(root, path.Join(srcDir, entry.Name(), lang)) {
+					dirs[entry.Name()] = []string{path.Join(srcDir, entry.Name())}
+					break
+				}
+			}
+		}
+	}
+
+	for sourceSet, declared := range p.sourceSets {
+		for _, dir := range declared {
+			if !isDir(root, dir) {
+				continue
+			}
+
+			// Directories within the conventional directory are included in its library
+			conventional := path.Join(srcDir, sourceSet)
+			if slices.Contains(dirs[sourceSet], conventional) && (dir == conventional || strings.HasPrefix(dir, conventional+"/")) {
+				continue
+			}
+			dirs[sourceSet] = append(dirs[sourceSet], dir)
+		}
+	}
+
+	return dirs
+}
+
+// Whether a source set contains tests, such as `test`, `androidTest`, `integrationTest`
+// or `testFixtures`.
+func isTestSourceSet(name string) bool {
+	return strings.Contains(strings.ToLower(name), "test")
+}
+
+// The directives of the BUILD files of the source sets of the projects by directory
+// relative to the root, generating a library per source set using the module
+// generation mode.
+func projectDirectives(root string, projects []*gradleProject) map[string][]string {
+	directives := map[string][]string{
+		"": {"# gazelle:kotlin_generation_mode module"},
+	}
+
+	for _, p := range projects {
+		for sourceSet, dirs := range p.sourceSetDirs(root) {
+			for _, dir := range dirs {
+				directives[dir] = append(directives[dir], "# gazelle:kotlin_module_root")
+				if isTestSourceSet(sourceSet) {
+					directives[dir] = append(directives[dir], "# gazelle:kotlin_test_srcs enabled")
+				}
+			}
+		}
+	}
+
+	return directives
+}
+
+// The maven coordinates of the projects for a maven_install `artifacts` list, sorted.
+// The highest version of artifacts of multiple versions is selected, returned along
+// with the other versions as conflicts.
+func seedArtifacts(coordinates []string) ([]string, []string) {
+	versions := make(map[string][]string)
+	for _, c := range coordinates {
+		artifact, version := c, ""
+		if parts := strings.SplitN(c, ":", 3); len(parts) == 3 {
+			artifact, version = parts[0]+":"+parts[1], parts[2]
+		}
+		if !slices.Contains(versions[artifact], version) {
+			versions[artifact] = append(versions[artifact], version)
+		}
+	}
+
+	artifacts := make([]string, 0, len(versions))
+	conflicts := make([]string, 0)
+	for artifact, vs := range versions {
+		sort.Slice(vs, func(i, j int) bool {
+			return compareVersions(vs[i], vs[j]) > 0
+		})
+
+		// Artifacts without a version are managed by a platform if versioned elsewhere
+		if vs[0] == "" {
+			artifacts = append(artifacts, artifact)
+			continue
+		}
+		artifacts = append(artifacts, artifact+":"+vs[0])
+
+		if others := slices.DeleteFunc(vs[1:], func(v string) bool { return v == "" }); len(others) > 0 {
+			conflicts = append(conflicts, fmt.Sprintf("%s: %s selected over %s", artifact, vs[0], strings.Join(others, ", ")))
+		}
+	}
+
+	sort.Strings(artifacts)
+	sort.Strings(conflicts)
+	return artifacts, conflicts
+}
+
+// Compare versions such as `1.10.0` and `1.9.0-jre` by their numeric parts, then
+// lexically. Empty versions are lower than any version.
+func compareVersions(a, b string) int {
+	if a == "" || b == "" {
+		return len(a) - len(b)
+	}
+
+	split := func(v string) []string {
+		return strings.FieldsFunc(v, func(r rune) bool { return r == '.' || r == '-' })
+	}
+	as, bs := split(a), split(b)
+
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				return an - bn
+			}
+		case as[i] != bs[i]:
+			return strings.Compare(as[i], bs[i])
+		}
+	}
+	return len(as) - len(bs)
+}
+
+// Call the function with the calls and the calls of their blocks, depth first.
+func walkCalls(calls []*gradleCall, f func(call *gradleCall)) {
+	for _, call := range calls {
+		f(call)
+		walkCalls(call.block, f)
+	}
+}
+
+// The path relative to the root of the first of the files existing in the directory,
+// or empty if none exist.
+func findFile(root, dir string, names []string) string {
+	for _, name := range names {
+		p := path.Join(dir, name)
+		if info, err := os.Stat(filepath.Join(root, filepath.FromSlash(p))); err == nil && info.Mode().IsRegular() {
+			return p
+		}
+	}
+	return ""
+}
+
+func isDir(root, dir string) bool {
+	info, err := os.Stat(filepath.Join(root, filepath.FromSlash(dir)))
+	return err == nil && info.IsDir()
+}
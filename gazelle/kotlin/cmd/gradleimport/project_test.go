@@ -0,0 +1,207 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadProjectsGroovy(t *testing.T) {
+	root := t.TempDir()
+
+	writeFile(t, root, "settings.gradle", `
+rootProject.name = 'example'
+include ':app', ':lib:core'
+include 'other'
+project(':other').projectDir = file('libs/other')
+`)
+	writeFile(t, root, "app/build.gradle", `
+plugins {
+    id 'org.jetbrains.kotlin.jvm'
+}
+
+dependencies {
+    implementation project(':lib:core')
+    implementation 'com.squareup.okhttp3:okhttp:4.12.0'
+    implementation group: 'com.google.guava', name: 'guava', version: '33.0.0-jre'
+    implementation platform('org.jetbrains.kotlin:kotlin-bom:1.9.22')
+    implementation libs.kotlinx.coroutines
+    testImplementation 'junit:junit:4.13.2'
+}
+`)
+	writeFile(t, root, "lib/core/build.gradle", `
+sourceSets {
+    main {
+        kotlin {
+            srcDirs = ['src/main/kotlin', 'generated/kotlin']
+        }
+    }
+}
+
+dependencies {
+    api "com.squareup.okhttp3:okhttp:4.11.0"
+}
+`)
+	writeFile(t, root, "app/src/main/kotlin/App.kt", "package app")
+	writeFile(t, root, "app/src/test/kotlin/AppTest.kt", "package app")
+	writeFile(t, root, "lib/core/src/main/kotlin/Core.kt", "package core")
+	writeFile(t, root, "lib/core/generated/kotlin/Gen.kt", "package core")
+	writeFile(t, root, "libs/other/src/main/java/Other.java", "package other;")
+
+	projects, err := readProjects(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths := make(map[string]string)
+	for _, p := range projects {
+		paths[p.path] = p.dir
+	}
+	expectedPaths := map[string]string{":": "", ":app": "app", ":lib:core": "lib/core", ":other": "libs/other"}
+	if !reflect.DeepEqual(paths, expectedPaths) {
+		t.Errorf("readProjects() projects...\nactual:  %v;\nexpected: %v", paths, expectedPaths)
+	}
+
+	app := projects[1]
+	expectedArtifacts := []string{
+		"com.squareup.okhttp3:okhttp:4.12.0",
+		"com.google.guava:guava:33.0.0-jre",
+		"junit:junit:4.13.2",
+	}
+	if !reflect.DeepEqual(app.artifacts, expectedArtifacts) {
+		t.Errorf("readProjects() artifacts...\nactual:  %v;\nexpected: %v", app.artifacts, expectedArtifacts)
+	}
+	if expected := []string{"org.jetbrains.kotlin:kotlin-bom:1.9.22"}; !reflect.DeepEqual(app.boms, expected) {
+		t.Errorf("readProjects() boms...\nactual:  %v;\nexpected: %v", app.boms, expected)
+	}
+	if expected := []string{"libs.kotlinx.coroutines"}; !reflect.DeepEqual(app.unsupported, expected) {
+		t.Errorf("readProjects() unsupported...\nactual:  %v;\nexpected: %v", app.unsupported, expected)
+	}
+
+	directives := projectDirectives(root, projects)
+	expectedDirectives := map[string][]string{
+		"":                          {"# gazelle:kotlin_generation_mode module"},
+		"app/src/main":              {"# gazelle:kotlin_module_root"},
+		"app/src/test":              {"# gazelle:kotlin_module_root", "# gazelle:kotlin_test_srcs enabled"},
+		"lib/core/src/main":         {"# gazelle:kotlin_module_root"},
+		"lib/core/generated/kotlin": {"# gazelle:kotlin_module_root"},
+		"libs/other/src/main":       {"# gazelle:kotlin_module_root"},
+	}
+	if !reflect.DeepEqual(directives, expectedDirectives) {
+		t.Errorf("projectDirectives()...\nactual:  %v;\nexpected: %v", directives, expectedDirectives)
+	}
+}
+
+func TestReadProjectsKotlin(t *testing.T) {
+	root := t.TempDir()
+
+	writeFile(t, root, "settings.gradle.kts", `
+rootProject.name = "example"
+include(":app")
+include(":lib")
+`)
+	writeFile(t, root, "app/build.gradle.kts", `
+plugins {
+    kotlin("jvm")
+}
+
+dependencies {
+    implementation(project(":lib"))
+    implementation("com.squareup.okhttp3:okhttp:4.12.0")
+    implementation(kotlin("reflect"))
+    implementation(platform("org.jetbrains.kotlin:kotlin-bom:1.9.22"))
+    implementation(libs.okio)
+    testImplementation(group = "junit", name = "junit", version = "4.13.2")
+}
+`)
+	writeFile(t, root, "lib/build.gradle.kts", `
+sourceSets {
+    getByName("integrationTest") {
+        kotlin.srcDir("it/kotlin")
+    }
+}
+`)
+	writeFile(t, root, "app/src/main/kotlin/App.kt", "package app")
+	writeFile(t, root, "lib/src/main/kotlin/Lib.kt", "package lib")
+	writeFile(t, root, "lib/it/kotlin/LibIT.kt", "package lib")
+
+	projects, err := readProjects(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projects) != 3 {
+		t.Fatalf("readProjects() returned %d projects, expected 3", len(projects))
+	}
+
+	app := projects[1]
+	expectedArtifacts := []string{
+		"com.squareup.okhttp3:okhttp:4.12.0",
+		"org.jetbrains.kotlin:kotlin-reflect",
+		"junit:junit:4.13.2",
+	}
+	if !reflect.DeepEqual(app.artifacts, expectedArtifacts) {
+		t.Errorf("readProjects() artifacts...\nactual:  %v;\nexpected: %v", app.artifacts, expectedArtifacts)
+	}
+	if expected := []string{"org.jetbrains.kotlin:kotlin-bom:1.9.22"}; !reflect.DeepEqual(app.boms, expected) {
+		t.Errorf("readProjects() boms...\nactual:  %v;\nexpected: %v", app.boms, expected)
+	}
+	if expected := []string{"libs.okio"}; !reflect.DeepEqual(app.unsupported, expected) {
+		t.Errorf("readProjects() unsupported...\nactual:  %v;\nexpected: %v", app.unsupported, expected)
+	}
+
+	directives := projectDirectives(root, projects)
+	expectedDirectives := map[string][]string{
+		"":              {"# gazelle:kotlin_generation_mode module"},
+		"app/src/main":  {"# gazelle:kotlin_module_root"},
+		"lib/src/main":  {"# gazelle:kotlin_module_root"},
+		"lib/it/kotlin": {"# gazelle:kotlin_module_root", "# gazelle:kotlin_test_srcs enabled"},
+	}
+	if !reflect.DeepEqual(directives, expectedDirectives) {
+		t.Errorf("projectDirectives()...\nactual:  %v;\nexpected: %v", directives, expectedDirectives)
+	}
+}
+
+func TestSeedArtifacts(t *testing.T) {
+	artifacts, conflicts := seedArtifacts([]string{
+		"com.squareup.okhttp3:okhttp:4.9.0",
+		"junit:junit:4.13.2",
+		"com.squareup.okhttp3:okhttp:4.12.0",
+		"junit:junit:4.13.2",
+	})
+
+	expected := []string{"com.squareup.okhttp3:okhttp:4.12.0", "junit:junit:4.13.2"}
+	if !reflect.DeepEqual(artifacts, expected) {
+		t.Errorf("seedArtifacts() artifacts...\nactual:  %v;\nexpected: %v", artifacts, expected)
+	}
+	if len(conflicts) != 1 {
+		t.Errorf("seedArtifacts() conflicts...\nactual:  %v;\nexpected 1 conflict", conflicts)
+	}
+}
+
+func TestMergeDirectives(t *testing.T) {
+	content := "# gazelle:kotlin_module_root\n\nkt_jvm_library(name = \"a\")"
+	merged, added := mergeDirectives(content, []string{"# gazelle:kotlin_module_root", "# gazelle:kotlin_test_srcs enabled"})
+
+	expected := content + "\n\n# gazelle:kotlin_test_srcs enabled\n"
+	if merged != expected || added != 1 {
+		t.Errorf("mergeDirectives()...\nactual:  %q (%d);\nexpected: %q (1)", merged, added, expected)
+	}
+
+	merged, added = mergeDirectives("", []string{"# gazelle:kotlin_module_root"})
+	if merged != "# gazelle:kotlin_module_root\n" || added != 1 {
+		t.Errorf("mergeDirectives() of an empty file: %q (%d)", merged, added)
+	}
+}
+
+func writeFile(t *testing.T, root, file, content string) {
+	t.Helper()
+
+	p := filepath.Join(root, file)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,106 @@
+// Package profiling adds the -cpuprofile, -memprofile and -trace flags to the kotlin
+// commands, for investigating their performance on large repositories without
+// rebuilding them with ad-hoc profiling.
+package profiling
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// The files of the profiling flags, registered by RegisterFlags.
+var cpuProfile, memProfile, traceFile string
+
+// The open profile files while profiling.
+var cpuProfileFile, traceOutFile *os.File
+
+// RegisterFlags registers the profiling flags on the flag set of the command line.
+func RegisterFlags() {
+	flag.StringVar(&cpuProfile, "cpuprofile", "", "Write a CPU profile of the command to `file`.")
+	flag.StringVar(&memProfile, "memprofile", "", "Write a heap profile of the command to `file` when it completes.")
+	flag.StringVar(&traceFile, "trace", "", "Write an execution trace of the command to `file`.")
+}
+
+// Start starts the profiles of the flags, relative to the working directory. Stop or
+// Exit must be called to complete them.
+func Start() error {
+	var err error
+	for _, p := range []*string{&cpuProfile, &memProfile, &traceFile} {
+		if *p != "" {
+			// Relative to the working directory, before commands change to the root.
+			if *p, err = filepath.Abs(*p); err != nil {
+				return err
+			}
+		}
+	}
+
+	if cpuProfile != "" {
+		if cpuProfileFile, err = os.Create(cpuProfile); err != nil {
+			return err
+		}
+		if err := pprof.StartCPUProfile(cpuProfileFile); err != nil {
+			cpuProfileFile.Close()
+			cpuProfileFile = nil
+			return err
+		}
+	}
+
+	if traceFile != "" {
+		if traceOutFile, err = os.Create(traceFile); err != nil {
+			Stop()
+			return err
+		}
+		if err := trace.Start(traceOutFile); err != nil {
+			traceOutFile.Close()
+			traceOutFile = nil
+			Stop()
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Stop completes the profiles, reporting failures to write them.
+func Stop() {
+	if cpuProfileFile != nil {
+		pprof.StopCPUProfile()
+		cpuProfileFile.Close()
+		cpuProfileFile = nil
+	}
+
+	if traceOutFile != nil {
+		trace.Stop()
+		traceOutFile.Close()
+		traceOutFile = nil
+	}
+
+	if memProfile != "" {
+		if err := writeHeapProfile(memProfile); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write the heap profile %s: %v\n", memProfile, err)
+		}
+		memProfile = ""
+	}
+}
+
+// Exit completes the profiles and exits with a code, for commands exiting after Start.
+func Exit(code int) {
+	Stop()
+	os.Exit(code)
+}
+
+func writeHeapProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	runtime.GC()
+	return pprof.WriteHeapProfile(f)
+}
@@ -0,0 +1,42 @@
+package profiling
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStartStop(t *testing.T) {
+	dir := t.TempDir()
+	cpuProfile = filepath.Join(dir, "cpu.pprof")
+	memProfile = filepath.Join(dir, "mem.pprof")
+	traceFile = filepath.Join(dir, "trace.out")
+
+	if err := Start(); err != nil {
+		t.Fatal(err)
+	}
+	Stop()
+
+	for _, f := range []string{"cpu.pprof", "mem.pprof", "trace.out"} {
+		info, err := os.Stat(filepath.Join(dir, f))
+		if err != nil {
+			t.Errorf("Expected the profile %s: %v", f, err)
+		} else if info.Size() == 0 {
+			t.Errorf("Expected the profile %s to not be empty", f)
+		}
+	}
+
+	// Stopping again does not rewrite the profiles
+	Stop()
+}
+
+func TestStartInvalidPath(t *testing.T) {
+	cpuProfile = ""
+	memProfile = ""
+	traceFile = filepath.Join(t.TempDir(), "missing", "trace.out")
+
+	if err := Start(); err == nil {
+		Stop()
+		t.Error("Expected an error for a trace file in a missing directory")
+	}
+}
@@ -0,0 +1,118 @@
+// Package rungazelle runs gazelle with the kotlin extension in-process for the
+// kotlin commands.
+package rungazelle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	kotlin "aspect.build/cli/gazelle/kotlin"
+	"aspect.build/cli/pkg/aspect/configure"
+	"aspect.build/cli/pkg/aspecterrors"
+	"aspect.build/cli/pkg/ioutils"
+	"github.com/spf13/cobra"
+)
+
+// Update runs gazelle with the kotlin extension in fix mode in the working directory,
+// updating the BUILD files of the directories visited and printing the number of
+// BUILD files updated. The args such as gazelle flags and the directories to visit
+// are passed to gazelle.
+func Update(args []string) error {
+	return run("fix", ioutils.DefaultStreams, args)
+}
+
+// ExportDepGraph runs gazelle with the kotlin extension in diff mode in the working
+// directory, discarding the diff of the BUILD files, to write the dependency graph
+// of the -kotlin-depgraph flag to a file. The args such as gazelle flags and the
+// directories to visit are passed to gazelle.
+func ExportDepGraph(graphFile, format string, args []string) error {
+	gazelleArgs := []string{
+		"-patch=" + os.DevNull,
+		"-kotlin-depgraph=" + graphFile,
+		"-kotlin-depgraph-format=" + format,
+	}
+
+	streams := ioutils.Streams{Stdin: os.Stdin, Stdout: io.Discard, Stderr: os.Stderr}
+	return run("diff", streams, append(gazelleArgs, args...))
+}
+
+// Diff runs gazelle with the kotlin extension in diff mode in the working directory,
+// without writing BUILD files, and returns the unified diff of the BUILD files which
+// differ from the generated rules, empty if all are up to date. The args such as
+// gazelle flags and the directories to visit are passed to gazelle.
+//
+// Runs failing once the BUILD files are generated, such as on imports failing to
+// resolve, return the diff along with the error.
+func Diff(args []string) (string, error) {
+	f, err := os.CreateTemp("", "diff-*.patch")
+	if err != nil {
+		return "", err
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	streams := ioutils.Streams{Stdin: os.Stdin, Stdout: io.Discard, Stderr: os.Stderr}
+	runErr := run("diff", streams, append([]string{"-patch=" + f.Name()}, args...))
+
+	patch, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", errors.Join(runErr, err)
+	}
+	return string(patch), runErr
+}
+
+// Run gazelle with the kotlin extension in a mode, succeeding if BUILD files are
+// changed or differ.
+func run(mode string, streams ioutils.Streams, args []string) error {
+	runner := configure.New(streams)
+	runner.AddLanguage("kotlin", kotlin.NewLanguage)
+
+	cmd := &cobra.Command{}
+	cmd.Flags().String("mode", mode, "")
+
+	err := runner.Run(context.Background(), cmd, args)
+
+	// Changes to the BUILD files are expected
+	var exitErr *aspecterrors.ExitError
+	if !errors.As(err, &exitErr) {
+		return err
+	}
+	switch exitErr.ExitCode {
+	case aspecterrors.OK, aspecterrors.ConfigureDiff, aspecterrors.ConfigureFixed:
+		return nil
+	}
+	if exitErr.Err == nil {
+		return fmt.Errorf("gazelle exited with code %d", exitErr.ExitCode)
+	}
+	return exitErr.Err
+}
+
+// LoadDepGraph exports the dependency graph to a temporary JSON file and returns
+// its targets, sorted by label.
+func LoadDepGraph(args []string) ([]kotlin.DepGraphTarget, error) {
+	f, err := os.CreateTemp("", "depgraph-*.json")
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	if err := ExportDepGraph(f.Name(), kotlin.DepGraphFormat_JSON, args); err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(f.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []kotlin.DepGraphTarget
+	if err := json.Unmarshal(content, &targets); err != nil {
+		return nil, fmt.Errorf("failed to decode the dependency graph: %w", err)
+	}
+	return targets, nil
+}
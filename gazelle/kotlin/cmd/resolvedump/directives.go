@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// Merge the directives into a BUILD file or directive fragment, created if it does
+// not exist. Directives already present are skipped, as are directives resolving an
+// import already resolved by the file to another label, which are returned as conflicts.
+// Returns the number of directives added.
+func mergeDirectivesFile(filePath string, directives []string) (int, []string, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, nil, err
+	}
+
+	merged, added, conflicts := mergeDirectives(string(content), directives)
+	if added == 0 {
+		return 0, conflicts, nil
+	}
+
+	return added, conflicts, os.WriteFile(filePath, []byte(merged), 0644)
+}
+
+// Append the directives missing from the content, returning the merged content, the
+// number of directives added and the directives conflicting with existing directives.
+func mergeDirectives(content string, directives []string) (string, int, []string) {
+	// The existing resolve directives by the import they resolve
+	existing := make(map[string]string)
+	for _, line := range strings.Split(content, "\n") {
+		if imp, l, ok := parseResolveDirective(line); ok {
+			existing[imp] = l
+		}
+	}
+
+	var s strings.Builder
+	s.WriteString(content)
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		s.WriteString("\n")
+	}
+
+	added := 0
+	conflicts := make([]string, 0)
+	for _, d := range directives {
+		imp, l, ok := parseResolveDirective(d)
+		if !ok {
+			continue
+		}
+
+		if existingLabel, found := existing[imp]; found {
+			if existingLabel != l {
+				conflicts = append(conflicts, d)
+			}
+			continue
+		}
+
+		if added == 0 && content != "" {
+			s.WriteString("\n")
+		}
+		s.WriteString(d)
+		s.WriteString("\n")
+
+		existing[imp] = l
+		added++
+	}
+
+	return s.String(), added, conflicts
+}
+
+// Parse the import and label of a `# gazelle:resolve kotlin kotlin <import> <label>`
+// or `# gazelle:resolve kotlin <import> <label>` directive.
+func parseResolveDirective(line string) (string, string, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "#") {
+		return "", "", false
+	}
+
+	fields := strings.Fields(strings.TrimSpace(strings.TrimPrefix(line, "#")))
+	if len(fields) == 0 || fields[0] != "gazelle:resolve" {
+		return "", "", false
+	}
+
+	switch {
+	case len(fields) == 5 && fields[1] == "kotlin" && fields[2] == "kotlin":
+		return fields[3], fields[4], true
+	case len(fields) == 4 && fields[1] == "kotlin":
+		return fields[2], fields[3], true
+	}
+
+	return "", "", false
+}
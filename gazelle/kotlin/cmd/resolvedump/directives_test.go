@@ -0,0 +1,60 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMergeDirectives(t *testing.T) {
+	t.Run("empty file", func(t *testing.T) {
+		merged, added, conflicts := mergeDirectives("", []string{
+			"# gazelle:resolve kotlin kotlin com.a //a",
+			"# gazelle:resolve kotlin kotlin com.b //b",
+		})
+
+		expected := "# gazelle:resolve kotlin kotlin com.a //a\n# gazelle:resolve kotlin kotlin com.b //b\n"
+		if merged != expected || added != 2 || len(conflicts) != 0 {
+			t.Errorf("Merged...\nactual:   %q (%d added, conflicts %v)\nexpected: %q", merged, added, conflicts, expected)
+		}
+	})
+
+	t.Run("existing directives", func(t *testing.T) {
+		content := `load("@io_bazel_rules_kotlin//kotlin:jvm.bzl", "kt_jvm_library")
+
+#   gazelle:resolve   kotlin kotlin com.a //a
+# gazelle:resolve kotlin com.b //other:b
+`
+		merged, added, conflicts := mergeDirectives(content, []string{
+			"# gazelle:resolve kotlin kotlin com.a //a",
+			"# gazelle:resolve kotlin kotlin com.b //b",
+			"# gazelle:resolve kotlin kotlin com.c //c",
+		})
+
+		expected := content + "\n# gazelle:resolve kotlin kotlin com.c //c\n"
+		if merged != expected || added != 1 {
+			t.Errorf("Merged...\nactual:   %q (%d added)\nexpected: %q", merged, added, expected)
+		}
+		if !slices.Equal(conflicts, []string{"# gazelle:resolve kotlin kotlin com.b //b"}) {
+			t.Errorf("Conflicts...\nactual:   %v", conflicts)
+		}
+	})
+
+	t.Run("nothing to add", func(t *testing.T) {
+		content := "# gazelle:resolve kotlin kotlin com.a //a"
+		merged, added, _ := mergeDirectives(content, []string{"# gazelle:resolve kotlin kotlin com.a //a"})
+		if added != 0 || merged != content+"\n" {
+			t.Errorf("Merged...\nactual:   %q (%d added)", merged, added)
+		}
+	})
+}
+
+func TestResolveDirective(t *testing.T) {
+	for _, tc := range []struct{ imp, bazelPkg, expected string }{
+		{"com.a", "src/a", "# gazelle:resolve kotlin kotlin com.a //src/a"},
+		{"com.b.Foo", "", "# gazelle:resolve kotlin kotlin com.b.Foo //:root"},
+	} {
+		if actual := resolveDirective(tc.imp, tc.bazelPkg); actual != tc.expected {
+			t.Errorf("resolveDirective(%q, %q)...\nactual:   %q\nexpected: %q", tc.imp, tc.bazelPkg, actual, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"aspect.build/cli/gazelle/kotlin/symbolindex"
+	"github.com/bazelbuild/bazel-gazelle/label"
+)
+
+// The packages and top-level declarations of the analyzed kotlin, java and scala
+// sources, by the Bazel package containing them.
+type symbolIndex struct {
+	// The Bazel packages declaring each kotlin package
+	packages map[string]map[string]bool
+
+	// The top-level declarations of each kotlin package by Bazel package
+	declarations map[string]map[string][]string
+
+	// The files importing each package
+	imports map[string]map[string]bool
+
+	// The symbols of each file declaring a package, sorted by path
+	files []symbolindex.File
+}
+
+func newSymbolIndex() *symbolIndex {
+	return &symbolIndex{
+		packages:     make(map[string]map[string]bool),
+		declarations: make(map[string]map[string][]string),
+		imports:      make(map[string]map[string]bool),
+	}
+}
+
+// The result of analyzing a single file.
+type analyzeFileResult struct {
+	file    string
+	readErr error
+	symbols *fileSymbols
+	errs    []error
+
+	// The time spent parsing the file, excluding reading it
+	duration time.Duration
+}
+
+// Parse the files relative to the root using a number of workers, returning the
+// index of their declarations, the result of each file in the order of the files
+// and the number of files with parse errors. Errors are reported in the order of
+// the files, independent of the order the workers complete in.
+func analyzeFiles(root string, files []string, jobs int, progress *progressReporter) (*symbolIndex, []analyzeFileResult, int) {
+	// The channel of all files to parse.
+	filesChannel := make(chan string)
+
+	// The channel of analysis results.
+	resultsChannel := make(chan analyzeFileResult)
+
+	// Don't create more workers than files.
+	workerCount := min(jobs, len(files))
+
+	// Start the worker goroutines.
+	var wg sync.WaitGroup
+	for i := 0; i < workerCount; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for f := range filesChannel {
+				content, err := os.ReadFile(filepath.Join(root, f))
+				if err != nil {
+					resultsChannel <- analyzeFileResult{file: f, readErr: err}
+					continue
+				}
+
+				start := time.Now()
+				symbols, errs := analyzeFile(f, content)
+				resultsChannel <- analyzeFileResult{file: f, symbols: symbols, errs: errs, duration: time.Since(start)}
+			}
+		}()
+	}
+
+	// Send files to the workers.
+	go func() {
+		for _, f := range files {
+			filesChannel <- f
+		}
+		close(filesChannel)
+	}()
+
+	// Wait for all workers to finish.
+	go func() {
+		wg.Wait()
+		close(resultsChannel)
+	}()
+
+	results := make(map[string]analyzeFileResult, len(files))
+	for r := range resultsChannel {
+		results[r.file] = r
+		progress.increment()
+	}
+	progress.done()
+
+	index := newSymbolIndex()
+	ordered := make([]analyzeFileResult, 0, len(files))
+	failed := 0
+
+	for _, f := range files {
+		r := results[f]
+		ordered = append(ordered, r)
+
+		if r.readErr != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read %s: %v\n", f, r.readErr)
+			failed++
+			continue
+		}
+
+		if len(r.errs) > 0 {
+			fmt.Fprintln(os.Stderr, f, "parse error(s):")
+			for _, err := range r.errs {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			failed++
+		}
+
+		if r.symbols != nil && r.symbols.pkg != "" {
+			index.add(bazelPackage(f), r.symbols.pkg, r.symbols.declarations())
+			index.addFile(f, r.symbols)
+		}
+		if r.symbols != nil {
+			index.addImports(f, r.symbols.imports)
+		}
+	}
+
+	return index, ordered, failed
+}
+
+func (idx *symbolIndex) add(bazelPkg, pkg string, declarations []string) {
+	if idx.packages[pkg] == nil {
+		idx.packages[pkg] = make(map[string]bool)
+		idx.declarations[pkg] = make(map[string][]string)
+	}
+	idx.packages[pkg][bazelPkg] = true
+	idx.declarations[pkg][bazelPkg] = append(idx.declarations[pkg][bazelPkg], declarations...)
+}
+
+func (idx *symbolIndex) addFile(file string, symbols *fileSymbols) {
+	idx.files = append(idx.files, symbolindex.File{
+		Path:      file,
+		Package:   symbols.pkg,
+		Label:     packageLabel(bazelPackage(file)).String(),
+		Classes:   symbols.classes,
+		Functions: symbols.functions,
+	})
+}
+
+func (idx *symbolIndex) addImports(file string, imports []string) {
+	for _, imp := range imports {
+		if idx.imports[imp] == nil {
+			idx.imports[imp] = make(map[string]bool)
+		}
+		idx.imports[imp][file] = true
+	}
+}
+
+// Whether the package, or the class of a nested class import such as `com.foo.Outer`
+// for `import com.foo.Outer.Inner`, is declared by the analyzed sources.
+func (idx *symbolIndex) declares(pkg string) bool {
+	if _, declared := idx.packages[pkg]; declared {
+		return true
+	}
+
+	i := strings.LastIndex(pkg, ".")
+	if i < 0 {
+		return false
+	}
+
+	for _, declarations := range idx.declarations[pkg[:i]] {
+		if slices.Contains(declarations, pkg[i+1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// The directives resolving each kotlin package to the Bazel package declaring it,
+// or each top-level declaration if the kotlin package is split across Bazel packages.
+func (idx *symbolIndex) directives() []string {
+	directives := make([]string, 0, len(idx.packages))
+
+	for pkg, bazelPkgs := range idx.packages {
+		if len(bazelPkgs) == 1 {
+			for bazelPkg := range bazelPkgs {
+				directives = append(directives, resolveDirective(pkg, bazelPkg))
+			}
+			continue
+		}
+
+		for bazelPkg, declarations := range idx.declarations[pkg] {
+			for _, d := range declarations {
+				directives = append(directives, resolveDirective(pkg+"."+d, bazelPkg))
+			}
+		}
+	}
+
+	sortStrings(directives)
+	return directives
+}
+
+// The name of the target of the root package generated by the kotlin extension
+// for repositories without a name.
+const rootTargetName = "root"
+
+// The directive resolving an import to the default target of a Bazel package.
+func resolveDirective(imp, bazelPkg string) string {
+	return fmt.Sprintf("# gazelle:resolve kotlin kotlin %s %s", imp, packageLabel(bazelPkg).String())
+}
+
+// The default target of a Bazel package, named after the directory as generated by
+// the kotlin extension.
+func packageLabel(bazelPkg string) label.Label {
+	name := rootTargetName
+	if bazelPkg != "" {
+		name = path.Base(bazelPkg)
+	}
+	return label.New("", bazelPkg, name)
+}
+
+// The Bazel package of a file relative to the root, assumed to be its directory.
+func bazelPackage(file string) string {
+	if dir := path.Dir(file); dir != "." {
+		return dir
+	}
+	return ""
+}
+
+func sortStrings(s []string) {
+	sort.Strings(s)
+}
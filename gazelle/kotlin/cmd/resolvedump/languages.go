@@ -0,0 +1,179 @@
+package main
+
+import (
+	"strings"
+
+	treeutils "aspect.build/cli/gazelle/common/treesitter"
+	"aspect.build/cli/gazelle/kotlin/parser"
+)
+
+// The names of the queries of the package, top-level declarations and imports of a file.
+const (
+	packageQuery      = "resolvedump_package"
+	declarationsQuery = "resolvedump_declarations"
+	importsQuery      = "resolvedump_imports"
+)
+
+func init() {
+	// The kotlin parser extracts the package and imports, only the kinds of declarations are queried.
+	treeutils.MustRegisterQuery(treeutils.Kotlin, declarationsQuery, `(source_file [
+		(class_declaration (type_identifier) @class)
+		(object_declaration (type_identifier) @class)
+		(type_alias (type_identifier) @class)
+		(function_declaration (simple_identifier) @function)
+		(property_declaration (variable_declaration (simple_identifier) @function))
+	])`)
+
+	treeutils.MustRegisterQuery(treeutils.Java, packageQuery, `(program (package_declaration [(scoped_identifier) (identifier)] @package))`)
+	treeutils.MustRegisterQuery(treeutils.Java, declarationsQuery, `(program [
+		(class_declaration name: (identifier) @class)
+		(interface_declaration name: (identifier) @class)
+		(enum_declaration name: (identifier) @class)
+		(record_declaration name: (identifier) @class)
+		(annotation_type_declaration name: (identifier) @class)
+	])`)
+	treeutils.MustRegisterQuery(treeutils.Java, importsQuery, `(import_declaration "static"? @static [(scoped_identifier) (identifier)] @path (asterisk)? @star)`)
+
+	// Chained package clauses such as `package a` followed by `package b` declare `a.b`.
+	treeutils.MustRegisterQuery(treeutils.Scala, packageQuery, `(compilation_unit (package_clause name: (package_identifier) @package))`)
+	treeutils.MustRegisterQuery(treeutils.Scala, declarationsQuery, `(compilation_unit [
+		(class_definition name: (identifier) @class)
+		(object_definition name: (identifier) @class)
+		(trait_definition name: (identifier) @class)
+		(enum_definition name: (identifier) @class)
+		(function_definition name: (identifier) @function)
+	])`)
+	treeutils.MustRegisterQuery(treeutils.Scala, importsQuery, `(import_declaration [(namespace_wildcard) (namespace_selectors)]? @selector) @import`)
+}
+
+// The languages of the analyzed files, selected by file extension.
+var analyzedLanguages = map[treeutils.LanguageGrammar]bool{
+	treeutils.Kotlin: true,
+	treeutils.Java:   true,
+	treeutils.Scala:  true,
+}
+
+// The package, top-level declarations and imports of a source file of an analyzed language.
+type fileSymbols struct {
+	lang treeutils.LanguageGrammar
+	pkg  string
+
+	// The names of the top-level classes, interfaces, objects and type aliases
+	classes []string
+
+	// The names of the top-level functions and properties
+	functions []string
+
+	// The packages imported by the file
+	imports []string
+}
+
+// Parse the package, top-level declarations and imports of a source file, selecting the
+// grammar by its extension. Files of other languages are ignored.
+func analyzeFile(filePath string, content []byte) (*fileSymbols, []error) {
+	lang, found := treeutils.DetectLanguage(filePath, content)
+	if !found || !analyzedLanguages[lang] {
+		return nil, nil
+	}
+
+	if lang == treeutils.Kotlin {
+		result, errs := parser.NewParser().Parse(filePath, string(content))
+		if result == nil {
+			return nil, errs
+		}
+
+		captures, err := treeutils.ExtractCaptures(lang, filePath, content, declarationsQuery)
+		if err != nil {
+			return nil, append(errs, err)
+		}
+
+		symbols := &fileSymbols{
+			lang:      lang,
+			pkg:       result.Package,
+			classes:   captures.Texts(declarationsQuery, "class"),
+			functions: captures.Texts(declarationsQuery, "function"),
+			imports:   result.Imports,
+		}
+		return symbols, errs
+	}
+
+	tree, err := treeutils.ParseSourceCode(lang, filePath, content)
+	if err != nil {
+		return nil, []error{err}
+	}
+	defer tree.Close()
+
+	captures := tree.Captures(packageQuery, declarationsQuery, importsQuery)
+	symbols := &fileSymbols{
+		lang:      lang,
+		pkg:       strings.Join(captures.Texts(packageQuery, "package"), "."),
+		classes:   captures.Texts(declarationsQuery, "class"),
+		functions: captures.Texts(declarationsQuery, "function"),
+		imports:   make([]string, 0),
+	}
+
+	for m := range captures.Matches(importsQuery) {
+		var imp string
+		if lang == treeutils.Java {
+			imp = javaImportedPackage(m)
+		} else {
+			imp = scalaImportedPackage(m)
+		}
+		if imp != "" {
+			symbols.imports = append(symbols.imports, imp)
+		}
+	}
+
+	return symbols, tree.QueryErrors()
+}
+
+// The package of a java import such as `com.foo` for `import com.foo.Bar` or
+// `import static com.foo.Bar.baz`. Nested classes are not distinguished from packages.
+func javaImportedPackage(m treeutils.CaptureMatch) string {
+	segments := strings.Split(m.Text("path"), ".")
+
+	// The imported class or static member
+	dropped := 1
+	if _, isStar := m.Get("star"); isStar {
+		dropped = 0
+	}
+	if _, isStatic := m.Get("static"); isStatic {
+		dropped++
+	}
+
+	if dropped >= len(segments) {
+		return ""
+	}
+	return strings.Join(segments[:len(segments)-dropped], ".")
+}
+
+// The package of a scala import such as `com.foo` for `import com.foo.Bar`,
+// `import com.foo._` or `import com.foo.{Bar, Baz}`.
+func scalaImportedPackage(m treeutils.CaptureMatch) string {
+	imp, _ := m.Get("import")
+	p := imp.Text
+
+	selector, hasSelector := m.Get("selector")
+	if hasSelector {
+		p = p[:selector.StartByte-imp.StartByte]
+	}
+
+	p = strings.Join(strings.Fields(strings.TrimPrefix(p, "import")), "")
+	p = strings.TrimSuffix(p, ".")
+
+	// The imported class or object
+	if !hasSelector {
+		i := strings.LastIndex(p, ".")
+		if i < 0 {
+			return ""
+		}
+		p = p[:i]
+	}
+
+	return p
+}
+
+// The names of all top-level declarations.
+func (s *fileSymbols) declarations() []string {
+	return append(append([]string{}, s.classes...), s.functions...)
+}
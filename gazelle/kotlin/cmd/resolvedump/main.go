@@ -0,0 +1,270 @@
+// resolvedump parses the kotlin, java and scala sources of a tree and prints the
+// `# gazelle:resolve kotlin kotlin ...` directives resolving the packages they
+// declare to the Bazel packages containing them, for resolving imports of sources
+// gazelle has not generated rules for yet.
+//
+// Usage:
+//
+//	resolvedump [-root dir] [-write file] [-jobs n] [-pattern glob ...] [-exclude glob ...] [pattern ...]
+//	resolvedump [-root dir] -maven-install file [-maven-repository name] [-jobs n] [-pattern glob ...] [-exclude glob ...] [pattern ...]
+//	resolvedump [-root dir] -index file [-jobs n] [-pattern glob ...] [-exclude glob ...] [pattern ...]
+//	resolvedump [-root dir] -stats [-top n] [-jobs n] [-pattern glob ...] [-exclude glob ...] [pattern ...]
+//
+// The patterns such as `src/**/*.kt`, of the repeatable -pattern flag and the arguments,
+// are relative to the root, all kotlin, java and scala files by default. Files and
+// directories matching a pattern of the repeatable -exclude flag such as `**/generated`
+// are skipped, as are those ignored by the .bazelignore file or a .gitignore file.
+//
+// With -maven-install, the packages imported by the sources are instead checked against
+// the artifacts pinned by a maven_install.json file, reporting the packages provided by
+// the artifacts and the packages which can not be resolved.
+//
+// With -index, the package and top-level symbols of each source file are instead written
+// to an index file which the kotlin_symbol_index directive resolves imports against.
+//
+// With -stats, statistics of the sources are instead printed: the number of files parsed,
+// the parse durations and slowest files, the number of imports, the most imported external
+// packages and the packages with the most parse errors.
+//
+// The -cpuprofile, -memprofile and -trace flags write profiles of the analysis, such as
+// for investigating the parse performance of large repositories.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	common "aspect.build/cli/gazelle/common"
+	"aspect.build/cli/gazelle/common/git"
+	"aspect.build/cli/gazelle/kotlin/cmd/internal/profiling"
+	"aspect.build/cli/gazelle/kotlin/maven"
+	"aspect.build/cli/gazelle/kotlin/symbolindex"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/mattn/go-isatty"
+)
+
+// The files analyzed when no pattern is passed.
+var defaultPatterns = []string{"**/*.{kt,kts,java,scala}"}
+
+// The glob patterns of a repeatable flag such as -pattern or -exclude.
+type patternFlags []string
+
+var _ flag.Value = (*patternFlags)(nil)
+
+func (f *patternFlags) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *patternFlags) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+func main() {
+	root := flag.String("root", defaultRoot(), "The directory of the Bazel workspace containing the sources, by default the workspace of `bazel run`.")
+	write := flag.String("write", "", "Path of a BUILD file or directive fragment to merge the directives into instead of printing them, relative to the root.")
+	mavenInstall := flag.String("maven-install", "", "Path of a maven_install.json file to check the imported packages against instead of printing directives, relative to the root.")
+	mavenRepository := flag.String("maven-repository", "maven", "The name of the maven_install repository of the -maven-install file.")
+	indexFile := flag.String("index", "", "Path of a symbol index file to write the symbols of the sources to instead of printing directives, relative to the root.")
+	stats := flag.Bool("stats", false, "Print statistics of parsing the sources and of their imports instead of printing directives.")
+	top := flag.Int("top", 10, "The number of entries of each ranking of -stats, such as the slowest files.")
+	jobs := flag.Int("jobs", runtime.NumCPU(), "The number of files to parse in parallel.")
+	var patterns, excludes patternFlags
+	flag.Var(&patterns, "pattern", "A glob pattern of the files to analyze relative to the root, such as `src/**/*.kt`. Can be repeated, along with patterns passed as arguments.")
+	flag.Var(&excludes, "exclude", "A glob pattern of the files and directories to skip relative to the root, such as `**/generated`. Can be repeated.")
+	profiling.RegisterFlags()
+	flag.Parse()
+
+	if *jobs < 1 {
+		fmt.Fprintf(os.Stderr, "Invalid -jobs: %d, must be at least 1\n", *jobs)
+		os.Exit(2)
+	}
+	if *top < 1 {
+		fmt.Fprintf(os.Stderr, "Invalid -top: %d, must be at least 1\n", *top)
+		os.Exit(2)
+	}
+	modes := countNonEmpty(*write, *mavenInstall, *indexFile)
+	if *stats {
+		modes++
+	}
+	if modes > 1 {
+		fmt.Fprintln(os.Stderr, "Only one of -write, -maven-install, -index and -stats can be used")
+		os.Exit(2)
+	}
+
+	if err := profiling.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to start profiling: %v\n", err)
+		os.Exit(2)
+	}
+	defer profiling.Stop()
+
+	patterns = append(patterns, flag.Args()...)
+	if len(patterns) == 0 {
+		patterns = defaultPatterns
+	}
+
+	files, err := globFiles(*root, patterns, excludes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to find files: %v\n", err)
+		profiling.Exit(2)
+	}
+
+	// Only report progress to a terminal, not when redirected to a file or CI log.
+	var progress *progressReporter
+	if isatty.IsTerminal(os.Stderr.Fd()) || isatty.IsCygwinTerminal(os.Stderr.Fd()) {
+		progress = newProgressReporter(os.Stderr, len(files))
+	}
+
+	index, results, failed := analyzeFiles(*root, files, *jobs, progress)
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "%d of %d file(s) could not be parsed\n", failed, len(files))
+	}
+
+	if *stats {
+		computeStats(index, results).write(os.Stdout, *top)
+		return
+	}
+
+	if *mavenInstall != "" {
+		// The resolver resolves nothing instead of failing if the file does not exist.
+		installPath := resolvePath(*root, *mavenInstall)
+		_, err := os.Stat(installPath)
+		var resolver maven.Resolver
+		if err == nil {
+			resolver, err = maven.NewResolver(installPath)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load %s: %v\n", *mavenInstall, err)
+			profiling.Exit(1)
+		}
+
+		checkMavenImports(index, resolver, *mavenRepository).write(os.Stdout)
+		return
+	}
+
+	if *indexFile != "" {
+		symbols, err := symbolindex.New(index.files)
+		if err == nil {
+			err = symbols.Write(resolvePath(*root, *indexFile))
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write %s: %v\n", *indexFile, err)
+			profiling.Exit(1)
+		}
+
+		fmt.Printf("Wrote the symbols of %d file(s) to %s\n", len(index.files), *indexFile)
+		return
+	}
+
+	directives := index.directives()
+
+	if *write == "" {
+		for _, d := range directives {
+			fmt.Println(d)
+		}
+		return
+	}
+
+	added, conflicts, err := mergeDirectivesFile(resolvePath(*root, *write), directives)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write %s: %v\n", *write, err)
+		profiling.Exit(1)
+	}
+	for _, c := range conflicts {
+		fmt.Fprintf(os.Stderr, "Kept the existing directive of %s: %s\n", filepath.Base(*write), c)
+	}
+	fmt.Printf("Added %d directive(s) to %s\n", added, *write)
+}
+
+// The workspace directory when run using `bazel run`, otherwise the working directory.
+func defaultRoot() string {
+	if dir := os.Getenv("BUILD_WORKSPACE_DIRECTORY"); dir != "" {
+		return dir
+	}
+	return "."
+}
+
+func countNonEmpty(values ...string) int {
+	count := 0
+	for _, v := range values {
+		if v != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// A path relative to the root unless absolute.
+func resolvePath(root, p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(root, p)
+}
+
+// The files relative to the root matching any of the patterns, sorted. Files and
+// directories matching any of the exclude patterns are skipped, as are those ignored
+// by the .bazelignore file or a .gitignore file as in the gazelle walk.
+func globFiles(root string, patterns, excludes []string) ([]string, error) {
+	for _, pattern := range append(append([]string{}, patterns...), excludes...) {
+		if !doublestar.ValidatePattern(pattern) {
+			return nil, fmt.Errorf("%q: %w", pattern, doublestar.ErrBadPattern)
+		}
+	}
+
+	c := config.New()
+	c.RepoRoot = root
+	git.EnableGitignore(c, true)
+	common.CollectExcludes(c, "", nil)
+
+	files := make([]string, 0)
+
+	err := fs.WalkDir(os.DirFS(root), ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if p == "." {
+				git.CollectIgnoreFiles(c, "")
+				return nil
+			}
+			if common.IsIgnored(c, p) || matchesAny(excludes, p) {
+				return fs.SkipDir
+			}
+			git.CollectIgnoreFiles(c, p)
+			return nil
+		}
+
+		if !d.Type().IsRegular() || common.IsIgnored(c, p) || matchesAny(excludes, p) {
+			return nil
+		}
+
+		if matchesAny(patterns, p) {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortStrings(files)
+	return files, nil
+}
+
+// Whether the path matches any of the validated glob patterns.
+func matchesAny(patterns []string, p string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := doublestar.Match(pattern, p); matched {
+			return true
+		}
+	}
+	return false
+}
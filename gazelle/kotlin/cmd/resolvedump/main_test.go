@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestGlobFiles(t *testing.T) {
+	root := t.TempDir()
+	for p, content := range map[string]string{
+		".bazelignore":              "bazel-out\n# comment\n./third_party/vendored\n",
+		".gitignore":                "generated/\n",
+		"src/A.kt":                  "",
+		"src/B.java":                "",
+		"src/.gitignore":            "Ignored.kt\n",
+		"src/Ignored.kt":            "",
+		"src/sub/Ignored.kt":        "",
+		"generated/C.kt":            "",
+		"bazel-out/D.kt":            "",
+		"third_party/E.kt":          "",
+		"third_party/vendored/F.kt": "",
+	} {
+		p = filepath.Join(root, p)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := globFiles(root, []string{"**/*.kt", "src/*.java"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"src/A.kt", "src/B.java", "third_party/E.kt"}
+	if !slices.Equal(files, expected) {
+		t.Errorf("Files...\nactual:   %v\nexpected: %v", files, expected)
+	}
+
+	if _, err := globFiles(root, []string{"src/[a"}, nil); err == nil {
+		t.Errorf("Expected an error for an invalid pattern")
+	}
+	if _, err := globFiles(root, []string{"**/*.kt"}, []string{"src/[a"}); err == nil {
+		t.Errorf("Expected an error for an invalid exclude pattern")
+	}
+}
+
+func TestGlobFilesExcludes(t *testing.T) {
+	root := t.TempDir()
+	for _, p := range []string{
+		"app/src/A.kt",
+		"app/src/ATest.kt",
+		"app/generated/B.kt",
+		"lib/src/C.java",
+		"lib/build/generated/D.java",
+		"vendor/E.kt",
+		"other/F.kt",
+	} {
+		p = filepath.Join(root, p)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := globFiles(root,
+		[]string{"app/**/*.kt", "lib/**/*.java", "vendor/**/*.kt"},
+		[]string{"**/generated", "vendor/**", "**/*Test.kt"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"app/src/A.kt", "lib/src/C.java"}
+	if !slices.Equal(files, expected) {
+		t.Errorf("Files...\nactual:   %v\nexpected: %v", files, expected)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	gazelle "aspect.build/cli/gazelle/kotlin"
+	"aspect.build/cli/gazelle/kotlin/maven"
+	"github.com/bazel-contrib/rules_jvm/java/gazelle/private/types"
+	"github.com/bazelbuild/bazel-gazelle/label"
+)
+
+// The packages imported by the analyzed sources which are neither declared by the
+// analyzed sources nor native, by how the artifacts of a maven_install.json file
+// provide them.
+type mavenReport struct {
+	// The label of the artifact providing each package
+	provided map[string]label.Label
+
+	// The labels of the artifacts providing each package provided by multiple artifacts
+	ambiguous map[string][]label.Label
+
+	// The files importing each package provided by no artifact
+	unresolvable map[string][]string
+}
+
+// Check the packages imported by the analyzed sources against the artifacts of the
+// maven_install repository of a resolver.
+func checkMavenImports(idx *symbolIndex, resolver maven.Resolver, mavenRepositoryName string) *mavenReport {
+	report := &mavenReport{
+		provided:     make(map[string]label.Label),
+		ambiguous:    make(map[string][]label.Label),
+		unresolvable: make(map[string][]string),
+	}
+
+	for pkg, files := range idx.imports {
+		if idx.declares(pkg) || gazelle.IsNativeImport(pkg) {
+			continue
+		}
+
+		l, err := resolver.Resolve(types.NewPackageName(pkg), nil, mavenRepositoryName)
+		if err == nil {
+			report.provided[pkg] = l
+			continue
+		}
+
+		var ambiguous *maven.AmbiguousPackageError
+		if errors.As(err, &ambiguous) {
+			report.ambiguous[pkg] = ambiguous.Artifacts
+			continue
+		}
+
+		importers := make([]string, 0, len(files))
+		for f := range files {
+			importers = append(importers, f)
+		}
+		sortStrings(importers)
+		report.unresolvable[pkg] = importers
+	}
+
+	return report
+}
+
+// Write the report grouped by how the packages are provided, each group sorted by package.
+func (r *mavenReport) write(w io.Writer) {
+	if len(r.provided) > 0 {
+		fmt.Fprintln(w, "Provided by maven artifacts:")
+		for _, pkg := range sortedKeys(r.provided) {
+			fmt.Fprintf(w, "\t%s: %s\n", pkg, r.provided[pkg])
+		}
+	}
+
+	if len(r.ambiguous) > 0 {
+		fmt.Fprintln(w, "Provided by multiple maven artifacts:")
+		for _, pkg := range sortedKeys(r.ambiguous) {
+			artifacts := make([]string, len(r.ambiguous[pkg]))
+			for i, a := range r.ambiguous[pkg] {
+				artifacts[i] = a.String()
+			}
+			fmt.Fprintf(w, "\t%s: %s\n", pkg, strings.Join(artifacts, ", "))
+		}
+	}
+
+	if len(r.unresolvable) > 0 {
+		fmt.Fprintln(w, "Unresolvable:")
+		for _, pkg := range sortedKeys(r.unresolvable) {
+			fmt.Fprintf(w, "\t%s: imported by %s\n", pkg, strings.Join(r.unresolvable[pkg], ", "))
+		}
+	}
+
+	fmt.Fprintf(w, "%d package(s) provided by maven artifacts, %d by multiple artifacts, %d unresolvable\n", len(r.provided), len(r.ambiguous), len(r.unresolvable))
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sortStrings(keys)
+	return keys
+}
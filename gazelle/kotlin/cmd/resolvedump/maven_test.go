@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"aspect.build/cli/gazelle/kotlin/maven"
+)
+
+func TestCheckMavenImports(t *testing.T) {
+	installFile := filepath.Join(t.TempDir(), "maven_install.json")
+	err := os.WriteFile(installFile, []byte(`{
+		"packages": {
+			"com.google.guava:guava": ["com.google.common.collect"],
+			"org.a:one": ["org.shared"],
+			"org.b:two": ["org.shared"]
+		}
+	}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolver, err := maven.NewResolver(installFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idx := newSymbolIndex()
+	idx.add("src", "com.local", []string{"Outer"})
+	idx.addImports("src/A.kt", []string{"com.google.common.collect", "com.local", "com.local.Outer", "kotlin.collections", "java.util"})
+	idx.addImports("src/B.java", []string{"org.shared", "com.missing"})
+	idx.addImports("src/C.scala", []string{"com.missing"})
+
+	var out strings.Builder
+	checkMavenImports(idx, resolver, "maven").write(&out)
+
+	expected := `Provided by maven artifacts:
+	com.google.common.collect: @maven//:com_google_guava_guava
+Provided by multiple maven artifacts:
+	org.shared: @maven//:org_a_one, @maven//:org_b_two
+Unresolvable:
+	com.missing: imported by src/B.java, src/C.scala
+1 package(s) provided by maven artifacts, 1 by multiple artifacts, 1 unresolvable
+`
+	if out.String() != expected {
+		t.Errorf("Report...\nactual:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+}
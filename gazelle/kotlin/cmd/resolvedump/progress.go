@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Reports the number of analyzed files and the estimated time remaining, redrawn
+// on a single line of a terminal at most every progressInterval.
+type progressReporter struct {
+	out   io.Writer
+	total int
+	start time.Time
+
+	mutex    sync.Mutex
+	count    int
+	reported time.Time
+}
+
+const progressInterval = 100 * time.Millisecond
+
+// A reporter of the progress of analyzing a number of files, or nil to not report
+// progress such as when the output is not a terminal.
+func newProgressReporter(out io.Writer, total int) *progressReporter {
+	return &progressReporter{out: out, total: total, start: time.Now()}
+}
+
+// Record a file as analyzed.
+func (p *progressReporter) increment() {
+	if p == nil {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.count++
+
+	now := time.Now()
+	if now.Sub(p.reported) < progressInterval && p.count < p.total {
+		return
+	}
+	p.reported = now
+
+	fmt.Fprintf(p.out, "\r\033[KAnalyzed %d/%d file(s)%s", p.count, p.total, p.eta(now))
+}
+
+// Clear the progress line once all files are analyzed.
+func (p *progressReporter) done() {
+	if p == nil {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	fmt.Fprint(p.out, "\r\033[K")
+}
+
+// The estimated time remaining, extrapolated from the average time per file so far.
+func (p *progressReporter) eta(now time.Time) string {
+	if p.count == 0 || p.count >= p.total {
+		return ""
+	}
+
+	elapsed := now.Sub(p.start)
+	remaining := time.Duration(float64(elapsed) / float64(p.count) * float64(p.total-p.count))
+
+	return fmt.Sprintf(", %s remaining", remaining.Round(time.Second))
+}
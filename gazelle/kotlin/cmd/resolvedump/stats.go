@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	treeutils "aspect.build/cli/gazelle/common/treesitter"
+	gazelle "aspect.build/cli/gazelle/kotlin"
+)
+
+// The statistics of parsing the analyzed sources and of their imports, for tuning the
+// performance of gazelle and dependency hygiene.
+type parseStats struct {
+	// The number of files parsed, by language
+	files     int
+	languages map[treeutils.LanguageGrammar]int
+
+	// The files with parse errors
+	failed int
+
+	// The parse duration of each file, slowest first
+	durations []fileDuration
+
+	// The number of imports of all files
+	imports int
+
+	// The number of files importing each package neither declared by the analyzed
+	// sources nor native
+	external map[string]int
+
+	// The files with parse errors and the number of errors of each Bazel package
+	errorFiles map[string]int
+	errors     map[string]int
+}
+
+type fileDuration struct {
+	file     string
+	duration time.Duration
+}
+
+// Compute the statistics of the results of analyzing the files of an index.
+func computeStats(idx *symbolIndex, results []analyzeFileResult) *parseStats {
+	stats := &parseStats{
+		languages:  make(map[treeutils.LanguageGrammar]int),
+		external:   make(map[string]int),
+		errorFiles: make(map[string]int),
+		errors:     make(map[string]int),
+	}
+
+	for _, r := range results {
+		if r.readErr != nil || (r.symbols == nil && len(r.errs) == 0) {
+			continue
+		}
+
+		stats.files++
+		stats.durations = append(stats.durations, fileDuration{file: r.file, duration: r.duration})
+
+		if r.symbols != nil {
+			stats.languages[r.symbols.lang]++
+			stats.imports += len(r.symbols.imports)
+		}
+
+		if len(r.errs) > 0 {
+			stats.failed++
+			stats.errorFiles[bazelPackage(r.file)]++
+			stats.errors[bazelPackage(r.file)] += len(r.errs)
+		}
+	}
+
+	sort.SliceStable(stats.durations, func(i, j int) bool {
+		return stats.durations[i].duration > stats.durations[j].duration
+	})
+
+	for pkg, files := range idx.imports {
+		if !idx.declares(pkg) && !gazelle.IsNativeImport(pkg) {
+			stats.external[pkg] = len(files)
+		}
+	}
+
+	return stats
+}
+
+// The total parse duration of all files.
+func (s *parseStats) totalDuration() time.Duration {
+	var total time.Duration
+	for _, d := range s.durations {
+		total += d.duration
+	}
+	return total
+}
+
+// The parse duration of the percentile of the files, such as 0.9 for the duration
+// 90% of the files are parsed within.
+func (s *parseStats) percentile(p float64) time.Duration {
+	if len(s.durations) == 0 {
+		return 0
+	}
+
+	// The durations are sorted slowest first
+	i := int(float64(len(s.durations)-1) * (1 - p))
+	return s.durations[i].duration
+}
+
+// The keys of the counts sorted by count, highest first, then by key, at most n.
+func topCounts(counts map[string]int, n int) []string {
+	keys := sortedKeys(counts)
+	sort.SliceStable(keys, func(i, j int) bool {
+		return counts[keys[i]] > counts[keys[j]]
+	})
+	return keys[:min(n, len(keys))]
+}
+
+// Write the statistics, listing at most top entries of each ranking.
+func (s *parseStats) write(w io.Writer, top int) {
+	fmt.Fprintf(w, "Files parsed: %d", s.files)
+	for _, lang := range []treeutils.LanguageGrammar{treeutils.Kotlin, treeutils.Java, treeutils.Scala} {
+		if s.languages[lang] > 0 {
+			fmt.Fprintf(w, ", %d %s", s.languages[lang], lang)
+		}
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Files with parse errors: %d\n", s.failed)
+
+	total := s.totalDuration()
+	mean := time.Duration(0)
+	if s.files > 0 {
+		mean = total / time.Duration(s.files)
+	}
+	fmt.Fprintf(w, "Parse duration: %s total, %s mean, %s p50, %s p90, %s p99\n",
+		roundDuration(total), roundDuration(mean), roundDuration(s.percentile(0.5)), roundDuration(s.percentile(0.9)), roundDuration(s.percentile(0.99)))
+
+	meanImports := 0.0
+	if s.files > 0 {
+		meanImports = float64(s.imports) / float64(s.files)
+	}
+	fmt.Fprintf(w, "Imports: %d, %.1f per file, %d external package(s)\n", s.imports, meanImports, len(s.external))
+
+	if len(s.durations) > 0 {
+		fmt.Fprintln(w, "Slowest files:")
+		for _, d := range s.durations[:min(top, len(s.durations))] {
+			fmt.Fprintf(w, "\t%s: %s\n", d.file, roundDuration(d.duration))
+		}
+	}
+
+	if len(s.external) > 0 {
+		fmt.Fprintln(w, "Most imported external packages:")
+		for _, pkg := range topCounts(s.external, top) {
+			fmt.Fprintf(w, "\t%s: imported by %d file(s)\n", pkg, s.external[pkg])
+		}
+	}
+
+	if len(s.errors) > 0 {
+		fmt.Fprintln(w, "Parse error hotspots:")
+		for _, pkg := range topCounts(s.errors, top) {
+			dir := pkg
+			if dir == "" {
+				dir = "."
+			}
+			fmt.Fprintf(w, "\t%s: %d error(s) in %d file(s)\n", dir, s.errors[pkg], s.errorFiles[pkg])
+		}
+	}
+}
+
+// The duration rounded for display, to microseconds below a second.
+func roundDuration(d time.Duration) time.Duration {
+	if d < time.Second {
+		return d.Round(time.Microsecond)
+	}
+	return d.Round(time.Millisecond)
+}
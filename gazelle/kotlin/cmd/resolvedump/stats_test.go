@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	treeutils "aspect.build/cli/gazelle/common/treesitter"
+)
+
+func TestComputeStats(t *testing.T) {
+	idx := newSymbolIndex()
+	idx.add("app", "com.example.app", []string{"App"})
+	idx.addImports("app/App.kt", []string{"com.google.common.collect", "kotlin.collections", "com.example.lib"})
+	idx.addImports("lib/Lib.kt", []string{"com.google.common.collect", "okhttp3"})
+	idx.add("lib", "com.example.lib", []string{"Lib"})
+
+	results := []analyzeFileResult{
+		{
+			file:     "app/App.kt",
+			symbols:  &fileSymbols{lang: treeutils.Kotlin, pkg: "com.example.app", imports: []string{"com.google.common.collect", "kotlin.collections", "com.example.lib"}},
+			duration: 3 * time.Millisecond,
+		},
+		{
+			file:     "lib/Lib.kt",
+			symbols:  &fileSymbols{lang: treeutils.Kotlin, pkg: "com.example.lib", imports: []string{"com.google.common.collect", "okhttp3"}},
+			duration: 1 * time.Millisecond,
+		},
+		{
+			file:     "gen/Broken.java",
+			symbols:  &fileSymbols{lang: treeutils.Java, imports: []string{}},
+			errs:     []error{errors.New("a"), errors.New("b")},
+			duration: 5 * time.Millisecond,
+		},
+		{file: "unreadable/A.kt", readErr: errors.New("denied")},
+		{file: "BUILD.bazel"},
+	}
+
+	stats := computeStats(idx, results)
+
+	if stats.files != 3 || stats.languages[treeutils.Kotlin] != 2 || stats.languages[treeutils.Java] != 1 {
+		t.Errorf("Files: %d, languages: %v", stats.files, stats.languages)
+	}
+	if stats.failed != 1 || stats.errors["gen"] != 2 || stats.errorFiles["gen"] != 1 {
+		t.Errorf("Parse errors: %d, %v, %v", stats.failed, stats.errors, stats.errorFiles)
+	}
+	if stats.imports != 5 {
+		t.Errorf("Imports: %d, expected 5", stats.imports)
+	}
+	if stats.totalDuration() != 9*time.Millisecond || stats.durations[0].file != "gen/Broken.java" {
+		t.Errorf("Durations: %v", stats.durations)
+	}
+	if stats.percentile(0.5) != 3*time.Millisecond {
+		t.Errorf("Median duration: %s, expected 3ms", stats.percentile(0.5))
+	}
+
+	// Packages declared by the sources and native packages are not external
+	expectedExternal := map[string]int{"com.google.common.collect": 2, "okhttp3": 1}
+	if len(stats.external) != len(expectedExternal) {
+		t.Errorf("External packages...\nactual:  %v;\nexpected: %v", stats.external, expectedExternal)
+	}
+	for pkg, count := range expectedExternal {
+		if stats.external[pkg] != count {
+			t.Errorf("External packages...\nactual:  %v;\nexpected: %v", stats.external, expectedExternal)
+		}
+	}
+
+	var out strings.Builder
+	stats.write(&out, 1)
+	for _, expected := range []string{
+		"Files parsed: 3, 2 kotlin, 1 java\n",
+		"Imports: 5, 1.7 per file, 2 external package(s)\n",
+		"Slowest files:\n\tgen/Broken.java: 5ms\nMost",
+		"\tcom.google.common.collect: imported by 2 file(s)\nParse",
+		"\tgen: 2 error(s) in 1 file(s)\n",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected %q in the statistics:\n%s", expected, out.String())
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// unuseddeps compares the dependencies of the existing kotlin rules of a repository
+// against the imports of their sources and reports the dependencies nothing imports,
+// which gazelle keeps in binaries and tests or when marked `# keep`.
+//
+// Usage:
+//
+//	unuseddeps [-root dir] [-buildozer] [-keep] [gazelle flag ...] [dir ...]
+//
+// Imports are resolved by running the kotlin extension without writing BUILD files,
+// as for the dependency graph of the depgraph command. Dependencies added by the
+// configuration such as the kotlin_extra_deps directive are not reported. The gazelle
+// flags and directories following the unuseddeps flags are passed to gazelle, all
+// directories of the repository are visited by default.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"aspect.build/cli/gazelle/kotlin/cmd/internal/profiling"
+	"aspect.build/cli/gazelle/kotlin/cmd/internal/rungazelle"
+)
+
+func main() {
+	root := flag.String("root", defaultRoot(), "The directory of the Bazel workspace containing the sources, by default the workspace of `bazel run`.")
+	buildozer := flag.Bool("buildozer", false, "Print buildozer commands removing the unused dependencies instead of reporting them.")
+	includeKeep := flag.Bool("keep", false, "Include the dependencies marked `# keep`.")
+	profiling.RegisterFlags()
+	flag.Parse()
+
+	if err := profiling.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to start profiling: %v\n", err)
+		os.Exit(2)
+	}
+	defer profiling.Stop()
+
+	// gazelle resolves the repository relative to the working directory.
+	if err := os.Chdir(*root); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to change to the root %s: %v\n", *root, err)
+		profiling.Exit(2)
+	}
+
+	targets, err := rungazelle.LoadDepGraph(flag.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to resolve the kotlin imports: %v\n", err)
+		profiling.Exit(1)
+	}
+
+	unused, err := findUnusedDeps(".", targets, *includeKeep)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read the BUILD files: %v\n", err)
+		profiling.Exit(1)
+	}
+
+	if *buildozer {
+		writeBuildozerCommands(os.Stdout, unused)
+		return
+	}
+
+	writeReport(os.Stdout, unused)
+}
+
+// The workspace directory when run using `bazel run`, otherwise the working directory.
+func defaultRoot() string {
+	if dir := os.Getenv("BUILD_WORKSPACE_DIRECTORY"); dir != "" {
+		return dir
+	}
+	return "."
+}
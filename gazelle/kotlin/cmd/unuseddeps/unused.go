@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	kotlin "aspect.build/cli/gazelle/kotlin"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	bzl "github.com/bazelbuild/buildtools/build"
+)
+
+// The attributes of the kotlin rules checked for unused dependencies.
+var depsAttrs = []string{"deps", "compile_only_deps"}
+
+// The names of the BUILD files of a package, in order of precedence.
+var buildFileNames = []string{"BUILD.bazel", "BUILD"}
+
+// The existing dependencies of a kotlin rule attribute nothing imports.
+type unusedDeps struct {
+	target label.Label
+	attr   string
+
+	// The dependencies as written in the BUILD file
+	deps []string
+}
+
+// Find the dependencies of the existing rules of the graph targets not required by
+// the imports of their sources or the configuration, sorted by target. Dependencies
+// marked `# keep` are skipped unless includeKeep is set.
+func findUnusedDeps(root string, targets []kotlin.DepGraphTarget, includeKeep bool) ([]unusedDeps, error) {
+	files := make(map[string]*rule.File)
+	unused := make([]unusedDeps, 0)
+
+	for _, target := range targets {
+		from, err := label.Parse(target.Label)
+		if err != nil {
+			return nil, fmt.Errorf("invalid target %q: %w", target.Label, err)
+		}
+
+		f, loaded := files[from.Pkg]
+		if !loaded {
+			f, err = loadBuildFile(root, from.Pkg)
+			if err != nil {
+				return nil, err
+			}
+			files[from.Pkg] = f
+		}
+		if f == nil {
+			continue
+		}
+
+		for _, r := range f.Rules {
+			if r.Name() == from.Name {
+				unused = append(unused, unusedRuleDeps(r, target, from, includeKeep)...)
+				break
+			}
+		}
+	}
+
+	sort.SliceStable(unused, func(i, j int) bool {
+		return unused[i].target.String() < unused[j].target.String()
+	})
+	return unused, nil
+}
+
+// The unused dependencies of each attribute of an existing rule, the dependencies
+// not in the graph or only preserved from the existing rule.
+func unusedRuleDeps(r *rule.Rule, target kotlin.DepGraphTarget, from label.Label, includeKeep bool) []unusedDeps {
+	used := make(map[label.Label]bool, len(target.Deps))
+	for _, dep := range target.Deps {
+		if dep.Preserved {
+			continue
+		}
+		if l, err := label.Parse(dep.Label); err == nil {
+			used[l.Abs(from.Repo, from.Pkg)] = true
+		}
+	}
+
+	unused := make([]unusedDeps, 0)
+	for _, attr := range depsAttrs {
+		list, isList := r.Attr(attr).(*bzl.ListExpr)
+		if !isList {
+			continue
+		}
+
+		attrUnused := unusedDeps{target: from, attr: attr}
+		for _, e := range list.List {
+			s, isString := e.(*bzl.StringExpr)
+			if !isString || (!includeKeep && rule.ShouldKeep(e)) {
+				continue
+			}
+
+			l, err := label.Parse(s.Value)
+			if err != nil || used[l.Abs(from.Repo, from.Pkg)] {
+				continue
+			}
+			attrUnused.deps = append(attrUnused.deps, s.Value)
+		}
+
+		if len(attrUnused.deps) > 0 {
+			unused = append(unused, attrUnused)
+		}
+	}
+	return unused
+}
+
+// Load the BUILD file of a package, nil if the package has none.
+func loadBuildFile(root, pkg string) (*rule.File, error) {
+	for _, name := range buildFileNames {
+		path := filepath.Join(root, filepath.FromSlash(pkg), name)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		return rule.LoadFile(path, pkg)
+	}
+	return nil, nil
+}
+
+// Write the unused dependencies grouped by target.
+func writeReport(w io.Writer, unused []unusedDeps) {
+	for i, u := range unused {
+		if i == 0 || unused[i-1].target != u.target {
+			fmt.Fprintf(w, "%s:\n", u.target)
+		}
+		for _, dep := range u.deps {
+			if u.attr == "deps" {
+				fmt.Fprintf(w, "\t%s\n", dep)
+			} else {
+				fmt.Fprintf(w, "\t%s (%s)\n", dep, u.attr)
+			}
+		}
+	}
+}
+
+// Write a buildozer command removing the unused dependencies of each attribute.
+func writeBuildozerCommands(w io.Writer, unused []unusedDeps) {
+	for _, u := range unused {
+		fmt.Fprintf(w, "buildozer 'remove %s %s' %s\n", u.attr, strings.Join(u.deps, " "), u.target)
+	}
+}
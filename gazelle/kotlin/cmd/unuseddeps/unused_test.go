@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	kotlin "aspect.build/cli/gazelle/kotlin"
+)
+
+func TestFindUnusedDeps(t *testing.T) {
+	root := t.TempDir()
+
+	build := `
+kt_jvm_binary(
+    name = "app",
+    deps = [
+        ":helper",
+        "//lib/used",
+        "//lib/unused",
+        "//lib/kept",  # keep
+        "//lib/preserved",
+        "@maven//:com_google_dagger_dagger",
+    ],
+    compile_only_deps = [
+        "//lib/annotations",
+        "@maven//:javax_inject_javax_inject",
+    ],
+)
+
+kt_jvm_library(
+    name = "helper",
+    deps = ["//lib/used"],
+)
+`
+	if err := os.MkdirAll(filepath.Join(root, "app"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "app", "BUILD.bazel"), []byte(build), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	targets := []kotlin.DepGraphTarget{
+		{
+			Label: "//app",
+			Kind:  "kt_jvm_binary",
+			Deps: []kotlin.DepGraphDep{
+				{Label: "//app:helper", Imports: []string{"app.Helper"}},
+				{Label: "//lib/preserved", Preserved: true},
+				{Label: "//lib/used", Imports: []string{"lib.used.Used"}},
+				{Label: "@maven//:com_google_dagger_dagger"},
+				{Label: "@maven//:javax_inject_javax_inject", Imports: []string{"javax.inject.Inject"}, CompileOnly: true},
+			},
+		},
+		{
+			Label: "//app:helper",
+			Kind:  "kt_jvm_library",
+			Deps: []kotlin.DepGraphDep{
+				{Label: "//lib/used", Imports: []string{"lib.used.Used"}},
+			},
+		},
+		{
+			Label: "//missing",
+			Kind:  "kt_jvm_library",
+			Deps:  []kotlin.DepGraphDep{},
+		},
+	}
+
+	unused, err := findUnusedDeps(root, targets, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var report strings.Builder
+	writeReport(&report, unused)
+	expectedReport := `//app:
+	//lib/unused
+	//lib/preserved
+	//lib/annotations (compile_only_deps)
+`
+	if report.String() != expectedReport {
+		t.Errorf("writeReport()...\nactual:\n%s\nexpected:\n%s", report.String(), expectedReport)
+	}
+
+	var commands strings.Builder
+	writeBuildozerCommands(&commands, unused)
+	expectedCommands := `buildozer 'remove deps //lib/unused //lib/preserved' //app
+buildozer 'remove compile_only_deps //lib/annotations' //app
+`
+	if commands.String() != expectedCommands {
+		t.Errorf("writeBuildozerCommands()...\nactual:\n%s\nexpected:\n%s", commands.String(), expectedCommands)
+	}
+
+	unused, err = findUnusedDeps(root, targets, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := unused[0].deps, []string{"//lib/unused", "//lib/kept", "//lib/preserved"}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("findUnusedDeps(includeKeep)...\nactual:  %v;\nexpected: %v", actual, expected)
+	}
+}
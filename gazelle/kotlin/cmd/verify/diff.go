@@ -0,0 +1,26 @@
+package main
+
+import (
+	"strings"
+)
+
+// The BUILD files changed by a unified diff written by gazelle, in the order of the
+// diff. Files created by the diff are included, named by their new path.
+func diffFiles(diff string) []string {
+	files := make([]string, 0)
+
+	for _, line := range strings.Split(diff, "\n") {
+		name, isFile := strings.CutPrefix(line, "+++ ")
+		if !isFile {
+			continue
+		}
+
+		// The file name is followed by a tab and the timestamp
+		if i := strings.IndexByte(name, '\t'); i >= 0 {
+			name = name[:i]
+		}
+		files = append(files, name)
+	}
+
+	return files
+}
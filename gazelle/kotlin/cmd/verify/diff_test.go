@@ -0,0 +1,32 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDiffFiles(t *testing.T) {
+	diff := `--- app/BUILD.bazel	1970-01-01 00:00:00.000000001 +0000
++++ app/BUILD.bazel	1970-01-01 00:00:00.000000001 +0000
+@@ -1,3 +1,4 @@
+ kt_jvm_library(
+     name = "app",
++    deps = ["//lib"],
+ )
+--- /dev/null	1970-01-01 00:00:00.000000001 +0000
++++ lib/BUILD.bazel	1970-01-01 00:00:00.000000001 +0000
+@@ -0,0 +1,3 @@
++kt_jvm_library(
++    name = "lib",
++)
+`
+
+	expected := []string{"app/BUILD.bazel", "lib/BUILD.bazel"}
+	if actual := diffFiles(diff); !slices.Equal(actual, expected) {
+		t.Errorf("diffFiles()...\nactual:  %v;\nexpected: %v", actual, expected)
+	}
+
+	if actual := diffFiles(""); len(actual) != 0 {
+		t.Errorf("diffFiles() of an empty diff: %v", actual)
+	}
+}
@@ -0,0 +1,101 @@
+// verify runs the kotlin gazelle extension over a repository without writing BUILD
+// files, and checks the BUILD files are up to date with the generated rules, for
+// "BUILD files are up to date" checks in CI.
+//
+// Usage:
+//
+//	verify [-root dir] [-patch file] [gazelle flag ...] [dir ...]
+//
+// The unified diff of the BUILD files which differ from the generated rules is printed,
+// and the exit code is 1 if any differ, 0 if all are up to date, or 2 if the generation
+// failed. Failures once the BUILD files are generated, such as imports failing to
+// resolve, still print and write the diff. The gazelle flags and directories following the verify flags, such as
+// `-kotlin-directive`, are passed to gazelle, all directories of the repository are
+// visited by default.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"aspect.build/cli/gazelle/kotlin/cmd/internal/profiling"
+	"aspect.build/cli/gazelle/kotlin/cmd/internal/rungazelle"
+)
+
+func main() {
+	root := flag.String("root", defaultRoot(), "The directory of the Bazel workspace containing the sources, by default the workspace of `bazel run`.")
+	patch := flag.String("patch", "", "Path of a file to also write the diff to, such as a CI artifact to apply with `git apply`.")
+	profiling.RegisterFlags()
+	flag.Parse()
+
+	if err := profiling.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to start profiling: %v\n", err)
+		os.Exit(2)
+	}
+	defer profiling.Stop()
+
+	// Relative to the working directory, before changing to the root.
+	patchFile := *patch
+	if patchFile != "" && !filepath.IsAbs(patchFile) {
+		wd, err := os.Getwd()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to resolve %s: %v\n", patchFile, err)
+			profiling.Exit(2)
+		}
+		patchFile = filepath.Join(wd, patchFile)
+	}
+
+	// gazelle resolves the repository relative to the working directory.
+	if err := os.Chdir(*root); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to change to the root %s: %v\n", *root, err)
+		profiling.Exit(2)
+	}
+
+	if code := verify(os.Stdout, os.Stderr, patchFile, flag.Args()); code != 0 {
+		profiling.Exit(code)
+	}
+}
+
+// Verify the BUILD files of the working directory, printing the diff to stdout and
+// writing it to the patch file if any, and returning the exit code.
+func verify(stdout, stderr io.Writer, patchFile string, args []string) int {
+	diff, genErr := rungazelle.Diff(args)
+
+	if patchFile != "" && (genErr == nil || diff != "") {
+		if err := os.WriteFile(patchFile, []byte(diff), 0644); err != nil {
+			fmt.Fprintf(stderr, "Failed to write %s: %v\n", patchFile, err)
+			return 2
+		}
+	}
+
+	files := diffFiles(diff)
+	if len(files) > 0 {
+		fmt.Fprint(stdout, diff)
+		fmt.Fprintf(stderr, "%d BUILD file(s) are not up to date, run gazelle to update them:\n", len(files))
+		for _, f := range files {
+			fmt.Fprintf(stderr, "  %s\n", f)
+		}
+	}
+
+	if genErr != nil {
+		fmt.Fprintf(stderr, "Failed to generate the BUILD files: %v\n", genErr)
+		return 2
+	}
+	if len(files) > 0 {
+		return 1
+	}
+
+	fmt.Fprintln(stderr, "BUILD files are up to date")
+	return 0
+}
+
+// The workspace directory when run using `bazel run`, otherwise the working directory.
+func defaultRoot() string {
+	if dir := os.Getenv("BUILD_WORKSPACE_DIRECTORY"); dir != "" {
+		return dir
+	}
+	return "."
+}
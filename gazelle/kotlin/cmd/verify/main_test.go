@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		files    map[string]string
+		code     int
+		stderr   string
+		patchAdd string
+	}{
+		{
+			name: "not up to date",
+			files: map[string]string{
+				"lib/Lib.kt": "package lib\n\nclass Lib\n",
+			},
+			code:     1,
+			stderr:   "1 BUILD file(s) are not up to date",
+			patchAdd: "+    srcs = [\"Lib.kt\"],",
+		},
+		{
+			name: "unresolved imports",
+			files: map[string]string{
+				"BUILD.bazel": "# gazelle:kotlin_validate_import_statements error\n",
+				"lib/Lib.kt":  "package lib\n\nimport missing.Thing\n\nclass Lib(val t: Thing)\n",
+			},
+			code:     2,
+			stderr:   "Failed to generate the BUILD files: failed to resolve or validate kotlin dependencies",
+			patchAdd: "+    srcs = [\"Lib.kt\"],",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+			tc.files["WORKSPACE"] = "workspace(name = \"verify\")\n"
+			for p, content := range tc.files {
+				p = filepath.Join(root, p)
+				if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			chdir(t, root)
+
+			patchFile := filepath.Join(t.TempDir(), "verify.patch")
+			var stdout, stderr bytes.Buffer
+			if code := verify(&stdout, &stderr, patchFile, nil); code != tc.code {
+				t.Errorf("verify() exit code: expected %d, got %d\n%s", tc.code, code, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tc.stderr) {
+				t.Errorf("verify() stderr: expected %q, got:\n%s", tc.stderr, stderr.String())
+			}
+
+			patch, err := os.ReadFile(patchFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(patch), tc.patchAdd) {
+				t.Errorf("verify() patch: expected %q, got:\n%s", tc.patchAdd, patch)
+			}
+			if stdout.String() != string(patch) {
+				t.Errorf("verify() stdout: expected the patch, got:\n%s", stdout.String())
+			}
+		})
+	}
+}
+
+// Change the working directory for the duration of the test.
+func chdir(t *testing.T, dir string) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Chdir(wd)
+	})
+}
@@ -0,0 +1,149 @@
+// watch keeps the kotlin rules of a repository up to date during development by
+// watching the kotlin sources for changes and running gazelle with the kotlin
+// extension on the packages of the changed files.
+//
+// Usage:
+//
+//	watch [-root dir] [-interval duration] [-resolution-cache file] [gazelle flag ...]
+//
+// The whole repository is updated once when starting, then only the directories of
+// the sources added, removed or modified since the previous update. The maven
+// resolutions of imported packages are persisted between updates and runs in the
+// resolution cache, so only the imports of new packages are resolved against the
+// maven_install.json files. The sources of the changed directories are parsed again
+// on each update. The gazelle flags following the watch flags are passed to every
+// update.
+//
+// Updates failing, such as on imports failing to resolve, are reported and the watch
+// continues with the next change.
+package main
+
+import (
+	"crypto/sha256"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"aspect.build/cli/gazelle/kotlin/cmd/internal/profiling"
+	"aspect.build/cli/gazelle/kotlin/cmd/internal/rungazelle"
+)
+
+func main() {
+	root := flag.String("root", defaultRoot(), "The directory of the Bazel workspace containing the sources, by default the workspace of `bazel run`.")
+	interval := flag.Duration("interval", time.Second, "How often the sources are scanned for changes.")
+	cacheFile := flag.String("resolution-cache", "", "Path of the file persisting the maven resolutions of kotlin imports between updates, by default in the user cache directory.")
+	profiling.RegisterFlags()
+	flag.Parse()
+
+	if *interval <= 0 {
+		fmt.Fprintf(os.Stderr, "Invalid -interval: %s, must be positive\n", *interval)
+		os.Exit(2)
+	}
+
+	if err := profiling.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to start profiling: %v\n", err)
+		os.Exit(2)
+	}
+	defer profiling.Stop()
+
+	absRoot, err := filepath.Abs(*root)
+	if err == nil {
+		// gazelle resolves the repository and the directories to visit relative to the working directory.
+		err = os.Chdir(absRoot)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to change to the root %s: %v\n", *root, err)
+		profiling.Exit(2)
+	}
+
+	gazelleArgs := flag.Args()
+	if *cacheFile == "" {
+		*cacheFile = defaultResolutionCache(absRoot)
+	}
+	if *cacheFile != "" {
+		gazelleArgs = append([]string{"-kotlin-resolution-cache=" + *cacheFile}, gazelleArgs...)
+	}
+
+	snapshot, err := scanSources(".")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to scan the sources: %v\n", err)
+		profiling.Exit(1)
+	}
+
+	update(gazelleArgs)
+	fmt.Printf("Watching %d kotlin file(s) for changes\n", len(snapshot))
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-interrupt:
+			return
+		case <-ticker.C:
+		}
+
+		current, err := scanSources(".")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to scan the sources: %v\n", err)
+			continue
+		}
+
+		dirs := changedDirs(snapshot, current)
+		snapshot = current
+		if len(dirs) == 0 {
+			continue
+		}
+
+		dirs = existingDirs(".", dirs)
+		fmt.Printf("Sources changed in %s\n", strings.Join(dirs, ", "))
+
+		// Only the changed directories, not their subdirectories
+		args := append(append([]string{}, gazelleArgs...), "-r=false")
+		update(append(args, dirs...))
+	}
+}
+
+// Update the BUILD files, reporting failures without stopping to watch.
+func update(args []string) {
+	start := time.Now()
+	if err := rungazelle.Update(args); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to update the BUILD files: %v\n", err)
+		return
+	}
+	fmt.Printf("Updated in %s\n", time.Since(start).Round(time.Millisecond))
+}
+
+// The resolution cache of the repository in the user cache directory, none if the
+// user has no cache directory.
+func defaultResolutionCache(root string) string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+
+	dir := filepath.Join(cacheDir, "aspect", "kotlin-watch")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return ""
+	}
+
+	// One cache per repository, by the digest of its path
+	digest := sha256.Sum256([]byte(root))
+	return filepath.Join(dir, fmt.Sprintf("resolutions-%x.json", digest[:8]))
+}
+
+// The workspace directory when run using `bazel run`, otherwise the working directory.
+func defaultRoot() string {
+	if dir := os.Getenv("BUILD_WORKSPACE_DIRECTORY"); dir != "" {
+		return dir
+	}
+	return "."
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	common "aspect.build/cli/gazelle/common"
+	"aspect.build/cli/gazelle/common/git"
+	"github.com/bazelbuild/bazel-gazelle/config"
+)
+
+// The extensions of the watched source files.
+var sourceExtensions = []string{".kt", ".kts"}
+
+// The modification time and size of a source file when scanned.
+type sourceState struct {
+	modTime time.Time
+	size    int64
+}
+
+// The states of the source files of a tree by path relative to the root.
+type sourceSnapshot map[string]sourceState
+
+// Scan the kotlin source files of the tree of the root. Files and directories ignored
+// by the .bazelignore file or a .gitignore file are skipped, as in the gazelle walk.
+func scanSources(root string) (sourceSnapshot, error) {
+	c := config.New()
+	c.RepoRoot = root
+	git.EnableGitignore(c, true)
+	common.CollectExcludes(c, "", nil)
+
+	snapshot := make(sourceSnapshot)
+
+	err := fs.WalkDir(os.DirFS(root), ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Files removed while scanning are picked up by the next scan
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		if d.IsDir() {
+			if p == "." {
+				git.CollectIgnoreFiles(c, "")
+				return nil
+			}
+			if common.IsIgnored(c, p) {
+				return fs.SkipDir
+			}
+			git.CollectIgnoreFiles(c, p)
+			return nil
+		}
+
+		if !d.Type().IsRegular() || !isSource(p) || common.IsIgnored(c, p) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		snapshot[p] = sourceState{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+func isSource(p string) bool {
+	for _, ext := range sourceExtensions {
+		if strings.HasSuffix(p, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// The directories of the source files added, removed or modified between snapshots,
+// sorted, "." for the root directory.
+func changedDirs(previous, current sourceSnapshot) []string {
+	dirs := make(map[string]bool)
+
+	for p, state := range current {
+		if prev, found := previous[p]; !found || prev != state {
+			dirs[path.Dir(p)] = true
+		}
+	}
+	for p := range previous {
+		if _, found := current[p]; !found {
+			dirs[path.Dir(p)] = true
+		}
+	}
+
+	changed := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		changed = append(changed, dir)
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// The nearest existing directory of each directory relative to the root, without
+// duplicates, for directories removed along with their sources.
+func existingDirs(root string, dirs []string) []string {
+	existing := make([]string, 0, len(dirs))
+	seen := make(map[string]bool, len(dirs))
+
+	for _, dir := range dirs {
+		for dir != "." {
+			if info, err := os.Stat(filepath.Join(root, filepath.FromSlash(dir))); err == nil && info.IsDir() {
+				break
+			}
+			dir = path.Dir(dir)
+		}
+
+		if !seen[dir] {
+			seen[dir] = true
+			existing = append(existing, dir)
+		}
+	}
+	return existing
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestScanSources(t *testing.T) {
+	root := t.TempDir()
+
+	for _, f := range []string{
+		"Root.kt",
+		"build.gradle.kts",
+		"app/App.kt",
+		"app/README.md",
+		"lib/Lib.kt",
+		"generated/Gen.kt",
+		"vendored/Vendored.kt",
+	} {
+		writeFile(t, root, f, "package x")
+	}
+	writeFile(t, root, ".gitignore", "generated/\n")
+	writeFile(t, root, ".bazelignore", "vendored\n")
+
+	snapshot, err := scanSources(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual := make([]string, 0, len(snapshot))
+	for p := range snapshot {
+		actual = append(actual, p)
+	}
+	sort.Strings(actual)
+
+	expected := []string{"Root.kt", "app/App.kt", "build.gradle.kts", "lib/Lib.kt"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("scanSources()...\nactual:  %v;\nexpected: %v", actual, expected)
+	}
+}
+
+func TestChangedDirs(t *testing.T) {
+	now := time.Now()
+	previous := sourceSnapshot{
+		"Root.kt":          {modTime: now, size: 10},
+		"app/App.kt":       {modTime: now, size: 10},
+		"lib/Lib.kt":       {modTime: now, size: 10},
+		"lib/sub/Sub.kt":   {modTime: now, size: 10},
+		"removed/Gone.kt":  {modTime: now, size: 10},
+		"resized/Size.kts": {modTime: now, size: 10},
+	}
+	current := sourceSnapshot{
+		"Root.kt":          {modTime: now, size: 10},
+		"app/App.kt":       {modTime: now.Add(time.Second), size: 10},
+		"lib/Lib.kt":       {modTime: now, size: 10},
+		"lib/sub/Sub.kt":   {modTime: now, size: 10},
+		"new/New.kt":       {modTime: now, size: 10},
+		"resized/Size.kts": {modTime: now, size: 12},
+	}
+
+	expected := []string{"app", "new", "removed", "resized"}
+	if actual := changedDirs(previous, current); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("changedDirs()...\nactual:  %v;\nexpected: %v", actual, expected)
+	}
+
+	if actual := changedDirs(previous, previous); len(actual) != 0 {
+		t.Errorf("changedDirs() of the same snapshot...\nactual:  %v;\nexpected: []", actual)
+	}
+}
+
+func TestExistingDirs(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "app/App.kt", "package app")
+	writeFile(t, root, "lib/Lib.kt", "package lib")
+
+	expected := []string{"app", "lib", "."}
+	if actual := existingDirs(root, []string{"app", "lib/removed/deeper", "lib/removed", "gone"}); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("existingDirs()...\nactual:  %v;\nexpected: %v", actual, expected)
+	}
+}
+
+func writeFile(t *testing.T, root, p, content string) {
+	t.Helper()
+
+	path := filepath.Join(root, filepath.FromSlash(p))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
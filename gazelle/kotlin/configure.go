@@ -2,24 +2,89 @@ package gazelle
 
 import (
 	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 
 	common "aspect.build/cli/gazelle/common"
 	"aspect.build/cli/gazelle/common/git"
+	treeutils "aspect.build/cli/gazelle/common/treesitter"
 	"aspect.build/cli/gazelle/kotlin/kotlinconfig"
+	"aspect.build/cli/gazelle/kotlin/maven"
+	"aspect.build/cli/gazelle/kotlin/symbolindex"
 	BazelLog "aspect.build/cli/pkg/logger"
 	jvm_javaconfig "github.com/bazel-contrib/rules_jvm/java/gazelle/javaconfig"
 	jvm_maven "github.com/bazel-contrib/rules_jvm/java/gazelle/private/maven"
 	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
 	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/bmatcuk/doublestar/v4"
 	"github.com/rs/zerolog"
 )
 
 var _ config.Configurer = (*kotlinLang)(nil)
 
+// The kotlin language and API versions, such as "1.9" or "2.0".
+var kotlinVersionRegex = regexp.MustCompile(`^\d+\.\d+$`)
+
 func (kt *kotlinLang) KnownDirectives() []string {
 	return []string{
 		kotlinconfig.Directive_KotlinExtension,
+		kotlinconfig.Directive_JavaSources,
+		kotlinconfig.Directive_CompilerPlugin,
+		kotlinconfig.Directive_AnnotationProcessor,
+		kotlinconfig.Directive_Tags,
+		kotlinconfig.Directive_ServiceProvider,
+		kotlinconfig.Directive_MainClass,
+		kotlinconfig.Directive_ModuleName,
+		kotlinconfig.Directive_Associates,
+		kotlinconfig.Directive_Lint,
+		kotlinconfig.Directive_LintConfig,
+		kotlinconfig.Directive_Cleanup,
+		kotlinconfig.Directive_Data,
+		kotlinconfig.Directive_Resources,
+		kotlinconfig.Directive_ResourceStripPrefix,
+		kotlinconfig.Directive_NameCollision,
+		kotlinconfig.Directive_GenerateTests,
+		kotlinconfig.Directive_GenerateLibraries,
+		kotlinconfig.Directive_GenerateBinaries,
+		kotlinconfig.Directive_TestSources,
+		kotlinconfig.Directive_Testonly,
+		kotlinconfig.Directive_InferTestonly,
+		kotlinconfig.Directive_StrictDeps,
+		kotlinconfig.Directive_ValidateImportStatements,
+		kotlinconfig.Directive_ValidateTestonly,
+		kotlinconfig.Directive_MavenRepository,
+		kotlinconfig.Directive_MavenInstallFile,
+		kotlinconfig.Directive_MavenRepositoryName,
+		kotlinconfig.Directive_MavenExcludeArtifact,
+		kotlinconfig.Directive_MavenResolver,
+		kotlinconfig.Directive_GeneratedImport,
+		kotlinconfig.Directive_ResolveRegexp,
+		kotlinconfig.Directive_NativeImport,
+		kotlinconfig.Directive_ExtraDeps,
+		kotlinconfig.Directive_CompileOnly,
+		kotlinconfig.Directive_PackageFallbackDepth,
+		kotlinconfig.Directive_PreferProvider,
+		kotlinconfig.Directive_FallbackDep,
+		kotlinconfig.Directive_ResolutionTrace,
+		kotlinconfig.Directive_FollowSymlinks,
+		kotlinconfig.Directive_LabelRewrite,
+		kotlinconfig.Directive_SymbolIndex,
+		kotlinconfig.Directive_TestFileSuffixes,
+		kotlinconfig.Directive_TestFrameworkDeps,
+		kotlinconfig.Directive_GenerationMode,
+		kotlinconfig.Directive_ModuleRoot,
+		kotlinconfig.Directive_LanguageVersion,
+		kotlinconfig.Directive_ApiVersion,
+		kotlinconfig.Directive_ModuleRootMarkers,
 		jvm_javaconfig.JavaMavenInstallFile,
+		jvm_javaconfig.JavaMavenRepositoryName,
+		jvm_javaconfig.JavaExcludeArtifact,
 
 		// TODO: move to common
 		git.Directive_GitIgnore,
@@ -47,50 +112,779 @@ func (kt *kotlinLang) Configure(c *config.Config, rel string, f *rule.File) {
 		cfgs[rel] = cfg
 	}
 
-	// Collect the ignore files for this package
+	// Collect the ignore files and exclude directives for this package
 	git.CollectIgnoreFiles(c, rel)
+	common.CollectExcludes(c, rel, f)
 
+	// The directives of the workspace configuration file, environment and flags are applied
+	// beneath the root BUILD file.
+	var directives []rule.Directive
+	if rel == "" {
+		directives = append(directives, kt.workspaceDirectives(c.RepoRoot)...)
+		directives = append(directives, kt.flagDirectives...)
+	}
 	if f != nil {
-		for _, d := range f.Directives {
+		directives = append(directives, f.Directives...)
+	}
+
+	if len(directives) > 0 {
+		// The data patterns of the BUILD file, replacing the inherited patterns if specified.
+		var dataPatterns []string
+
+		// The resource patterns of the BUILD file, replacing the inherited patterns if specified.
+		var resourcePatterns []string
+
+		// The maven repositories of the BUILD file, replacing the inherited repositories if specified.
+		var mavenRepositories []kotlinconfig.MavenRepository
+
+		for _, d := range directives {
 			switch d.Key {
 
 			case kotlinconfig.Directive_KotlinExtension:
 				cfg.SetGenerationEnabled(common.ReadEnabled(d))
 
+			case kotlinconfig.Directive_TestFileSuffixes:
+				suffixes := strings.Fields(d.Value)
+				if len(suffixes) == 0 {
+					suffixes = kotlinconfig.DefaultTestFilePatterns()
+				}
+				for _, suffix := range suffixes {
+					if _, err := path.Match(suffix, ""); err != nil {
+						BazelLog.Fatalf("invalid glob pattern for directive %q: %s", d.Key, suffix)
+					}
+				}
+
+				cfg.SetTestFileSuffixes(suffixes)
+
+			case kotlinconfig.Directive_TestFrameworkDeps:
+				parts := strings.Fields(d.Value)
+				if len(parts) == 0 {
+					cfg.ResetTestFrameworkDeps()
+					break
+				}
+				if len(parts) < 2 || !kotlinconfig.IsTestFramework(parts[0]) {
+					BazelLog.Fatalf("invalid value for directive %q: %s: expected a test framework and labels", d.Key, d.Value)
+				}
+
+				deps := make([]label.Label, 0, len(parts)-1)
+				for _, part := range parts[1:] {
+					dep, err := label.Parse(part)
+					if err != nil {
+						BazelLog.Fatalf("invalid label for directive %q: %s: %v", d.Key, part, err)
+					}
+
+					// Relative labels are relative to the BUILD file of the directive, not the inheriting packages.
+					deps = append(deps, dep.Abs("", rel))
+				}
+
+				cfg.AddTestFrameworkDeps(parts[0], deps)
+
+			case kotlinconfig.Directive_Cleanup:
+				cfg.SetCleanupEnabled(common.ReadEnabled(d))
+
+			case kotlinconfig.Directive_Data:
+				if dataPatterns == nil {
+					dataPatterns = make([]string, 0)
+				}
+
+				if pattern := strings.TrimSpace(d.Value); pattern != "" {
+					if !doublestar.ValidatePattern(pattern) {
+						BazelLog.Fatalf("invalid glob pattern for directive %q: %s", d.Key, pattern)
+					}
+					dataPatterns = append(dataPatterns, pattern)
+				}
+
+			case kotlinconfig.Directive_Resources:
+				if resourcePatterns == nil {
+					resourcePatterns = make([]string, 0)
+				}
+
+				if pattern := strings.TrimSpace(d.Value); pattern != "" {
+					if !doublestar.ValidatePattern(pattern) {
+						BazelLog.Fatalf("invalid glob pattern for directive %q: %s", d.Key, pattern)
+					}
+					resourcePatterns = append(resourcePatterns, pattern)
+				}
+
+			case kotlinconfig.Directive_ResourceStripPrefix:
+				prefix := path.Clean(strings.TrimSpace(d.Value))
+				if prefix == "." {
+					prefix = ""
+				}
+				if path.IsAbs(prefix) || prefix == ".." || strings.HasPrefix(prefix, "../") {
+					BazelLog.Fatalf("invalid value for directive %q: %s: expected a path relative to the BUILD file", d.Key, d.Value)
+				}
+				cfg.SetResourceStripPrefix(prefix)
+
+			case kotlinconfig.Directive_MavenRepository:
+				if mavenRepositories == nil {
+					mavenRepositories = make([]kotlinconfig.MavenRepository, 0)
+				}
+
+				parts := strings.Fields(d.Value)
+				if len(parts) == 0 {
+					break
+				}
+				if len(parts) != 2 {
+					BazelLog.Fatalf("invalid value for directive %q: %s: expected a repository name and maven_install.json path", d.Key, d.Value)
+				}
+
+				mavenRepositories = append(mavenRepositories, kotlinconfig.MavenRepository{
+					Name:        parts[0],
+					InstallFile: filepath.Join(c.RepoRoot, parts[1]),
+				})
+
+			case kotlinconfig.Directive_MavenInstallFile:
+				installFile := strings.TrimSpace(d.Value)
+				if installFile != "" {
+					installFile = filepath.Join(c.RepoRoot, installFile)
+				}
+				cfg.SetKotlinMavenInstallFile(installFile)
+
+			case kotlinconfig.Directive_MavenRepositoryName:
+				name := strings.TrimSpace(d.Value)
+				if strings.ContainsAny(name, "@/: ") {
+					BazelLog.Fatalf("invalid value for directive %q: %s: expected a repository name such as \"maven\"", d.Key, d.Value)
+				}
+				cfg.SetKotlinMavenRepositoryName(name)
+
+			case kotlinconfig.Directive_MavenExcludeArtifact:
+				coordinate := strings.TrimSpace(d.Value)
+				if coordinate == "" {
+					cfg.ResetMavenExcludedArtifacts()
+					break
+				}
+
+				parts := strings.Split(coordinate, ":")
+				if len(parts) != 2 || parts[0] == "" || parts[1] == "" || strings.ContainsAny(coordinate, " \t") {
+					BazelLog.Fatalf("invalid value for directive %q: %s: expected a maven artifact such as \"com.google.guava:guava\"", d.Key, d.Value)
+				}
+				cfg.AddMavenExcludedArtifact(coordinate)
+
+			case kotlinconfig.Directive_MavenResolver:
+				switch strings.TrimSpace(d.Value) {
+				case "rules_jvm":
+					cfg.SetMavenResolver(kotlinconfig.MavenResolverRulesJvm)
+				case "builtin":
+					cfg.SetMavenResolver(kotlinconfig.MavenResolverBuiltin)
+				default:
+					BazelLog.Fatalf("invalid value for directive %q: %s", d.Key, d.Value)
+				}
+
+			case kotlinconfig.Directive_GenerateTests:
+				cfg.SetGenerateTests(common.ReadEnabled(d))
+
+			case kotlinconfig.Directive_GenerateLibraries:
+				cfg.SetGenerateLibraries(common.ReadEnabled(d))
+
+			case kotlinconfig.Directive_GenerateBinaries:
+				cfg.SetGenerateBinaries(common.ReadEnabled(d))
+
+			case kotlinconfig.Directive_TestSources:
+				cfg.SetTestSources(readTestSources(d))
+
+			case kotlinconfig.Directive_Testonly:
+				testonly, err := strconv.ParseBool(strings.TrimSpace(d.Value))
+				if err != nil {
+					BazelLog.Fatalf("invalid value for directive %q: %s: expected true or false", d.Key, d.Value)
+				}
+				cfg.SetTestonly(testonly)
+
+			case kotlinconfig.Directive_InferTestonly:
+				cfg.SetInferTestonly(common.ReadEnabled(d))
+
+			case kotlinconfig.Directive_StrictDeps:
+				cfg.SetStrictDeps(common.ReadEnabled(d))
+
+			case kotlinconfig.Directive_ResolutionTrace:
+				cfg.SetResolutionTrace(common.ReadEnabled(d))
+
+			case kotlinconfig.Directive_FollowSymlinks:
+				cfg.SetFollowSymlinks(common.ReadEnabled(d))
+
+			case kotlinconfig.Directive_ValidateImportStatements:
+				switch strings.TrimSpace(d.Value) {
+				case "error":
+					cfg.SetValidateImportStatements(kotlinconfig.ValidationError)
+				case "warn":
+					cfg.SetValidateImportStatements(kotlinconfig.ValidationWarn)
+				case "off":
+					cfg.SetValidateImportStatements(kotlinconfig.ValidationOff)
+				default:
+					BazelLog.Fatalf("invalid value for directive %q: %s", d.Key, d.Value)
+				}
+
+			case kotlinconfig.Directive_ValidateTestonly:
+				switch strings.TrimSpace(d.Value) {
+				case "error":
+					cfg.SetValidateTestonly(kotlinconfig.ValidationError)
+				case "warn":
+					cfg.SetValidateTestonly(kotlinconfig.ValidationWarn)
+				case "off":
+					cfg.SetValidateTestonly(kotlinconfig.ValidationOff)
+				default:
+					BazelLog.Fatalf("invalid value for directive %q: %s", d.Key, d.Value)
+				}
+
+			case kotlinconfig.Directive_NameCollision:
+				switch strings.TrimSpace(d.Value) {
+				case "error":
+					cfg.SetNameCollision(kotlinconfig.NameCollisionError)
+				case "rename":
+					cfg.SetNameCollision(kotlinconfig.NameCollisionRename)
+				default:
+					BazelLog.Fatalf("invalid value for directive %q: %s", d.Key, d.Value)
+				}
+
+			case kotlinconfig.Directive_GenerationMode:
+				switch strings.TrimSpace(d.Value) {
+				case "directory":
+					cfg.SetGenerationMode(kotlinconfig.GenerationDirectory)
+				case "package":
+					cfg.SetGenerationMode(kotlinconfig.GenerationPackage)
+				case "module":
+					cfg.SetGenerationMode(kotlinconfig.GenerationModule)
+				case "file":
+					cfg.SetGenerationMode(kotlinconfig.GenerationFile)
+				default:
+					BazelLog.Fatalf("invalid value for directive %q: %s", d.Key, d.Value)
+				}
+
+			case kotlinconfig.Directive_LanguageVersion, kotlinconfig.Directive_ApiVersion:
+				version := strings.TrimSpace(d.Value)
+				if version != "" && !kotlinVersionRegex.MatchString(version) {
+					BazelLog.Fatalf("invalid value for directive %q: %s: expected a kotlin version such as \"1.9\"", d.Key, d.Value)
+				}
+
+				if d.Key == kotlinconfig.Directive_LanguageVersion {
+					cfg.SetLanguageVersion(version)
+				} else {
+					cfg.SetApiVersion(version)
+				}
+
+			case kotlinconfig.Directive_ModuleRoot:
+				cfg.SetModuleRoot()
+
+			case kotlinconfig.Directive_ModuleRootMarkers:
+				markers := strings.Fields(d.Value)
+				for _, marker := range markers {
+					if strings.Contains(marker, "/") {
+						BazelLog.Fatalf("invalid value for directive %q: %s: expected file names", d.Key, d.Value)
+					}
+				}
+				cfg.SetModuleRootMarkers(markers)
+
+			case kotlinconfig.Directive_JavaSources:
+				cfg.SetJavaSourcesEnabled(common.ReadEnabled(d))
+
+			case kotlinconfig.Directive_CompilerPlugin:
+				parts := strings.Fields(d.Value)
+				if len(parts) < 2 {
+					BazelLog.Fatalf("invalid value for directive %q: %s: expected a plugin id, label and optional annotations", d.Key, d.Value)
+				}
+
+				pluginLabel, err := label.Parse(parts[1])
+				if err != nil {
+					BazelLog.Fatalf("invalid label for directive %q: %s: %v", d.Key, parts[1], err)
+				}
+
+				// Relative labels are relative to the BUILD file of the directive, not the inheriting packages.
+				pluginLabel = pluginLabel.Abs("", rel)
+
+				if err := cfg.SetCompilerPlugin(parts[0], pluginLabel, parts[2:]); err != nil {
+					BazelLog.Fatalf("invalid value for directive %q: %v", d.Key, err)
+				}
+
+			case kotlinconfig.Directive_Tags:
+				tags := make([]string, 0)
+				for _, tag := range strings.Split(d.Value, ",") {
+					if tag = strings.TrimSpace(tag); tag != "" {
+						tags = append(tags, tag)
+					}
+				}
+				cfg.SetTags(tags)
+
+			case kotlinconfig.Directive_ServiceProvider:
+				parts := strings.Fields(d.Value)
+				if len(parts) != 2 {
+					BazelLog.Fatalf("invalid value for directive %q: %s: expected a service and label", d.Key, d.Value)
+				}
+
+				providerLabel, err := label.Parse(parts[1])
+				if err != nil {
+					BazelLog.Fatalf("invalid label for directive %q: %s: %v", d.Key, parts[1], err)
+				}
+
+				// Relative labels are relative to the BUILD file of the directive, not the inheriting packages.
+				providerLabel = providerLabel.Abs("", rel)
+
+				cfg.AddServiceProvider(parts[0], providerLabel)
+
+			case kotlinconfig.Directive_MainClass:
+				parts := strings.Fields(d.Value)
+				if len(parts) != 2 || path.IsAbs(parts[0]) {
+					BazelLog.Fatalf("invalid value for directive %q: %s: expected a relative source file and main class", d.Key, d.Value)
+				}
+
+				// Files are relative to the BUILD file of the directive, not the inheriting packages.
+				cfg.SetMainClass(path.Join(rel, parts[0]), parts[1])
+
+			case kotlinconfig.Directive_GeneratedImport:
+				parts := strings.Fields(d.Value)
+				if len(parts) != 2 {
+					BazelLog.Fatalf("invalid value for directive %q: %s: expected a pattern and label or \"self\"", d.Key, d.Value)
+				}
+
+				if _, err := path.Match(parts[0], ""); err != nil {
+					BazelLog.Fatalf("invalid pattern for directive %q: %s: %v", d.Key, parts[0], err)
+				}
+
+				generated := kotlinconfig.GeneratedImport{Pattern: parts[0]}
+				if parts[1] != "self" {
+					generatorLabel, err := label.Parse(parts[1])
+					if err != nil {
+						BazelLog.Fatalf("invalid label for directive %q: %s: %v", d.Key, parts[1], err)
+					}
+
+					// Relative labels are relative to the BUILD file of the directive, not the inheriting packages.
+					generatorLabel = generatorLabel.Abs("", rel)
+					generated.Label = &generatorLabel
+				}
+
+				cfg.AddGeneratedImport(generated)
+
+			case kotlinconfig.Directive_ResolveRegexp:
+				parts := strings.Fields(d.Value)
+				if len(parts) != 2 {
+					BazelLog.Fatalf("invalid value for directive %q: %s: expected a regular expression and label", d.Key, d.Value)
+				}
+
+				re, err := regexp.Compile("^(?:" + parts[0] + ")$")
+				if err != nil {
+					BazelLog.Fatalf("invalid regular expression for directive %q: %s: %v", d.Key, parts[0], err)
+				}
+
+				resolveLabel, err := label.Parse(parts[1])
+				if err != nil {
+					BazelLog.Fatalf("invalid label for directive %q: %s: %v", d.Key, parts[1], err)
+				}
+
+				// Relative labels are relative to the BUILD file of the directive, not the inheriting packages.
+				cfg.AddResolveRegexp(kotlinconfig.ResolveRegexp{
+					Regexp: re,
+					Label:  resolveLabel.Abs("", rel),
+				})
+
+			case kotlinconfig.Directive_NativeImport:
+				prefix := strings.TrimSpace(d.Value)
+				native := !strings.HasPrefix(prefix, "!")
+				prefix = strings.TrimSuffix(strings.TrimPrefix(prefix, "!"), ".*")
+				if prefix == "" || strings.ContainsAny(prefix, " \t*") {
+					BazelLog.Fatalf("invalid value for directive %q: %s: expected a package prefix", d.Key, d.Value)
+				}
+
+				cfg.SetNativeImport(prefix, native)
+
+			case kotlinconfig.Directive_AnnotationProcessor:
+				parts := strings.Fields(d.Value)
+				if len(parts) == 0 {
+					cfg.ResetAnnotationProcessors()
+					break
+				}
+				if len(parts) < 2 {
+					BazelLog.Fatalf("invalid value for directive %q: %s: expected an annotation, a plugin label and optional dependency labels", d.Key, d.Value)
+				}
+
+				labels := make([]label.Label, 0, len(parts)-1)
+				for _, part := range parts[1:] {
+					l, err := label.Parse(part)
+					if err != nil {
+						BazelLog.Fatalf("invalid label for directive %q: %s: %v", d.Key, part, err)
+					}
+
+					// Relative labels are relative to the BUILD file of the directive, not the inheriting packages.
+					labels = append(labels, l.Abs("", rel))
+				}
+
+				cfg.AddAnnotationProcessor(kotlinconfig.AnnotationProcessor{
+					Annotation: parts[0],
+					Plugin:     labels[0],
+					Deps:       labels[1:],
+				})
+
+			case kotlinconfig.Directive_ExtraDeps:
+				parts := strings.Fields(d.Value)
+				if len(parts) == 0 {
+					cfg.ResetExtraDeps()
+					break
+				}
+
+				kind := ""
+				if kotlinKinds[parts[0]].ResolveAttrs["deps"] {
+					kind, parts = parts[0], parts[1:]
+				}
+				if len(parts) == 0 {
+					BazelLog.Fatalf("invalid value for directive %q: %s: expected an optional kind and labels", d.Key, d.Value)
+				}
+
+				deps := make([]label.Label, 0, len(parts))
+				for _, part := range parts {
+					dep, err := label.Parse(part)
+					if err != nil {
+						BazelLog.Fatalf("invalid label for directive %q: %s: %v", d.Key, part, err)
+					}
+
+					// Relative labels are relative to the BUILD file of the directive, not the inheriting packages.
+					deps = append(deps, dep.Abs("", rel))
+				}
+
+				cfg.AddExtraDeps(kind, deps)
+
+			case kotlinconfig.Directive_CompileOnly:
+				parts := strings.Fields(d.Value)
+				if len(parts) != 1 && len(parts) != 2 {
+					BazelLog.Fatalf("invalid value for directive %q: %s: expected a pattern and optional label", d.Key, d.Value)
+				}
+
+				compileOnly := kotlinconfig.CompileOnlyImport{Pattern: parts[0]}
+				if len(parts) == 2 {
+					wrapperLabel, err := label.Parse(parts[1])
+					if err != nil {
+						BazelLog.Fatalf("invalid label for directive %q: %s: %v", d.Key, parts[1], err)
+					}
+
+					// Relative labels are relative to the BUILD file of the directive, not the inheriting packages.
+					wrapperLabel = wrapperLabel.Abs("", rel)
+					compileOnly.Label = &wrapperLabel
+				}
+
+				cfg.AddCompileOnlyImport(compileOnly)
+
+			case kotlinconfig.Directive_PackageFallbackDepth:
+				depth, err := strconv.Atoi(strings.TrimSpace(d.Value))
+				if err != nil || depth < 0 {
+					BazelLog.Fatalf("invalid value for directive %q: %s: expected a non-negative number", d.Key, d.Value)
+				}
+
+				cfg.SetPackageFallbackDepth(depth)
+
+			case kotlinconfig.Directive_PreferProvider:
+				parts := strings.Fields(d.Value)
+				if len(parts) == 0 {
+					cfg.ResetPreferredProviders()
+					break
+				}
+
+				providers := make([]label.Label, 0, len(parts))
+				for _, part := range parts {
+					provider, err := label.Parse(part)
+					if err != nil {
+						BazelLog.Fatalf("invalid label for directive %q: %s: %v", d.Key, part, err)
+					}
+
+					// Relative labels are relative to the BUILD file of the directive, not the inheriting packages.
+					providers = append(providers, provider.Abs("", rel))
+				}
+
+				cfg.AddPreferredProviders(providers)
+
+			case kotlinconfig.Directive_LabelRewrite:
+				parts := strings.Fields(d.Value)
+				if len(parts) == 0 {
+					cfg.ResetLabelRewrites()
+					break
+				}
+				if len(parts) != 2 {
+					BazelLog.Fatalf("invalid value for directive %q: %s: expected a label prefix and replacement", d.Key, d.Value)
+				}
+
+				cfg.AddLabelRewrite(kotlinconfig.LabelRewrite{Prefix: parts[0], Replacement: parts[1]})
+
+			case kotlinconfig.Directive_SymbolIndex:
+				indexFile := strings.TrimSpace(d.Value)
+				if indexFile == "" {
+					cfg.ResetSymbolIndexes()
+					break
+				}
+
+				indexFile = filepath.Join(c.RepoRoot, indexFile)
+				kt.initSymbolIndex(indexFile)
+				cfg.AddSymbolIndex(indexFile)
+
+			case kotlinconfig.Directive_ModuleName:
+				cfg.SetModuleName(strings.TrimSpace(d.Value))
+
+			case kotlinconfig.Directive_Associates:
+				cfg.SetAssociatesEnabled(common.ReadEnabled(d))
+
+			case kotlinconfig.Directive_Lint:
+				cfg.SetLintEnabled(common.ReadEnabled(d))
+
+			case kotlinconfig.Directive_FallbackDep:
+				value := strings.TrimSpace(d.Value)
+				if value == "" {
+					cfg.SetFallbackDep(nil)
+					break
+				}
+
+				fallback, err := label.Parse(value)
+				if err != nil {
+					BazelLog.Fatalf("invalid label for directive %q: %s: %v", d.Key, value, err)
+				}
+
+				// Relative labels are relative to the BUILD file of the directive, not the inheriting packages.
+				fallback = fallback.Abs("", rel)
+				cfg.SetFallbackDep(&fallback)
+
+			case kotlinconfig.Directive_LintConfig:
+				value := strings.TrimSpace(d.Value)
+				if value == "" {
+					cfg.SetLintConfig(nil)
+					break
+				}
+
+				configLabel, err := label.Parse(value)
+				if err != nil {
+					BazelLog.Fatalf("invalid label for directive %q: %s: %v", d.Key, value, err)
+				}
+
+				// Relative labels are relative to the BUILD file of the directive, not the inheriting packages.
+				configLabel = configLabel.Abs("", rel)
+				cfg.SetLintConfig(&configLabel)
+
+			// The java directives configuring the maven resolution are applied to the wrapped
+			// javaconfig, shared with the java extension.
 			// TODO: invoke java gazelle.Configure() to support all jvm directives?
-			// TODO: JavaMavenRepositoryName: https://github.com/bazel-contrib/rules_jvm/commit/e46bb11bedb2ead45309eae04619caca684f6243
 
 			case jvm_javaconfig.JavaMavenInstallFile:
 				cfg.SetMavenInstallFile(d.Value)
 
+			case jvm_javaconfig.JavaMavenRepositoryName:
+				cfg.SetMavenRepositoryName(d.Value)
+
+			case jvm_javaconfig.JavaExcludeArtifact:
+				if err := cfg.AddExcludedArtifact(d.Value); err != nil {
+					BazelLog.Fatalf("invalid value for directive %q: %s: %v", d.Key, d.Value, err)
+				}
+
 			// TODO: move to common
 			case git.Directive_GitIgnore:
 				git.EnableGitignore(c, common.ReadEnabled(d))
 			}
 		}
+
+		if dataPatterns != nil {
+			cfg.SetDataPatterns(dataPatterns)
+		}
+
+		if resourcePatterns != nil {
+			cfg.SetResourcePatterns(resourcePatterns)
+		}
+
+		if mavenRepositories != nil {
+			cfg.SetMavenRepositories(mavenRepositories)
+		}
 	}
 
-	if kt.mavenResolver == nil {
-		BazelLog.Tracef("Creating Maven resolver: %s", cfg.MavenInstallFile())
+	// Directories containing a marker file such as build.gradle.kts are module roots.
+	if cfg.ModuleRoot() != rel && isModuleRoot(c.RepoRoot, rel, cfg.ModuleRootMarkers()) {
+		cfg.SetModuleRoot()
+	}
 
-		// TODO: better zerolog configuration
-		logger := zerolog.New(BazelLog.GetOutput()).Level(zerolog.TraceLevel)
+	// One Maven resolver per maven_install.json, shared by all packages using it
+	for _, repository := range cfg.MavenRepositories() {
+		kt.initMavenResolver(cfg.MavenResolver(), repository.InstallFile)
+	}
 
-		resolver, err := jvm_maven.NewResolver(
-			cfg.MavenInstallFile(),
-			logger,
-		)
-		if err != nil {
-			BazelLog.Fatalf("error creating Maven resolver: %s", err.Error())
+	if kt.printConfig {
+		printConfig(rel, cfg)
+	}
+}
+
+// Load a symbol index file once, shared by all packages using it.
+func (kt *kotlinLang) initSymbolIndex(indexFile string) {
+	if _, exists := kt.symbolIndexes[indexFile]; exists {
+		return
+	}
+
+	BazelLog.Tracef("Loading symbol index: %s", indexFile)
+
+	idx, err := symbolindex.Load(indexFile)
+	if err != nil {
+		BazelLog.Fatalf("error loading symbol index: %v", err)
+	}
+
+	if kt.symbolIndexes == nil {
+		kt.symbolIndexes = make(map[string]*symbolindex.Index)
+	}
+	kt.symbolIndexes[indexFile] = idx
+}
+
+// Whether the directory contains any of the files marking module roots.
+func isModuleRoot(repoRoot, rel string, markers []string) bool {
+	for _, marker := range markers {
+		if _, err := os.Stat(filepath.Join(repoRoot, rel, marker)); err == nil {
+			return true
 		}
-		kt.mavenResolver = &resolver
 	}
+	return false
+}
+
+// Print the effective kotlin directives of the directory, including inherited values.
+func printConfig(rel string, cfg *kotlinconfig.KotlinConfig) {
+	var s strings.Builder
+	fmt.Fprintf(&s, "Kotlin configuration of //%s:\n", rel)
+	for _, line := range cfg.Dump() {
+		fmt.Fprintf(&s, "\t%s\n", line)
+	}
+	fmt.Print(s.String())
+}
+
+// The kind of the builtin Maven resolvers shared across languages.
+const builtinMavenResolverKind = "kotlin_builtin_maven"
+
+// The key of the Maven resolvers of each maven_install.json file.
+type mavenResolverKey struct {
+	mode        kotlinconfig.MavenResolverMode
+	installFile string
+}
+
+func (kt *kotlinLang) initMavenResolver(mode kotlinconfig.MavenResolverMode, installFile string) {
+	key := mavenResolverKey{mode: mode, installFile: installFile}
+	if _, exists := kt.mavenResolvers[key]; exists {
+		return
+	}
+
+	BazelLog.Tracef("Creating Maven resolver: %s", installFile)
+
+	var resolver interface{}
+	var err error
+	if mode == kotlinconfig.MavenResolverBuiltin {
+		resolver, err = common.GetSharedResolver(builtinMavenResolverKind, installFile, func() (interface{}, error) {
+			return maven.NewResolver(installFile)
+		})
+	} else {
+		resolver, err = common.GetSharedResolver(common.RulesJvmMavenResolverKind, installFile, func() (interface{}, error) {
+			// TODO: better zerolog configuration
+			logger := zerolog.New(BazelLog.GetOutput()).Level(zerolog.TraceLevel)
+
+			return jvm_maven.NewResolver(
+				installFile,
+				logger,
+			)
+		})
+	}
+	if err != nil {
+		BazelLog.Fatalf("error creating Maven resolver: %s", err.Error())
+	}
+
+	if kt.mavenResolvers == nil {
+		kt.mavenResolvers = make(map[mavenResolverKey]maven.Resolver)
+	}
+	kt.mavenResolvers[key] = resolver.(maven.Resolver)
 }
 
 func (kc *kotlinLang) RegisterFlags(fs *flag.FlagSet, cmd string, c *config.Config) {
 	// TODO: support rules_jvm flags such as 'java-maven-install-file'? (see rules_jvm java/gazelle/configure.go)
+
+	fs.StringVar(&kc.changeReportFile, "kotlin-change-report", "", "Path of a JSON file to write the list of kotlin rule changes to. Combine with -mode=diff to preview changes without writing BUILD files.")
+	fs.StringVar(&kc.unresolvedReportFile, "kotlin-unresolved-report", "", "Path of a JSON file to write the list of kotlin imports that could not be resolved to.")
+	fs.StringVar(&kc.configFile, "kotlin-config", "", "Path of a YAML or JSON file of kotlin directives applied beneath the directives of the root BUILD file, by default "+strings.Join(workspaceConfigFiles, ", ")+" if present.")
+	fs.Var(&kc.directiveFlags, "kotlin-directive", fmt.Sprintf("A kotlin directive such as \"kotlin_generate_tests enabled\" applied to all packages beneath the directives of the root BUILD file. May be repeated, applied after the directives of the %s environment variable.", DirectivesEnv))
+	fs.BoolVar(&kc.printConfig, "kotlin-print-config", false, "Print the effective kotlin directives of each visited directory, including the values inherited from parent directories.")
+	fs.IntVar(&kc.parallelism, "kotlin-parallelism", 0, fmt.Sprintf("Maximum number of kotlin files parsed in parallel, by default the %s environment variable or %d.", ParallelismEnv, MaxWorkerCount))
+	fs.IntVar(&kc.maxFileSize, "kotlin-max-file-size", 0, "Maximum size in bytes of the kotlin files parsed, larger files are skipped. Unlimited by default.")
+	fs.DurationVar(&kc.parseTimeout, "kotlin-parse-timeout", 0, "Maximum duration of parsing a kotlin file such as \"10s\", files taking longer are skipped. Unlimited by default.")
+	fs.StringVar(&kc.depGraphFile, "kotlin-depgraph", "", "Path of a file to write the graph of the kotlin rules and the imports resolved to their dependencies to. Combine with -mode=diff to export the graph without writing BUILD files.")
+	fs.StringVar(&kc.depGraphFormat, "kotlin-depgraph-format", "", fmt.Sprintf("The format of the -kotlin-depgraph file, %q or %q. By default %q for files with a .dot or .gv extension, otherwise %q.", DepGraphFormat_JSON, DepGraphFormat_DOT, DepGraphFormat_DOT, DepGraphFormat_JSON))
+	fs.StringVar(&kc.resolutionCacheFile, "kotlin-resolution-cache", "", "Path of a file persisting the maven resolutions of kotlin imports between runs. Invalidated when the maven_install.json files or the configuration change.")
 }
 
 func (kc *kotlinLang) CheckFlags(fs *flag.FlagSet, c *config.Config) error {
+	kc.partialRun = isPartialRun(fs, c)
+
+	parallelism, err := readParallelism(kc.parallelism, os.Getenv(ParallelismEnv))
+	if err != nil {
+		return err
+	}
+	kc.parallelism = parallelism
+
+	if kc.maxFileSize < 0 {
+		return fmt.Errorf("invalid value for flag -kotlin-max-file-size: %d: expected a positive number", kc.maxFileSize)
+	}
+	if kc.parseTimeout < 0 {
+		return fmt.Errorf("invalid value for flag -kotlin-parse-timeout: %s: expected a positive duration", kc.parseTimeout)
+	}
+	treeutils.SetParseLimits(treeutils.ParseLimits{MaxFileSize: kc.maxFileSize, Timeout: kc.parseTimeout})
+
+	directives, err := readFlagDirectives(os.Getenv(DirectivesEnv), kc.directiveFlags, kc.KnownDirectives())
+	if err != nil {
+		return err
+	}
+	kc.flagDirectives = directives
+
+	if kc.depGraphFile != "" {
+		format, err := depGraphFormat(kc.depGraphFile, kc.depGraphFormat)
+		if err != nil {
+			return err
+		}
+		kc.depGraphFormat = format
+	}
+
+	if kc.resolutionCacheFile != "" {
+		kc.resolutionCache = loadResolutionCache(kc.resolutionCacheFile)
+	}
 	return nil
 }
+
+// Validate the parallelism of the -kotlin-parallelism flag, or of the environment
+// variable if the flag is not set. Returns 0 if neither is set.
+func readParallelism(flagValue int, envValue string) (int, error) {
+	if flagValue < 0 {
+		return 0, fmt.Errorf("invalid value for flag -kotlin-parallelism: %d: expected a positive number", flagValue)
+	}
+	if flagValue > 0 {
+		return flagValue, nil
+	}
+
+	envValue = strings.TrimSpace(envValue)
+	if envValue == "" {
+		return 0, nil
+	}
+
+	parallelism, err := strconv.Atoi(envValue)
+	if err != nil || parallelism <= 0 {
+		return 0, fmt.Errorf("invalid value for environment variable %s: %q: expected a positive number", ParallelismEnv, envValue)
+	}
+	return parallelism, nil
+}
+
+// Whether gazelle only visits some of the packages of the repository, such as
+// `gazelle some/dir` or `gazelle -r=false`, in which case the reverse
+// dependencies of libraries are unknown.
+func isPartialRun(fs *flag.FlagSet, c *config.Config) bool {
+	if recursive := fs.Lookup("r"); recursive != nil && recursive.Value.String() == "false" {
+		return true
+	}
+
+	for _, dir := range fs.Args() {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(c.WorkDir, dir)
+		}
+
+		if rel, err := filepath.Rel(c.RepoRoot, dir); err != nil || rel != "." {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Reads the kotlin_test_srcs directive, accepting true/false as well as the
+// enabled/disabled values of the other directives.
+func readTestSources(d rule.Directive) bool {
+	if testSources, err := strconv.ParseBool(strings.TrimSpace(d.Value)); err == nil {
+		return testSources
+	}
+	return common.ReadEnabled(d)
+}
@@ -0,0 +1,40 @@
+package gazelle
+
+import (
+	"testing"
+)
+
+func TestReadParallelism(t *testing.T) {
+	for _, tc := range []struct {
+		flag     int
+		env      string
+		expected int
+	}{
+		{flag: 0, env: "", expected: 0},
+		{flag: 4, env: "", expected: 4},
+		{flag: 4, env: "32", expected: 4},
+		{flag: 0, env: "32", expected: 32},
+		{flag: 0, env: " 2 ", expected: 2},
+	} {
+		actual, err := readParallelism(tc.flag, tc.env)
+		if err != nil {
+			t.Errorf("readParallelism(%d, %q) failed: %v", tc.flag, tc.env, err)
+		} else if actual != tc.expected {
+			t.Errorf("readParallelism(%d, %q)...\nactual:  %d;\nexpected: %d", tc.flag, tc.env, actual, tc.expected)
+		}
+	}
+
+	for _, tc := range []struct {
+		flag int
+		env  string
+	}{
+		{flag: -1, env: ""},
+		{flag: 0, env: "0"},
+		{flag: 0, env: "-2"},
+		{flag: 0, env: "many"},
+	} {
+		if _, err := readParallelism(tc.flag, tc.env); err == nil {
+			t.Errorf("readParallelism(%d, %q) expected an error", tc.flag, tc.env)
+		}
+	}
+}
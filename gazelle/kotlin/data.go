@@ -0,0 +1,50 @@
+package gazelle
+
+import (
+	gazelle "aspect.build/cli/gazelle/common"
+	"aspect.build/cli/gazelle/kotlin/kotlinconfig"
+	BazelLog "aspect.build/cli/pkg/logger"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/emirpasic/gods/sets/treeset"
+)
+
+// Collect the files within the package matching the configured data patterns,
+// including files in sub-directories which are not Bazel packages. Ignored files
+// are never included.
+func collectDataFiles(cfg *kotlinconfig.KotlinConfig, args language.GenerateArgs) []string {
+	return collectPackageFiles(cfg.DataPatterns(), "data", args)
+}
+
+// Collect the files within the package matching the configured resource patterns,
+// including files in sub-directories which are not Bazel packages.
+func collectResourceFiles(cfg *kotlinconfig.KotlinConfig, args language.GenerateArgs) []string {
+	return collectPackageFiles(cfg.ResourcePatterns(), "resource", args)
+}
+
+// Collect the files within the package matching any of the glob patterns.
+func collectPackageFiles(patterns []string, kind string, args language.GenerateArgs) []string {
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	files := treeset.NewWithStringComparator()
+
+	err := gazelle.GazelleWalkPackageFiles(args, func(f string) error {
+		for _, pattern := range patterns {
+			if matched, _ := doublestar.Match(pattern, f); matched {
+				BazelLog.Tracef("%s file: %s", kind, f)
+
+				files.Add(f)
+				break
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		BazelLog.Infof("failed to collect %s files in %q: %v", kind, args.Rel, err)
+	}
+
+	return toStrings(files)
+}
@@ -0,0 +1,207 @@
+package gazelle
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	common "aspect.build/cli/gazelle/common"
+	BazelLog "aspect.build/cli/pkg/logger"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+// The formats of the dependency graph written by the -kotlin-depgraph flag.
+const (
+	DepGraphFormat_JSON = "json"
+	DepGraphFormat_DOT  = "dot"
+)
+
+// DepGraphTarget is a kotlin rule of the dependency graph and the dependencies
+// resolved for it.
+type DepGraphTarget struct {
+	Label string        `json:"label"`
+	Kind  string        `json:"kind"`
+	Deps  []DepGraphDep `json:"deps"`
+}
+
+// DepGraphDep is a dependency of a rule of the dependency graph.
+type DepGraphDep struct {
+	Label string `json:"label"`
+
+	// The imports resolved to the dependency, none if not added for an import
+	// such as a kotlin_extra_dep or a dependency preserved from the existing rule.
+	Imports []string `json:"imports,omitempty"`
+
+	// Whether the dependency is in `compile_only_deps` instead of `deps`.
+	CompileOnly bool `json:"compile_only,omitempty"`
+
+	// Whether the dependency is an existing dependency of a binary or test only
+	// preserved, neither added for an import nor by the configuration.
+	Preserved bool `json:"preserved,omitempty"`
+}
+
+// The format of the dependency graph of a -kotlin-depgraph-format flag, by default
+// selected by the extension of the file.
+func depGraphFormat(file, format string) (string, error) {
+	switch format {
+	case DepGraphFormat_JSON, DepGraphFormat_DOT:
+		return format, nil
+	case "":
+		if strings.HasSuffix(file, ".dot") || strings.HasSuffix(file, ".gv") {
+			return DepGraphFormat_DOT, nil
+		}
+		return DepGraphFormat_JSON, nil
+	}
+	return "", fmt.Errorf("invalid value for flag -kotlin-depgraph-format: %q: expected %q or %q", format, DepGraphFormat_JSON, DepGraphFormat_DOT)
+}
+
+// Record an import of a rule resolved to a dependency for the dependency graph.
+func (kt *kotlinLang) recordDepGraphImport(mod ImportStatement, from, dep label.Label) {
+	if kt.depGraphFile == "" {
+		return
+	}
+
+	imp := mod.Imp
+	if mod.IsStar {
+		imp += ".*"
+	} else if mod.Symbol != "" {
+		imp = mod.Symbol
+	}
+
+	if kt.depGraphImports == nil {
+		kt.depGraphImports = make(map[label.Label]map[label.Label][]string)
+	}
+	if kt.depGraphImports[from] == nil {
+		kt.depGraphImports[from] = make(map[label.Label][]string)
+	}
+
+	dep = dep.Abs(from.Repo, from.Pkg)
+	kt.depGraphImports[from][dep] = append(kt.depGraphImports[from][dep], imp)
+}
+
+// Record a resolved rule and its dependencies for the dependency graph, along with
+// the imports recorded for each dependency and whether existing dependencies are
+// only preserved.
+func (kt *kotlinLang) recordDepGraphTarget(r *rule.Rule, from label.Label, deps, compileOnlyDeps, preservedDeps *common.LabelSet) {
+	if kt.depGraphFile == "" {
+		return
+	}
+
+	target := DepGraphTarget{
+		Label: label.New("", from.Pkg, from.Name).String(),
+		Kind:  r.Kind(),
+		Deps:  make([]DepGraphDep, 0),
+	}
+
+	preserved := make(map[label.Label]bool)
+	for _, l := range preservedDeps.Labels() {
+		preserved[l.Abs(from.Repo, from.Pkg)] = true
+	}
+
+	imports := kt.depGraphImports[from]
+	addDeps := func(labels []label.Label, compileOnly bool) {
+		for _, l := range labels {
+			l = l.Abs(from.Repo, from.Pkg)
+			target.Deps = append(target.Deps, DepGraphDep{
+				Label:       l.String(),
+				Imports:     sortedUnique(imports[l]),
+				CompileOnly: compileOnly,
+				Preserved:   preserved[l] && len(imports[l]) == 0,
+			})
+		}
+	}
+	addDeps(deps.Labels(), false)
+	addDeps(compileOnlyDeps.Labels(), true)
+
+	kt.depGraph = append(kt.depGraph, target)
+	delete(kt.depGraphImports, from)
+}
+
+// Write the dependency graph to the -kotlin-depgraph file, if enabled.
+func (kt *kotlinLang) writeDepGraph() {
+	if kt.depGraphFile == "" {
+		return
+	}
+
+	f, err := os.Create(kt.depGraphFile)
+	if err == nil {
+		err = writeDepGraph(f, kt.depGraphFormat, kt.depGraph)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		BazelLog.Fatalf("failed to write kotlin dependency graph %q: %v", kt.depGraphFile, err)
+	}
+}
+
+// Write the targets of a dependency graph sorted by label, as a JSON list of the
+// targets or a DOT digraph with an edge per dependency labeled with its imports.
+func writeDepGraph(w io.Writer, format string, targets []DepGraphTarget) error {
+	sort.SliceStable(targets, func(i, j int) bool {
+		return targets[i].Label < targets[j].Label
+	})
+
+	if format == DepGraphFormat_JSON {
+		if targets == nil {
+			targets = []DepGraphTarget{}
+		}
+
+		content, err := json.MarshalIndent(targets, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(content, '\n'))
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph kotlin {\n")
+	for _, target := range targets {
+		fmt.Fprintf(&b, "  %s [kind=%s];\n", strconv.Quote(target.Label), strconv.Quote(target.Kind))
+	}
+	for _, target := range targets {
+		for _, dep := range target.Deps {
+			var attrs []string
+			if len(dep.Imports) > 0 {
+				attrs = append(attrs, "label="+strconv.Quote(strings.Join(dep.Imports, "\n")))
+			}
+			if dep.CompileOnly {
+				attrs = append(attrs, "style=dashed")
+			}
+
+			fmt.Fprintf(&b, "  %s -> %s", strconv.Quote(target.Label), strconv.Quote(dep.Label))
+			if len(attrs) > 0 {
+				fmt.Fprintf(&b, " [%s]", strings.Join(attrs, ", "))
+			}
+			b.WriteString(";\n")
+		}
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// The strings sorted without duplicates, nil if empty.
+func sortedUnique(s []string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+
+	sorted := make([]string, 0, len(s))
+	seen := make(map[string]bool, len(s))
+	for _, v := range s {
+		if !seen[v] {
+			seen[v] = true
+			sorted = append(sorted, v)
+		}
+	}
+	sort.Strings(sorted)
+	return sorted
+}
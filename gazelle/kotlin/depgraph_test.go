@@ -0,0 +1,110 @@
+package gazelle
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDepGraphFormat(t *testing.T) {
+	for _, tc := range []struct {
+		file     string
+		format   string
+		expected string
+	}{
+		{file: "graph.json", format: "", expected: DepGraphFormat_JSON},
+		{file: "graph", format: "", expected: DepGraphFormat_JSON},
+		{file: "graph.dot", format: "", expected: DepGraphFormat_DOT},
+		{file: "graph.gv", format: "", expected: DepGraphFormat_DOT},
+		{file: "graph.dot", format: DepGraphFormat_JSON, expected: DepGraphFormat_JSON},
+		{file: "graph.json", format: DepGraphFormat_DOT, expected: DepGraphFormat_DOT},
+	} {
+		actual, err := depGraphFormat(tc.file, tc.format)
+		if err != nil {
+			t.Errorf("depGraphFormat(%q, %q) failed: %v", tc.file, tc.format, err)
+		} else if actual != tc.expected {
+			t.Errorf("depGraphFormat(%q, %q)...\nactual:  %q;\nexpected: %q", tc.file, tc.format, actual, tc.expected)
+		}
+	}
+
+	if _, err := depGraphFormat("graph.svg", "svg"); err == nil {
+		t.Errorf("depGraphFormat(%q, %q) expected an error", "graph.svg", "svg")
+	}
+}
+
+func TestWriteDepGraph(t *testing.T) {
+	targets := func() []DepGraphTarget {
+		return []DepGraphTarget{
+			{
+				Label: "//b:b",
+				Kind:  KtJvmLibrary,
+				Deps:  []DepGraphDep{},
+			},
+			{
+				Label: "//a:a",
+				Kind:  KtJvmBinary,
+				Deps: []DepGraphDep{
+					{Label: "//b:b", Imports: []string{"com.b.B", "com.b.C"}},
+					{Label: "//extra:extra"},
+					{Label: "@maven//:javax_inject", Imports: []string{"javax.inject.*"}, CompileOnly: true},
+				},
+			},
+		}
+	}
+
+	var dot strings.Builder
+	if err := writeDepGraph(&dot, DepGraphFormat_DOT, targets()); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedDot := `digraph kotlin {
+  "//a:a" [kind="kt_jvm_binary"];
+  "//b:b" [kind="kt_jvm_library"];
+  "//a:a" -> "//b:b" [label="com.b.B\ncom.b.C"];
+  "//a:a" -> "//extra:extra";
+  "//a:a" -> "@maven//:javax_inject" [label="javax.inject.*", style=dashed];
+}
+`
+	if dot.String() != expectedDot {
+		t.Errorf("writeDepGraph(dot)...\nactual:\n%s\nexpected:\n%s", dot.String(), expectedDot)
+	}
+
+	var json strings.Builder
+	if err := writeDepGraph(&json, DepGraphFormat_JSON, targets()); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedJSON := `[
+  {
+    "label": "//a:a",
+    "kind": "kt_jvm_binary",
+    "deps": [
+      {
+        "label": "//b:b",
+        "imports": [
+          "com.b.B",
+          "com.b.C"
+        ]
+      },
+      {
+        "label": "//extra:extra"
+      },
+      {
+        "label": "@maven//:javax_inject",
+        "imports": [
+          "javax.inject.*"
+        ],
+        "compile_only": true
+      }
+    ]
+  },
+  {
+    "label": "//b:b",
+    "kind": "kt_jvm_library",
+    "deps": []
+  }
+]
+`
+	if json.String() != expectedJSON {
+		t.Errorf("writeDepGraph(json)...\nactual:\n%s\nexpected:\n%s", json.String(), expectedJSON)
+	}
+}
@@ -0,0 +1,235 @@
+package gazelle
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"aspect.build/cli/gazelle/common/git"
+	"aspect.build/cli/gazelle/kotlin/kotlinconfig"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// The directives of the extension with an "enabled" or "disabled" value.
+var enabledDirectives = map[string]bool{
+	kotlinconfig.Directive_KotlinExtension:   true,
+	kotlinconfig.Directive_Cleanup:           true,
+	kotlinconfig.Directive_GenerateTests:     true,
+	kotlinconfig.Directive_GenerateLibraries: true,
+	kotlinconfig.Directive_GenerateBinaries:  true,
+	kotlinconfig.Directive_InferTestonly:     true,
+	kotlinconfig.Directive_StrictDeps:        true,
+	kotlinconfig.Directive_ResolutionTrace:   true,
+	kotlinconfig.Directive_FollowSymlinks:    true,
+	kotlinconfig.Directive_JavaSources:       true,
+	kotlinconfig.Directive_Associates:        true,
+	kotlinconfig.Directive_Lint:              true,
+	git.Directive_GitIgnore:                  true,
+}
+
+// The directives of the extension with one of a set of values.
+var enumDirectives = map[string][]string{
+	kotlinconfig.Directive_MavenResolver:            {"rules_jvm", "builtin"},
+	kotlinconfig.Directive_ValidateImportStatements: {"error", "warn", "off"},
+	kotlinconfig.Directive_ValidateTestonly:         {"error", "warn", "off"},
+	kotlinconfig.Directive_NameCollision:            {"error", "rename"},
+	kotlinconfig.Directive_GenerationMode:           {"directory", "package", "module", "file"},
+}
+
+// ValidateDirective checks the value of a directive of the extension as Configure does,
+// without configuring a package, returning the error gazelle would fail with. Directives
+// not read by the extension are valid.
+func ValidateDirective(d rule.Directive) error {
+	value := strings.TrimSpace(d.Value)
+	parts := strings.Fields(d.Value)
+
+	if enabledDirectives[d.Key] {
+		if value != "enabled" && value != "disabled" {
+			return fmt.Errorf("invalid value for directive %q: %s: expected enabled or disabled", d.Key, d.Value)
+		}
+		return nil
+	}
+
+	if values, isEnum := enumDirectives[d.Key]; isEnum {
+		for _, v := range values {
+			if value == v {
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid value for directive %q: %s: expected one of %s", d.Key, d.Value, strings.Join(values, ", "))
+	}
+
+	switch d.Key {
+	case kotlinconfig.Directive_TestFileSuffixes:
+		for _, suffix := range parts {
+			if _, err := path.Match(suffix, ""); err != nil {
+				return fmt.Errorf("invalid glob pattern for directive %q: %s", d.Key, suffix)
+			}
+		}
+
+	case kotlinconfig.Directive_TestFrameworkDeps:
+		if len(parts) == 0 {
+			return nil
+		}
+		if len(parts) < 2 || !kotlinconfig.IsTestFramework(parts[0]) {
+			return fmt.Errorf("invalid value for directive %q: %s: expected a test framework and labels", d.Key, d.Value)
+		}
+		return validateLabels(d.Key, parts[1:])
+
+	case kotlinconfig.Directive_Data, kotlinconfig.Directive_Resources:
+		if value != "" && !doublestar.ValidatePattern(value) {
+			return fmt.Errorf("invalid glob pattern for directive %q: %s", d.Key, value)
+		}
+
+	case kotlinconfig.Directive_ResourceStripPrefix:
+		prefix := path.Clean(value)
+		if path.IsAbs(prefix) || prefix == ".." || strings.HasPrefix(prefix, "../") {
+			return fmt.Errorf("invalid value for directive %q: %s: expected a path relative to the BUILD file", d.Key, d.Value)
+		}
+
+	case kotlinconfig.Directive_MavenRepository:
+		if len(parts) != 0 && len(parts) != 2 {
+			return fmt.Errorf("invalid value for directive %q: %s: expected a repository name and maven_install.json path", d.Key, d.Value)
+		}
+
+	case kotlinconfig.Directive_MavenRepositoryName:
+		if strings.ContainsAny(value, "@/: ") {
+			return fmt.Errorf("invalid value for directive %q: %s: expected a repository name such as \"maven\"", d.Key, d.Value)
+		}
+
+	case kotlinconfig.Directive_MavenExcludeArtifact:
+		if value == "" {
+			return nil
+		}
+		coordinate := strings.Split(value, ":")
+		if len(coordinate) != 2 || coordinate[0] == "" || coordinate[1] == "" || strings.ContainsAny(value, " \t") {
+			return fmt.Errorf("invalid value for directive %q: %s: expected a maven artifact such as \"com.google.guava:guava\"", d.Key, d.Value)
+		}
+
+	case kotlinconfig.Directive_TestSources:
+		if _, err := strconv.ParseBool(value); err != nil && value != "enabled" && value != "disabled" {
+			return fmt.Errorf("invalid value for directive %q: %s: expected enabled, disabled, true or false", d.Key, d.Value)
+		}
+
+	case kotlinconfig.Directive_Testonly:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("invalid value for directive %q: %s: expected true or false", d.Key, d.Value)
+		}
+
+	case kotlinconfig.Directive_LanguageVersion, kotlinconfig.Directive_ApiVersion:
+		if value != "" && !kotlinVersionRegex.MatchString(value) {
+			return fmt.Errorf("invalid value for directive %q: %s: expected a kotlin version such as \"1.9\"", d.Key, d.Value)
+		}
+
+	case kotlinconfig.Directive_ModuleRootMarkers:
+		for _, marker := range parts {
+			if strings.Contains(marker, "/") {
+				return fmt.Errorf("invalid value for directive %q: %s: expected file names", d.Key, d.Value)
+			}
+		}
+
+	case kotlinconfig.Directive_CompilerPlugin:
+		// Whether a plugin without annotations was declared depends upon the parent packages
+		if len(parts) < 2 {
+			return fmt.Errorf("invalid value for directive %q: %s: expected a plugin id, label and optional annotations", d.Key, d.Value)
+		}
+		return validateLabels(d.Key, parts[1:2])
+
+	case kotlinconfig.Directive_ServiceProvider:
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid value for directive %q: %s: expected a service and label", d.Key, d.Value)
+		}
+		return validateLabels(d.Key, parts[1:])
+
+	case kotlinconfig.Directive_MainClass:
+		if len(parts) != 2 || path.IsAbs(parts[0]) {
+			return fmt.Errorf("invalid value for directive %q: %s: expected a relative source file and main class", d.Key, d.Value)
+		}
+
+	case kotlinconfig.Directive_GeneratedImport:
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid value for directive %q: %s: expected a pattern and label or \"self\"", d.Key, d.Value)
+		}
+		if _, err := path.Match(parts[0], ""); err != nil {
+			return fmt.Errorf("invalid pattern for directive %q: %s: %v", d.Key, parts[0], err)
+		}
+		if parts[1] != "self" {
+			return validateLabels(d.Key, parts[1:])
+		}
+
+	case kotlinconfig.Directive_ResolveRegexp:
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid value for directive %q: %s: expected a regular expression and label", d.Key, d.Value)
+		}
+		if _, err := regexp.Compile("^(?:" + parts[0] + ")$"); err != nil {
+			return fmt.Errorf("invalid regular expression for directive %q: %s: %v", d.Key, parts[0], err)
+		}
+		return validateLabels(d.Key, parts[1:])
+
+	case kotlinconfig.Directive_NativeImport:
+		prefix := strings.TrimSuffix(strings.TrimPrefix(value, "!"), ".*")
+		if prefix == "" || strings.ContainsAny(prefix, " \t*") {
+			return fmt.Errorf("invalid value for directive %q: %s: expected a package prefix", d.Key, d.Value)
+		}
+
+	case kotlinconfig.Directive_AnnotationProcessor:
+		if len(parts) == 0 {
+			return nil
+		}
+		if len(parts) < 2 {
+			return fmt.Errorf("invalid value for directive %q: %s: expected an annotation, a plugin label and optional dependency labels", d.Key, d.Value)
+		}
+		return validateLabels(d.Key, parts[1:])
+
+	case kotlinconfig.Directive_ExtraDeps:
+		if len(parts) == 0 {
+			return nil
+		}
+		if kotlinKinds[parts[0]].ResolveAttrs["deps"] {
+			parts = parts[1:]
+		}
+		if len(parts) == 0 {
+			return fmt.Errorf("invalid value for directive %q: %s: expected an optional kind and labels", d.Key, d.Value)
+		}
+		return validateLabels(d.Key, parts)
+
+	case kotlinconfig.Directive_CompileOnly:
+		if len(parts) != 1 && len(parts) != 2 {
+			return fmt.Errorf("invalid value for directive %q: %s: expected a pattern and optional label", d.Key, d.Value)
+		}
+		return validateLabels(d.Key, parts[1:])
+
+	case kotlinconfig.Directive_PackageFallbackDepth:
+		if depth, err := strconv.Atoi(value); err != nil || depth < 0 {
+			return fmt.Errorf("invalid value for directive %q: %s: expected a non-negative number", d.Key, d.Value)
+		}
+
+	case kotlinconfig.Directive_PreferProvider:
+		return validateLabels(d.Key, parts)
+
+	case kotlinconfig.Directive_LabelRewrite:
+		if len(parts) != 0 && len(parts) != 2 {
+			return fmt.Errorf("invalid value for directive %q: %s: expected a label prefix and replacement", d.Key, d.Value)
+		}
+
+	case kotlinconfig.Directive_FallbackDep, kotlinconfig.Directive_LintConfig:
+		if value != "" {
+			return validateLabels(d.Key, []string{value})
+		}
+	}
+
+	return nil
+}
+
+func validateLabels(key string, labels []string) error {
+	for _, l := range labels {
+		if _, err := label.Parse(l); err != nil {
+			return fmt.Errorf("invalid label for directive %q: %s: %v", key, l, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package gazelle
+
+import (
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+func TestValidateDirective(t *testing.T) {
+	valid := []rule.Directive{
+		{Key: "kotlin_generate_tests", Value: "enabled"},
+		{Key: "kotlin_generation_mode", Value: "module"},
+		{Key: "kotlin_language_version", Value: "1.9"},
+		{Key: "kotlin_testonly", Value: "true"},
+		{Key: "kotlin_test_srcs", Value: "enabled"},
+		{Key: "kotlin_test_srcs", Value: "false"},
+		{Key: "kotlin_data", Value: "testdata/**"},
+		{Key: "kotlin_data", Value: ""},
+		{Key: "kotlin_extra_deps", Value: "kt_jvm_test //testing:junit"},
+		{Key: "kotlin_test_framework_deps", Value: "junit5 @maven//:org_junit_jupiter_junit_jupiter_api"},
+		{Key: "kotlin_resolve_regexp", Value: "com\\.example\\..* //lib"},
+		{Key: "kotlin_generated_import", Value: "com.example.proto.* self"},
+		{Key: "kotlin_native_import", Value: "!kotlinx.*"},
+		{Key: "kotlin_package_fallback_depth", Value: "2"},
+		{Key: "kotlin_maven_exclude_artifact", Value: "com.google.guava:guava"},
+		{Key: "kotlin_fallback_dep", Value: ""},
+		{Key: "gitignore", Value: "disabled"},
+		{Key: "resolve", Value: "anything"},
+	}
+	for _, d := range valid {
+		if err := ValidateDirective(d); err != nil {
+			t.Errorf("ValidateDirective(%s %s): unexpected error: %v", d.Key, d.Value, err)
+		}
+	}
+
+	invalid := []rule.Directive{
+		{Key: "kotlin_generate_tests", Value: "yes"},
+		{Key: "kotlin_generation_mode", Value: "modules"},
+		{Key: "kotlin_language_version", Value: "1.9.0"},
+		{Key: "kotlin_testonly", Value: "enabled"},
+		{Key: "kotlin_test_srcs", Value: "yes"},
+		{Key: "kotlin_data", Value: "testdata/[a"},
+		{Key: "kotlin_resource_strip_prefix", Value: "../other"},
+		{Key: "kotlin_extra_deps", Value: "kt_jvm_test"},
+		{Key: "kotlin_extra_deps", Value: "//a:b:c"},
+		{Key: "kotlin_test_framework_deps", Value: "jasmine //testing:jasmine"},
+		{Key: "kotlin_resolve_regexp", Value: "com.(example //lib"},
+		{Key: "kotlin_main_class", Value: "/abs/Main.kt com.example.MainKt"},
+		{Key: "kotlin_native_import", Value: "*"},
+		{Key: "kotlin_package_fallback_depth", Value: "-1"},
+		{Key: "kotlin_maven_exclude_artifact", Value: "guava"},
+		{Key: "kotlin_maven_repository_name", Value: "@maven"},
+		{Key: "kotlin_module_root_markers", Value: "sub/build.gradle.kts"},
+	}
+	for _, d := range invalid {
+		if err := ValidateDirective(d); err == nil {
+			t.Errorf("ValidateDirective(%s %s): expected an error", d.Key, d.Value)
+		}
+	}
+}
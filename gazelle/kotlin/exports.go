@@ -0,0 +1,64 @@
+package gazelle
+
+import (
+	"strings"
+
+	common "aspect.build/cli/gazelle/common"
+	"aspect.build/cli/gazelle/kotlin/kotlinconfig"
+	"aspect.build/cli/gazelle/kotlin/parser"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/emirpasic/gods/sets/treeset"
+)
+
+// The imported symbols referenced by the public declarations of a file, such as
+// `a.b.Foo` for `import a.b.Foo` and `fun f(): Foo`, or by alias such as
+// `import a.b.Foo as Bar` and `fun f(): Bar`.
+func exportedSymbols(p *parser.ParseResult) []string {
+	symbols := make([]string, 0)
+
+	for _, symbol := range p.ImportedSymbols {
+		name := p.ImportedName(symbol)
+
+		for _, t := range p.PublicTypes {
+			// The imported type or a nested type such as `Foo.Bar`
+			if t == name || strings.HasPrefix(t, name+".") {
+				symbols = append(symbols, symbol)
+				break
+			}
+		}
+	}
+
+	return symbols
+}
+
+// Resolve the deps providing the symbols referenced by the public declarations of a library,
+// exported so consumers of the library can compile against its public API.
+func (kt *kotlinLang) resolveExports(c *config.Config, ix *resolve.RuleIndex, target *KotlinLibTarget, from label.Label) *common.LabelSet {
+	exports := common.NewLabelSet(from)
+
+	exported := treeset.NewWith(importStatementComparator)
+	it := target.Imports.Iterator()
+	for it.Next() {
+		if impt := it.Value().(ImportStatement); impt.Symbol != "" && target.ExportedSymbols.Contains(impt.Symbol) {
+			exported.Add(impt)
+		}
+	}
+
+	if exported.Empty() {
+		return exports
+	}
+
+	// Errors are reported when resolving the deps
+	cfg := c.Exts[LanguageName].(kotlinconfig.Configs)[from.Pkg]
+	for _, impt := range exported.Values() {
+		resolutionType, dep, err := kt.resolveImport(c, ix, impt.(ImportStatement), from)
+		if err == nil && resolutionType == Resolution_Label && dep != nil {
+			rewritten := rewriteLabel(c, cfg, *dep)
+			exports.Add(&rewritten)
+		}
+	}
+
+	return exports
+}
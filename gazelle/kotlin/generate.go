@@ -1,27 +1,37 @@
 package gazelle
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"os"
 	"path"
+	"slices"
 	"strings"
 	"sync"
 
 	gazelle "aspect.build/cli/gazelle/common"
+	treeutils "aspect.build/cli/gazelle/common/treesitter"
 	"aspect.build/cli/gazelle/kotlin/kotlinconfig"
 	"aspect.build/cli/gazelle/kotlin/parser"
 	BazelLog "aspect.build/cli/pkg/logger"
+	"github.com/bazelbuild/bazel-gazelle/label"
 	"github.com/bazelbuild/bazel-gazelle/language"
 	"github.com/bazelbuild/bazel-gazelle/resolve"
 	"github.com/bazelbuild/bazel-gazelle/rule"
+	bzl "github.com/bazelbuild/buildtools/build"
 	"github.com/emirpasic/gods/maps/treemap"
 	"github.com/emirpasic/gods/sets/treeset"
 )
 
 const (
 	// TODO: move to common
+	// The default maximum number of files parsed in parallel
 	MaxWorkerCount = 12
+
+	// The environment variable setting the maximum number of files parsed in parallel,
+	// unless set by the -kotlin-parallelism flag
+	ParallelismEnv = "GAZELLE_KOTLIN_PARALLELISM"
 )
 
 func (kt *kotlinLang) GenerateRules(args language.GenerateArgs) language.GenerateResult {
@@ -29,10 +39,31 @@ func (kt *kotlinLang) GenerateRules(args language.GenerateArgs) language.Generat
 
 	cfg := args.Config.Exts[LanguageName].(kotlinconfig.Configs)[args.Rel]
 
+	// Rules of other languages depending on kotlin libraries, such as a java_library.
+	kt.recordOtherRules(args)
+
+	// Rules compiling proto_library rules to java or kotlin code, such as a java_proto_library.
+	kt.recordProtoRules(args)
+
+	// The visibility of rules depended upon by kotlin rules.
+	kt.recordVisibility(args)
+
 	// When we return empty, we mean that we don't generate anything, but this
 	// still triggers the indexing for all the TypeScript targets in this package.
 	if !cfg.GenerationEnabled() {
 		BazelLog.Tracef("GenerateRules(%s) disabled: %s", LanguageName, args.Rel)
+
+		var result language.GenerateResult
+		if cfg.CleanupEnabled() {
+			removeGeneratedRules(args, generatedRuleKinds, &result)
+		}
+		return result
+	}
+
+	// Sources of sub-directories of a module are generated in the module root, unless the
+	// sub-directory is a Bazel package.
+	if cfg.GenerationMode() == kotlinconfig.GenerationModule && args.Rel != cfg.ModuleRoot() && args.File == nil {
+		BazelLog.Tracef("GenerateRules(%s) included in module %q: %s", LanguageName, cfg.ModuleRoot(), args.Rel)
 		return language.GenerateResult{}
 	}
 
@@ -41,27 +72,93 @@ func (kt *kotlinLang) GenerateRules(args language.GenerateArgs) language.Generat
 	// Collect all source files.
 	sourceFiles := kt.collectSourceFiles(cfg, args)
 
-	// TODO: multiple library targets (lib, test, ...)
-	libTarget := NewKotlinLibTarget()
+	// The library targets by the key of their sources, see libraryKey.
+	libTargets := treemap.NewWithStringComparator()
 	binTargets := treemap.NewWithStringComparator()
+	testTargets := treemap.NewWithStringComparator()
 
 	// Parse all source files and group information into target(s)
-	for p := range kt.parseFiles(args, sourceFiles) {
+	for _, p := range kt.parseFiles(args, sourceFiles) {
 		var target *KotlinTarget
 
-		if p.HasMain {
+		if cfg.GenerateTests() && (cfg.TestSources() || cfg.IsTestFile(p.File)) {
+			testTarget := NewKotlinTestTarget(p.File, p.Package)
+			testTargets.Put(p.File, testTarget)
+
+			for _, impt := range slices.Concat(p.Imports, p.ImportedSymbols, p.StarImports) {
+				if framework := kotlinconfig.TestFrameworkOf(impt); framework != "" {
+					testTarget.Frameworks.Add(framework)
+				}
+			}
+
+			target = &testTarget.KotlinTarget
+		} else if cfg.GenerateBinaries() && p.HasMain {
 			binTarget := NewKotlinBinTarget(p.File, p.Package)
 			binTargets.Put(p.File, binTarget)
 
 			target = &binTarget.KotlinTarget
+		} else if !cfg.GenerateLibraries() {
+			BazelLog.Tracef("Library source not generated: %s", p.File)
+			continue
 		} else {
+			key := libraryKey(cfg, p)
+			v, exists := libTargets.Get(key)
+			if !exists {
+				v = NewKotlinLibTarget()
+				libTargets.Put(key, v)
+			}
+			libTarget := v.(*KotlinLibTarget)
+
 			libTarget.Files.Add(p.File)
 			libTarget.Packages.Add(p.Package)
 
+			for _, d := range p.Declarations {
+				libTarget.Declarations.Add(qualifiedName(p.Package, d))
+			}
+
+			for _, symbol := range exportedSymbols(p) {
+				libTarget.ExportedSymbols.Add(symbol)
+			}
+
 			target = &libTarget.KotlinTarget
 		}
 
+		// Star imports are resolved to all targets providing the package.
+		for _, pkg := range p.StarImports {
+			target.Imports.Add(ImportStatement{
+				ImportSpec: resolve.ImportSpec{
+					Lang: LanguageName,
+					Imp:  pkg,
+				},
+				IsStar:     true,
+				SourcePath: p.File,
+			})
+		}
+
+		// Imports of symbols are resolved by symbol before falling back to the package.
+		symbolPackages := make(map[string]bool, len(p.ImportedSymbols))
+		for _, symbol := range p.ImportedSymbols {
+			pkg := symbol
+			if dot := strings.LastIndex(symbol, "."); dot != -1 {
+				pkg = symbol[:dot]
+			}
+			symbolPackages[pkg] = true
+
+			target.Imports.Add(ImportStatement{
+				ImportSpec: resolve.ImportSpec{
+					Lang: LanguageName,
+					Imp:  pkg,
+				},
+				Symbol:     symbol,
+				SourcePath: p.File,
+			})
+		}
+
 		for _, impt := range p.Imports {
+			if symbolPackages[impt] || slices.Contains(p.StarImports, impt) {
+				continue
+			}
+
 			target.Imports.Add(ImportStatement{
 				ImportSpec: resolve.ImportSpec{
 					Lang: LanguageName,
@@ -70,34 +167,222 @@ func (kt *kotlinLang) GenerateRules(args language.GenerateArgs) language.Generat
 				SourcePath: p.File,
 			})
 		}
+
+		// Fully qualified names referenced without an import, unless also imported by another file.
+		for _, symbol := range p.QualifiedReferences {
+			impt := ImportStatement{
+				ImportSpec: resolve.ImportSpec{
+					Lang: LanguageName,
+					Imp:  symbol[:strings.LastIndex(symbol, ".")],
+				},
+				Symbol:               symbol,
+				IsQualifiedReference: true,
+				SourcePath:           p.File,
+			}
+			if !target.Imports.Contains(impt) {
+				target.Imports.Add(impt)
+			}
+		}
+
+		// Names referenced without an import may be declared in the same package by another target.
+		for _, name := range samePackageReferences(p) {
+			target.Imports.Add(ImportStatement{
+				ImportSpec: resolve.ImportSpec{
+					Lang: LanguageName,
+					Imp:  p.Package,
+				},
+				Symbol:        qualifiedName(p.Package, name),
+				IsSamePackage: true,
+				SourcePath:    p.File,
+			})
+		}
+
+		for _, plugin := range compilerPluginsForFile(cfg, p) {
+			target.Plugins.Add(*plugin.Label)
+		}
+
+		for _, processor := range annotationProcessorsForFile(cfg, p) {
+			target.Plugins.Add(processor.Plugin)
+			for _, dep := range processor.Deps {
+				target.ProcessorDeps.Add(dep)
+			}
+		}
+
+		for _, provider := range serviceProvidersForFile(cfg, p) {
+			target.RuntimeDeps.Add(provider)
+		}
+	}
+
+	// Generate nothing but remove the existing library if there are no library sources.
+	if libTargets.Empty() && cfg.GenerateLibraries() {
+		libTargets.Put("", NewKotlinLibTarget())
+	}
+
+	// Services declared in META-INF/services are provided by the libraries.
+	for _, service := range declaredServices(args) {
+		for _, provider := range cfg.ServiceProviders(service) {
+			for _, v := range libTargets.Values() {
+				v.(*KotlinLibTarget).RuntimeDeps.Add(provider)
+			}
+		}
 	}
 
 	var result language.GenerateResult
 
-	libTargetName := gazelle.ToDefaultTargetName(args, "root")
+	// Remove the existing rules of the kinds no longer generated if cleanup is enabled.
+	if cfg.CleanupEnabled() {
+		if !cfg.GenerateLibraries() {
+			removeGeneratedRules(args, libraryRuleKinds, &result)
+		}
+		if !cfg.GenerateBinaries() {
+			removeGeneratedRules(args, binaryRuleKinds, &result)
+		}
+	}
+
+	// Resources are added to the first library, resources of multiple libraries would
+	// be duplicated within the runtime classpath.
+	if resources := collectResourceFiles(cfg, args); len(resources) > 0 && !libTargets.Empty() {
+		libTargets.Values()[0].(*KotlinLibTarget).Resources = resources
+	}
+
+	// The names of the generated libraries, and of the libraries by package.
+	libTargetNames := make([]string, 0, libTargets.Size())
+	packageLibTargetNames := make(map[string][]string)
+
+	libTargetsIt := libTargets.Iterator()
+	for libTargetsIt.Next() {
+		libTarget := libTargetsIt.Value().(*KotlinLibTarget)
+
+		libTargetName := toLibraryTargetName(cfg, args, libTargetsIt.Key().(string), libTargets.Size())
+		if renamed := findRenamedRule(args, KtJvmLibrary, libTargetName, libTarget.Files.Values()); !slices.Contains(libTargetNames, renamed) {
+			libTargetName = renamed
+		}
+		if !libTarget.Files.Empty() {
+			libTargetName = resolveNameCollision(cfg, args, KtJvmLibrary, libTargetName)
+		}
+
+		srcGenErr := kt.addLibraryRule(libTargetName, libTarget, args, cfg.Testonly(), &result)
+		if srcGenErr != nil {
+			fmt.Fprintf(os.Stderr, "Source rule generation error: %v\n", srcGenErr)
+			os.Exit(1)
+		}
+
+		kt.addLintRule(toLintTargetName(libTargetName), libTarget, cfg, args, &result)
 
-	srcGenErr := kt.addLibraryRule(libTargetName, libTarget, args, false, &result)
-	if srcGenErr != nil {
-		fmt.Fprintf(os.Stderr, "Source rule generation error: %v\n", srcGenErr)
-		os.Exit(1)
+		if !libTarget.Files.Empty() {
+			libTargetNames = append(libTargetNames, libTargetName)
+			for _, pkg := range libTarget.Packages.Values() {
+				packageLibTargetNames[pkg.(string)] = append(packageLibTargetNames[pkg.(string)], libTargetName)
+			}
+		}
 	}
 
+	if mode := cfg.GenerationMode(); mode == kotlinconfig.GenerationPackage || mode == kotlinconfig.GenerationFile {
+		removeStaleLibraries(args, libTargetNames, libTargets, &result)
+	}
+
+	// Binaries and tests are associated with the library of the directory, or the
+	// library of the same package when generating multiple libraries, if any.
+	associateOf := func(pkg string) string {
+		if !cfg.AssociatesEnabled() {
+			return ""
+		}
+		if len(libTargetNames) == 1 {
+			return libTargetNames[0]
+		}
+		if names := packageLibTargetNames[pkg]; len(names) == 1 {
+			return names[0]
+		}
+		return ""
+	}
+
+	dataFiles := collectDataFiles(cfg, args)
+
 	for _, v := range binTargets.Values() {
 		binTarget := v.(*KotlinBinTarget)
 		binTargetName := toBinaryTargetName(binTarget.File)
-		kt.addBinaryRule(binTargetName, binTarget, args, &result)
+		binTargetName = findRenamedRule(args, KtJvmBinary, binTargetName, []interface{}{binTarget.File})
+		kt.addBinaryRule(binTargetName, binTarget, associateOf(binTarget.Package), dataFiles, args, &result)
+	}
+
+	for _, v := range testTargets.Values() {
+		testTarget := v.(*KotlinTestTarget)
+		associate := associateOf(testTarget.Package)
+
+		// Tests depend on the library of the same package unless associated with it.
+		if associate == "" && len(packageLibTargetNames[testTarget.Package]) > 0 {
+			testTarget.Imports.Add(ImportStatement{
+				ImportSpec: resolve.ImportSpec{
+					Lang: LanguageName,
+					Imp:  testTarget.Package,
+				},
+				SourcePath: testTarget.File,
+			})
+		}
+
+		testTargetName := toTestTargetName(testTarget.File)
+		testTargetName = findRenamedRule(args, KtJvmTest, testTargetName, []interface{}{testTarget.File})
+		kt.addTestRule(testTargetName, testTarget, associate, dataFiles, args, &result)
+	}
+
+	kt.recordGeneratedRules(args, &result)
+
+	if kt.changeReportFile != "" {
+		kt.recordChanges(args, &result)
 	}
 
 	return result
 }
 
-func (kt *kotlinLang) addLibraryRule(targetName string, target *KotlinLibTarget, args language.GenerateArgs, isTestRule bool, result *language.GenerateResult) error {
-	// Check for name-collisions with the rule being generated.
-	colError := gazelle.CheckCollisionErrors(targetName, KtJvmLibrary, sourceRuleKinds, args)
-	if colError != nil {
-		return colError
+// The key of the library of a parsed source file according to the generation mode.
+func libraryKey(cfg *kotlinconfig.KotlinConfig, p *parser.ParseResult) string {
+	switch cfg.GenerationMode() {
+	case kotlinconfig.GenerationPackage:
+		return p.Package
+	case kotlinconfig.GenerationFile:
+		return p.File
+	}
+	return ""
+}
+
+// Remove the existing libraries no longer generated when generating multiple libraries,
+// such as libraries of packages or files since removed. Only libraries whose sources are
+// all either removed or owned by the generated libraries are removed.
+func removeStaleLibraries(args language.GenerateArgs, libTargetNames []string, libTargets *treemap.Map, result *language.GenerateResult) {
+	if args.File == nil {
+		return
+	}
+
+	generated := treeset.NewWithStringComparator()
+	for _, v := range libTargets.Values() {
+		generated.Add(v.(*KotlinLibTarget).Files.Values()...)
+	}
+
+	for _, r := range args.File.Rules {
+		if r.Kind() != gazelle.MapKind(args, KtJvmLibrary) || slices.Contains(libTargetNames, r.Name()) {
+			continue
+		}
+
+		stale := true
+		for _, src := range r.AttrStrings("srcs") {
+			if _, err := os.Stat(path.Join(args.Dir, src)); err == nil && !generated.Contains(src) {
+				stale = false
+				break
+			}
+		}
+
+		if stale {
+			BazelLog.Infof("remove stale rule '%s' '%s:%s'", r.Kind(), args.Rel, r.Name())
+			result.Empty = append(result.Empty, rule.NewRule(KtJvmLibrary, r.Name()))
+
+			if existing := gazelle.GetFileRuleByName(args, toLintTargetName(r.Name())); existing != nil && existing.Kind() == gazelle.MapKind(args, KtlintTest) {
+				result.Empty = append(result.Empty, rule.NewRule(KtlintTest, existing.Name()))
+			}
+		}
 	}
+}
 
+func (kt *kotlinLang) addLibraryRule(targetName string, target *KotlinLibTarget, args language.GenerateArgs, isTestRule bool, result *language.GenerateResult) error {
 	// Generate nothing if there are no source files. Remove any existing rules.
 	if target.Files.Empty() {
 		if args.File == nil {
@@ -115,12 +400,32 @@ func (kt *kotlinLang) addLibraryRule(targetName string, target *KotlinLibTarget,
 		return nil
 	}
 
+	// Check for name-collisions with the rule being generated.
+	colError := gazelle.CheckCollisionErrors(targetName, KtJvmLibrary, sourceRuleKinds, args)
+	if colError != nil {
+		return colError
+	}
+
+	srcs := claimSources(KtJvmLibrary, targetName, target.Files.Values(), args)
+	if len(srcs) == 0 {
+		return nil
+	}
+
 	ktLibrary := rule.NewRule(KtJvmLibrary, targetName)
-	ktLibrary.SetAttr("srcs", target.Files.Values())
+	ktLibrary.SetAttr("srcs", srcs)
 	ktLibrary.SetPrivateAttr(packagesKey, target)
+	setExistingDepsAttr(ktLibrary, args)
+	setPluginsAttr(ktLibrary, &target.KotlinTarget, args)
+	setRuntimeDepsAttr(ktLibrary, &target.KotlinTarget, args)
+	setModuleNameAttr(ktLibrary, args)
+	setVersionAttrs(ktLibrary, args)
+	setResourcesAttr(ktLibrary, target, args)
+	setTagsAttr(ktLibrary, args)
 
 	if isTestRule {
 		ktLibrary.SetAttr("testonly", true)
+	} else {
+		setExistingTestonlyAttr(ktLibrary, args)
 	}
 
 	result.Gen = append(result.Gen, ktLibrary)
@@ -130,16 +435,78 @@ func (kt *kotlinLang) addLibraryRule(targetName string, target *KotlinLibTarget,
 	return nil
 }
 
-func (kt *kotlinLang) addBinaryRule(targetName string, target *KotlinBinTarget, args language.GenerateArgs, result *language.GenerateResult) {
-	main_class := strings.TrimSuffix(target.File, ".kt")
-	if target.Package != "" {
-		main_class = target.Package + "." + main_class
+// Add a lint rule for the kotlin sources of the library, or remove an existing lint rule
+// when linting is disabled or the library has no kotlin sources.
+func (kt *kotlinLang) addLintRule(targetName string, target *KotlinLibTarget, cfg *kotlinconfig.KotlinConfig, args language.GenerateArgs, result *language.GenerateResult) {
+	srcs := make([]string, 0, target.Files.Size())
+	for _, f := range target.Files.Values() {
+		if !isJavaSourceFileType(f.(string)) {
+			srcs = append(srcs, f.(string))
+		}
+	}
+
+	if !cfg.LintEnabled() || len(srcs) == 0 {
+		if existing := gazelle.GetFileRuleByName(args, targetName); existing != nil && existing.Kind() == gazelle.MapKind(args, KtlintTest) {
+			result.Empty = append(result.Empty, rule.NewRule(KtlintTest, targetName))
+		}
+		return
+	}
+
+	ktlint := rule.NewRule(KtlintTest, targetName)
+	ktlint.SetAttr("srcs", srcs)
+	if lintConfig := cfg.LintConfig(); lintConfig != nil {
+		ktlint.SetAttr("config", lintConfig.Rel("", args.Rel).String())
+	}
+	setTagsAttr(ktlint, args)
+
+	result.Gen = append(result.Gen, ktlint)
+	result.Imports = append(result.Imports, nil)
+
+	BazelLog.Infof("add rule '%s' '%s:%s'", ktlint.Kind(), args.Rel, ktlint.Name())
+}
+
+func (kt *kotlinLang) addBinaryRule(targetName string, target *KotlinBinTarget, associate string, dataFiles []string, args language.GenerateArgs, result *language.GenerateResult) {
+	cfg := args.Config.Exts[LanguageName].(kotlinconfig.Configs)[args.Rel]
+
+	// The pinned main_class replaces the main_class of an existing binary, which is
+	// otherwise preserved.
+	main_class, pinned := cfg.MainClass(path.Join(args.Rel, target.File))
+	if !pinned {
+		main_class = existingMainClass(args, targetName)
+	}
+	if main_class == "" {
+		main_class = strings.TrimSuffix(path.Base(target.File), ".kt")
+		if target.Package != "" {
+			main_class = target.Package + "." + main_class
+		}
+	}
+
+	if len(claimSources(KtJvmBinary, targetName, []interface{}{target.File}, args)) == 0 {
+		return
 	}
 
 	ktBinary := rule.NewRule(KtJvmBinary, targetName)
 	ktBinary.SetAttr("srcs", []string{target.File})
 	ktBinary.SetAttr("main_class", main_class)
+	if cfg.Testonly() {
+		ktBinary.SetAttr("testonly", true)
+	}
+	if len(dataFiles) > 0 {
+		ktBinary.SetAttr("data", dataFiles)
+	}
 	ktBinary.SetPrivateAttr(packagesKey, target)
+	setExistingDepsAttr(ktBinary, args)
+	setPluginsAttr(ktBinary, &target.KotlinTarget, args)
+	setRuntimeDepsAttr(ktBinary, &target.KotlinTarget, args)
+
+	// Associates share the module of the associated library and must not set `module_name`.
+	if associate != "" {
+		ktBinary.SetAttr("associates", []string{":" + associate})
+	} else {
+		setModuleNameAttr(ktBinary, args)
+	}
+	setVersionAttrs(ktBinary, args)
+	setTagsAttr(ktBinary, args)
 
 	result.Gen = append(result.Gen, ktBinary)
 	result.Imports = append(result.Imports, target)
@@ -147,16 +514,346 @@ func (kt *kotlinLang) addBinaryRule(targetName string, target *KotlinBinTarget,
 	BazelLog.Infof("add rule '%s' '%s:%s'", ktBinary.Kind(), args.Rel, ktBinary.Name())
 }
 
+// Claim the srcs of a generated rule, reporting srcs also claimed by other rules.
+// Returns the srcs successfully claimed, srcs claimed by other rules must not be
+// added to the rule.
+func claimSources(kind, targetName string, srcs []interface{}, args language.GenerateArgs) []string {
+	files := make([]string, 0, len(srcs))
+	for _, src := range srcs {
+		files = append(files, src.(string))
+	}
+
+	owner := label.New("", args.Rel, targetName)
+	for _, err := range gazelle.ClaimSources(kind, owner, files) {
+		fmt.Fprintf(os.Stderr, "Source ownership conflict: %v\n", err)
+	}
+
+	claimed := make([]string, 0, len(files))
+	for _, f := range files {
+		if o, _ := gazelle.GetSourceOwner(args.Rel, f); o.Label == owner {
+			claimed = append(claimed, f)
+		}
+	}
+	return claimed
+}
+
+// The kinds of the rules generated for libraries, removed when libraries are not generated.
+var libraryRuleKinds = treeset.NewWithStringComparator(KtJvmLibrary, KtlintTest)
+
+// The kinds of the rules generated for binaries, removed when binaries are not generated.
+var binaryRuleKinds = treeset.NewWithStringComparator(KtJvmBinary)
+
+// Remove all existing rules of the kinds, a subset of the kinds generated by the extension.
+func removeGeneratedRules(args language.GenerateArgs, kinds *treeset.Set, result *language.GenerateResult) {
+	if args.File == nil {
+		return
+	}
+
+	for _, r := range args.File.Rules {
+		gazelle.RemoveRule(args, r.Name(), kinds, result)
+	}
+}
+
+// Rename the rule when the name collides with an existing rule of a different kind and
+// renaming is enabled. Otherwise the name is returned and the collision is reported
+// when generating the rule.
+func resolveNameCollision(cfg *kotlinconfig.KotlinConfig, args language.GenerateArgs, kind, targetName string) string {
+	if cfg.NameCollision() != kotlinconfig.NameCollisionRename {
+		return targetName
+	}
+
+	if gazelle.CheckCollisionErrors(targetName, kind, sourceRuleKinds, args) == nil {
+		return targetName
+	}
+
+	for i := 1; ; i++ {
+		candidate := targetName + "_kt"
+		if i > 1 {
+			candidate = fmt.Sprintf("%s_kt%d", targetName, i)
+		}
+
+		if gazelle.CheckCollisionErrors(candidate, kind, sourceRuleKinds, args) == nil {
+			fmt.Fprintf(os.Stderr, "Renamed %q to %q to avoid colliding with an existing rule of a different kind\n", label.New("", args.Rel, targetName).String(), candidate)
+			return candidate
+		}
+	}
+}
+
+func (kt *kotlinLang) addTestRule(targetName string, target *KotlinTestTarget, associate string, dataFiles []string, args language.GenerateArgs, result *language.GenerateResult) {
+	test_class := strings.TrimSuffix(path.Base(target.File), path.Ext(target.File))
+	if target.Package != "" {
+		test_class = target.Package + "." + test_class
+	}
+
+	if len(claimSources(KtJvmTest, targetName, []interface{}{target.File}, args)) == 0 {
+		return
+	}
+
+	ktTest := rule.NewRule(KtJvmTest, targetName)
+	ktTest.SetAttr("srcs", []string{target.File})
+	ktTest.SetAttr("test_class", test_class)
+	if len(dataFiles) > 0 {
+		ktTest.SetAttr("data", dataFiles)
+	}
+	ktTest.SetPrivateAttr(packagesKey, target)
+	setExistingDepsAttr(ktTest, args)
+	setPluginsAttr(ktTest, &target.KotlinTarget, args)
+	setRuntimeDepsAttr(ktTest, &target.KotlinTarget, args)
+
+	// Associates share the module of the associated library and must not set `module_name`.
+	if associate != "" {
+		ktTest.SetAttr("associates", []string{":" + associate})
+	} else {
+		setModuleNameAttr(ktTest, args)
+	}
+	setVersionAttrs(ktTest, args)
+	setTagsAttr(ktTest, args)
+
+	result.Gen = append(result.Gen, ktTest)
+	result.Imports = append(result.Imports, target)
+
+	BazelLog.Infof("add rule '%s' '%s:%s'", ktTest.Kind(), args.Rel, ktTest.Name())
+}
+
+// Find the name of an existing rule of the same kind owning any of the srcs when no
+// rule of the same kind with the generated name exists. Rules renamed by users, or
+// renamed to avoid name collisions, are updated in place instead of generating a duplicate rule.
+func findRenamedRule(args language.GenerateArgs, kind, targetName string, srcs []interface{}) string {
+	if args.File == nil {
+		return targetName
+	}
+
+	mappedKind := gazelle.MapKind(args, kind)
+
+	if existing := gazelle.GetFileRuleByName(args, targetName); existing != nil && existing.Kind() == mappedKind {
+		return targetName
+	}
+
+	for _, r := range args.File.Rules {
+		if r.Kind() != mappedKind {
+			continue
+		}
+
+		existingSrcs := r.AttrStrings("srcs")
+		for _, src := range srcs {
+			if slices.Contains(existingSrcs, src.(string)) {
+				BazelLog.Infof("update renamed rule '%s:%s' owning %q instead of generating '%s'", args.Rel, r.Name(), src, targetName)
+				return r.Name()
+			}
+		}
+	}
+
+	return targetName
+}
+
+// Set the `plugins` of a rule to the compiler plugins required by the target.
+func setPluginsAttr(r *rule.Rule, target *KotlinTarget, args language.GenerateArgs) {
+	setLabelsAttr(r, "plugins", target.Plugins, args)
+}
+
+// Set the `runtime_deps` of a rule to the service providers required by the target.
+func setRuntimeDepsAttr(r *rule.Rule, target *KotlinTarget, args language.GenerateArgs) {
+	setLabelsAttr(r, "runtime_deps", target.RuntimeDeps, args)
+}
+
+// Set a label list attribute of a rule, relative to the rule and excluding the rule itself.
+func setLabelsAttr(r *rule.Rule, attr string, labels *treeset.Set, args language.GenerateArgs) {
+	if labels.Empty() {
+		return
+	}
+
+	set := gazelle.NewLabelSet(label.New("", args.Rel, r.Name()))
+	for _, v := range labels.Values() {
+		l := v.(label.Label)
+		set.Add(&l)
+	}
+
+	if !set.Empty() {
+		r.SetAttr(attr, set.Labels())
+	}
+}
+
+// Set the `module_name` of a rule to the configured module name, if any.
+func setModuleNameAttr(r *rule.Rule, args language.GenerateArgs) {
+	cfg := args.Config.Exts[LanguageName].(kotlinconfig.Configs)[args.Rel]
+
+	if moduleName := cfg.RenderModuleName(gazelle.ToDefaultTargetName(args, "root")); moduleName != "" {
+		r.SetAttr("module_name", moduleName)
+	}
+}
+
+// Set the configured `language_version` and `api_version`, if any.
+func setVersionAttrs(r *rule.Rule, args language.GenerateArgs) {
+	cfg := args.Config.Exts[LanguageName].(kotlinconfig.Configs)[args.Rel]
+
+	if languageVersion := cfg.LanguageVersion(); languageVersion != "" {
+		r.SetAttr("language_version", languageVersion)
+	}
+	if apiVersion := cfg.ApiVersion(); apiVersion != "" {
+		r.SetAttr("api_version", apiVersion)
+	}
+}
+
+// The main_class of an existing binary, if any.
+func existingMainClass(args language.GenerateArgs, targetName string) string {
+	existing := gazelle.GetFileRuleByName(args, targetName)
+	if existing == nil || existing.Kind() != gazelle.MapKind(args, KtJvmBinary) {
+		return ""
+	}
+	return existing.AttrString("main_class")
+}
+
+// Set the `resources` of a library and the configured `resource_strip_prefix`, if any.
+func setResourcesAttr(r *rule.Rule, target *KotlinLibTarget, args language.GenerateArgs) {
+	if len(target.Resources) == 0 {
+		return
+	}
+
+	r.SetAttr("resources", target.Resources)
+
+	cfg := args.Config.Exts[LanguageName].(kotlinconfig.Configs)[args.Rel]
+	if prefix := cfg.ResourceStripPrefix(); prefix != "" {
+		r.SetAttr("resource_strip_prefix", path.Join(args.Rel, prefix))
+	}
+}
+
+// Record the `deps` of the existing rule, added to the resolved deps of
+// preservedDepsKinds unless strict deps is enabled.
+func setExistingDepsAttr(r *rule.Rule, args language.GenerateArgs) {
+	if existing := gazelle.GetFileRuleByName(args, r.Name()); existing != nil && existing.Kind() == gazelle.MapKind(args, r.Kind()) {
+		r.SetPrivateAttr(existingDepsKey, existing.AttrStrings("deps"))
+	}
+}
+
+// Set the `tags` of a rule to the configured tags merged with the tags of the existing rule.
+// Existing tags once configured by the kotlin_tags directive and no longer configured are
+// removed unless marked `# keep`, other existing tags are preserved.
+func setTagsAttr(r *rule.Rule, args language.GenerateArgs) {
+	cfg := args.Config.Exts[LanguageName].(kotlinconfig.Configs)[args.Rel]
+
+	tags := treeset.NewWithStringComparator()
+	if existing := gazelle.GetFileRuleByName(args, r.Name()); existing != nil && existing.Kind() == gazelle.MapKind(args, r.Kind()) {
+		if existingTags, isList := existing.Attr("tags").(*bzl.ListExpr); isList {
+			for _, e := range existingTags.List {
+				if tag, isStr := e.(*bzl.StringExpr); isStr && (rule.ShouldKeep(e) || !cfg.IsManagedTag(tag.Value)) {
+					tags.Add(tag.Value)
+				}
+			}
+		}
+	}
+	for _, tag := range cfg.Tags() {
+		tags.Add(tag)
+	}
+
+	if !tags.Empty() {
+		r.SetAttr("tags", tags.Values())
+	}
+}
+
+// The compiler plugins required by annotations within the parsed file.
+func compilerPluginsForFile(cfg *kotlinconfig.KotlinConfig, p *parser.ParseResult) []*kotlinconfig.CompilerPlugin {
+	plugins := make([]*kotlinconfig.CompilerPlugin, 0)
+
+	for _, plugin := range cfg.CompilerPlugins() {
+		if hasAnyReference(p.Annotations, p, plugin.Annotations) {
+			plugins = append(plugins, plugin)
+		}
+	}
+
+	return plugins
+}
+
+// The annotation processors of the annotations within the parsed file.
+func annotationProcessorsForFile(cfg *kotlinconfig.KotlinConfig, p *parser.ParseResult) []kotlinconfig.AnnotationProcessor {
+	processors := make([]kotlinconfig.AnnotationProcessor, 0)
+
+	for _, processor := range cfg.AnnotationProcessors() {
+		if hasAnyReference(p.Annotations, p, []string{processor.Annotation}) {
+			processors = append(processors, processor)
+		}
+	}
+
+	return processors
+}
+
+// The simple names referenced in the parsed file that are neither declared in the
+// file nor imported, so possibly declared by another file of the same package.
+func samePackageReferences(p *parser.ParseResult) []string {
+	imported := make(map[string]bool, len(p.ImportedSymbols))
+	for _, symbol := range p.ImportedSymbols {
+		imported[p.ImportedName(symbol)] = true
+	}
+
+	refs := make([]string, 0)
+	for _, ref := range p.References {
+		if !imported[ref] && !slices.Contains(p.Declarations, ref) {
+			refs = append(refs, ref)
+		}
+	}
+
+	return refs
+}
+
+// Determine if any of the fully qualified names are referenced in the parsed file.
+// Names referenced by simple name must be imported or within the same package.
+func hasAnyReference(references []string, p *parser.ParseResult, fqns []string) bool {
+	for _, fqn := range fqns {
+		pkg, name := fqn, ""
+		if dot := strings.LastIndex(fqn, "."); dot != -1 {
+			pkg, name = fqn[:dot], fqn[dot+1:]
+		}
+
+		for _, ref := range references {
+			if ref == fqn {
+				return true
+			}
+
+			// Imported with an alias
+			if aliased, isAlias := p.ImportAliases[ref]; isAlias {
+				if aliased == fqn {
+					return true
+				}
+				continue
+			}
+
+			if ref == name && (p.Package == pkg || slices.Contains(p.Imports, pkg)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+type parseFileResult struct {
+	file   string
+	result *parser.ParseResult
+	errs   []error
+}
+
+// The maximum number of files parsed in parallel, set by the -kotlin-parallelism flag
+// or the GAZELLE_KOTLIN_PARALLELISM environment variable.
+func (kt *kotlinLang) maxWorkerCount() int {
+	if kt.parallelism > 0 {
+		return kt.parallelism
+	}
+	return MaxWorkerCount
+}
+
+// Parse the source files in parallel. Results are returned sorted by file path
+// so generation is independent of the order the workers complete in.
 // TODO: put in common?
-func (kt *kotlinLang) parseFiles(args language.GenerateArgs, sources *treeset.Set) chan *parser.ParseResult {
+func (kt *kotlinLang) parseFiles(args language.GenerateArgs, sources *treeset.Set) []*parser.ParseResult {
 	// The channel of all files to parse.
 	sourcePathChannel := make(chan string)
 
 	// The channel of parse results.
-	resultsChannel := make(chan *parser.ParseResult)
+	resultsChannel := make(chan parseFileResult)
+
+	cfg := args.Config.Exts[LanguageName].(kotlinconfig.Configs)[args.Rel]
 
 	// The number of workers. Don't create more workers than necessary.
-	workerCount := int(math.Min(MaxWorkerCount, float64(1+sources.Size()/2)))
+	workerCount := int(math.Min(float64(kt.maxWorkerCount()), float64(1+sources.Size()/2)))
 
 	// Start the worker goroutines.
 	var wg sync.WaitGroup
@@ -166,17 +863,9 @@ func (kt *kotlinLang) parseFiles(args language.GenerateArgs, sources *treeset.Se
 			defer wg.Done()
 
 			for sourcePath := range sourcePathChannel {
-				r, errs := parseFile(path.Join(args.Config.RepoRoot, args.Rel), sourcePath)
-
-				// Output errors to stdout
-				if len(errs) > 0 {
-					fmt.Println(sourcePath, "parse error(s):")
-					for _, err := range errs {
-						fmt.Println(err)
-					}
-				}
+				r, errs := parseFile(path.Join(args.Config.RepoRoot, args.Rel), sourcePath, cfg.LanguageVersion())
 
-				resultsChannel <- r
+				resultsChannel <- parseFileResult{file: sourcePath, result: r, errs: errs}
 			}
 		}()
 	}
@@ -197,11 +886,37 @@ func (kt *kotlinLang) parseFiles(args language.GenerateArgs, sources *treeset.Se
 		close(resultsChannel)
 	}()
 
-	return resultsChannel
+	// Collect the results in the order of the sorted source files.
+	parsed := make(map[string]parseFileResult, sources.Size())
+	for r := range resultsChannel {
+		parsed[r.file] = r
+	}
+
+	results := make([]*parser.ParseResult, 0, sources.Size())
+	for _, f := range sources.Values() {
+		r := parsed[f.(string)]
+
+		// Output errors to stdout
+		var skipped *treeutils.SkippedFileError
+		if len(r.errs) == 1 && errors.As(r.errs[0], &skipped) {
+			fmt.Println(skipped)
+		} else if len(r.errs) > 0 {
+			fmt.Println(r.file, "parse error(s):")
+			for _, err := range r.errs {
+				fmt.Println(err)
+			}
+		}
+
+		if r.result != nil {
+			results = append(results, r.result)
+		}
+	}
+
+	return results
 }
 
-// Parse the passed file for import statements.
-func parseFile(rootDir, filePath string) (*parser.ParseResult, []error) {
+// Parse the passed file for import statements, kotlin files according to the language version if set.
+func parseFile(rootDir, filePath, languageVersion string) (*parser.ParseResult, []error) {
 	BazelLog.Tracef("ParseImports(%s): %s", LanguageName, filePath)
 
 	content, err := os.ReadFile(path.Join(rootDir, filePath))
@@ -209,20 +924,61 @@ func parseFile(rootDir, filePath string) (*parser.ParseResult, []error) {
 		return nil, []error{err}
 	}
 
-	p := parser.NewParser()
+	p := parser.NewParserForLanguageVersion(languageVersion)
+	if isJavaSourceFileType(filePath) {
+		p = parser.NewJavaParser()
+	}
 	return p.Parse(filePath, string(content))
 }
 
 func (kt *kotlinLang) collectSourceFiles(cfg *kotlinconfig.KotlinConfig, args language.GenerateArgs) *treeset.Set {
 	sourceFiles := treeset.NewWithStringComparator()
 
-	// TODO: "module" targets similar to java?
+	// Sources already owned by other rules such as a java_library.
+	gazelle.ClaimOtherRuleSources(args, sourceOwnerKinds)
+
+	// Modules include the sources of sub-directories which are not Bazel packages.
+	isModuleRoot := cfg.GenerationMode() == kotlinconfig.GenerationModule && args.Rel == cfg.ModuleRoot()
+
+	walkDir := gazelle.GazelleWalkDir
+	if cfg.FollowSymlinks() {
+		walkDir = func(args language.GenerateArgs, walkFunc gazelle.GazelleWalkFunc) error {
+			return gazelle.GazelleWalkFollowingSymlinks(args, isModuleRoot, walkFunc)
+		}
+	} else if isModuleRoot {
+		walkDir = gazelle.GazelleWalkPackageFiles
+	}
+
+	walkDir(args, func(f string) error {
+		// Sources of nested module roots which are not Bazel packages yet belong to their module.
+		if isModuleRoot && !isInModule(args, cfg.ModuleRoot(), f) {
+			BazelLog.Tracef("SourceFile of a nested module: %s", f)
+			return nil
+		}
+
+		// Marker files such as build.gradle.kts are not sources.
+		if slices.Contains(cfg.ModuleRootMarkers(), path.Base(f)) {
+			return nil
+		}
 
-	gazelle.GazelleWalkDir(args, func(f string) error {
 		// Otherwise the file is either source or potentially importable.
 		if isSourceFileType(f) {
+			if owner, claimed := gazelle.GetSourceOwner(args.Rel, f); claimed && !isKotlinRuleKind(args, owner.Kind) {
+				fmt.Fprintf(os.Stderr, "Source file %q is claimed by %s and is not added to kotlin rules\n", path.Join(args.Rel, f), owner)
+				return nil
+			}
+
 			BazelLog.Tracef("SourceFile: %s", f)
 
+			sourceFiles.Add(f)
+		} else if cfg.JavaSourcesEnabled() && isJavaSourceFileType(f) {
+			if owner, claimed := gazelle.GetSourceOwner(args.Rel, f); claimed && !isKotlinRuleKind(args, owner.Kind) {
+				BazelLog.Tracef("JavaSourceFile claimed by %s: %s", owner, f)
+				return nil
+			}
+
+			BazelLog.Tracef("JavaSourceFile: %s", f)
+
 			sourceFiles.Add(f)
 		}
 
@@ -232,7 +988,38 @@ func (kt *kotlinLang) collectSourceFiles(cfg *kotlinconfig.KotlinConfig, args la
 	return sourceFiles
 }
 
+// Whether a file of the package, relative to the package, belongs to the module
+// and not to a module root nested within the module.
+func isInModule(args language.GenerateArgs, moduleRoot, f string) bool {
+	dir := path.Join(args.Rel, path.Dir(f))
+	if dirCfg, exists := args.Config.Exts[LanguageName].(kotlinconfig.Configs)[dir]; exists {
+		return dirCfg.ModuleRoot() == moduleRoot
+	}
+	return true
+}
+
+// The fully qualified name of a declaration within the package.
+func qualifiedName(pkg, name string) string {
+	if pkg == "" {
+		return name
+	}
+	return pkg + "." + name
+}
+
+func isKotlinRuleKind(args language.GenerateArgs, kind string) bool {
+	for kotlinKind := range kotlinKinds {
+		if kind == kotlinKind || kind == gazelle.MapKind(args, kotlinKind) {
+			return true
+		}
+	}
+	return false
+}
+
 func isSourceFileType(f string) bool {
 	ext := path.Ext(f)
 	return ext == ".kt" || ext == ".kts"
 }
+
+func isJavaSourceFileType(f string) bool {
+	return path.Ext(f) == ".java"
+}
@@ -0,0 +1,80 @@
+package gazelle
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+// Generate rules for the files and render the result, including the data passed
+// to the resolve phase, as a string.
+func generateToString(t *testing.T, dir string, files []string) string {
+	t.Helper()
+
+	c := config.New()
+	c.RepoRoot = dir
+
+	kt := NewLanguage().(*kotlinLang)
+	kt.Configure(c, "", nil)
+
+	result := kt.GenerateRules(language.GenerateArgs{
+		Config:       c,
+		Dir:          dir,
+		Rel:          "",
+		RegularFiles: files,
+	})
+
+	var s strings.Builder
+	for i, r := range result.Gen {
+		f := rule.EmptyFile("BUILD", "")
+		r.Insert(f)
+		s.Write(f.Format())
+
+		var target KotlinTarget
+		switch imports := result.Imports[i].(type) {
+		case *KotlinLibTarget:
+			target = imports.KotlinTarget
+			fmt.Fprintf(&s, "packages: %v\n", imports.Packages.Values())
+		case *KotlinBinTarget:
+			target = imports.KotlinTarget
+		}
+
+		for _, impt := range target.Imports.Values() {
+			fmt.Fprintf(&s, "import: %s from %s\n", impt.(ImportStatement).Imp, impt.(ImportStatement).SourcePath)
+		}
+	}
+
+	return s.String()
+}
+
+func TestGenerateDeterministic(t *testing.T) {
+	dir := t.TempDir()
+
+	files := make([]string, 0)
+	for i := 0; i < 40; i++ {
+		f := fmt.Sprintf("f%d.kt", i)
+		content := fmt.Sprintf("package p%d\n\nimport shared.Shared\nimport a%d.B\nimport b%d.C\n\nclass F%d\n", i%3, i%5, i%7, i)
+		if i%10 == 0 {
+			content += "\nfun main() {}\n"
+		}
+
+		if err := os.WriteFile(path.Join(dir, f), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, f)
+	}
+
+	expected := generateToString(t, dir, files)
+
+	for i := 0; i < 20; i++ {
+		if actual := generateToString(t, dir, files); actual != expected {
+			t.Fatalf("generation %d differs:\nactual:\n%s\nexpected:\n%s", i, actual, expected)
+		}
+	}
+}
@@ -9,11 +9,29 @@ import (
 type ImportStatement struct {
 	resolve.ImportSpec
 
+	// The fully qualified name of the imported symbol such as a class, or
+	// empty if only the package is known.
+	Symbol string
+
+	// Whether the import is a star import of all symbols of the package
+	IsStar bool
+
+	// Whether the symbol is referenced without an import and may be declared in the
+	// same package by another target. Never reported as unresolved.
+	IsSamePackage bool
+
+	// Whether the symbol is referenced by its fully qualified name without an import, such
+	// as `com.foo.Bar.create()`. Never reported as unresolved.
+	IsQualifiedReference bool
+
 	// The path of the file containing the import
 	SourcePath string
 }
 
-// importStatementComparator compares modules by name.
+// importStatementComparator compares modules by name and imported symbol.
 func importStatementComparator(a, b interface{}) int {
-	return godsutils.StringComparator(a.(ImportStatement).Imp, b.(ImportStatement).Imp)
+	if c := godsutils.StringComparator(a.(ImportStatement).Imp, b.(ImportStatement).Imp); c != 0 {
+		return c
+	}
+	return godsutils.StringComparator(a.(ImportStatement).Symbol, b.(ImportStatement).Symbol)
 }
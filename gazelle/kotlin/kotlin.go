@@ -5,16 +5,35 @@ import (
 	"strings"
 )
 
-import "github.com/emirpasic/gods/sets/treeset"
+import (
+	common "aspect.build/cli/gazelle/common"
+	"aspect.build/cli/gazelle/kotlin/kotlinconfig"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/emirpasic/gods/sets/treeset"
+)
 
 import (
 	jvm_java "github.com/bazel-contrib/rules_jvm/java/gazelle/private/java"
 	jvm_types "github.com/bazel-contrib/rules_jvm/java/gazelle/private/types"
 )
 
+// IsNativeImport returns whether the import is provided by the kotlin or java standard libraries.
 func IsNativeImport(impt string) bool {
-	if strings.HasPrefix(impt, "kotlin.") || strings.HasPrefix(impt, "kotlinx.") {
-		return true
+	return isNativeImport(impt, kotlinconfig.DefaultNativeImports())
+}
+
+// Whether the import is native according to the native import prefixes, where the longest
+// prefix matching the import takes precedence, before falling back to the java standard libraries.
+func isNativeImport(impt string, nativeImports map[string]bool) bool {
+	longest, native := "", false
+	for prefix, isNative := range nativeImports {
+		if (impt == prefix || strings.HasPrefix(impt, prefix+".")) && len(prefix) > len(longest) {
+			longest, native = prefix, isNative
+		}
+	}
+
+	if longest != "" {
+		return native
 	}
 
 	jvm_import := jvm_types.NewPackageName(impt)
@@ -29,6 +48,15 @@ func IsNativeImport(impt string) bool {
 
 type KotlinTarget struct {
 	Imports *treeset.Set
+
+	// The kt_compiler_plugin labels required by the target sources
+	Plugins *treeset.Set
+
+	// The service provider labels required at runtime by the target
+	RuntimeDeps *treeset.Set
+
+	// The labels required by the code generated by annotation processors of the target
+	ProcessorDeps *treeset.Set
 }
 
 /**
@@ -36,21 +64,36 @@ type KotlinTarget struct {
  * - kotlin files
  * - kotlin import statements from all files
  * - kotlin packages implemented
+ * - kotlin top-level declarations
  */
 type KotlinLibTarget struct {
 	KotlinTarget
 
 	Packages *treeset.Set
 	Files    *treeset.Set
+
+	// The fully qualified names of the top-level declarations
+	Declarations *treeset.Set
+
+	// The imported symbols referenced by public declarations
+	ExportedSymbols *treeset.Set
+
+	// The files of the `resources` of the library, relative to the package
+	Resources []string
 }
 
 func NewKotlinLibTarget() *KotlinLibTarget {
 	return &KotlinLibTarget{
 		KotlinTarget: KotlinTarget{
-			Imports: treeset.NewWith(importStatementComparator),
+			Imports:       treeset.NewWith(importStatementComparator),
+			Plugins:       treeset.NewWith(common.LabelComparator),
+			RuntimeDeps:   treeset.NewWith(common.LabelComparator),
+			ProcessorDeps: treeset.NewWith(common.LabelComparator),
 		},
-		Packages: treeset.NewWithStringComparator(),
-		Files:    treeset.NewWithStringComparator(),
+		Packages:        treeset.NewWithStringComparator(),
+		Files:           treeset.NewWithStringComparator(),
+		Declarations:    treeset.NewWithStringComparator(),
+		ExportedSymbols: treeset.NewWithStringComparator(),
 	}
 }
 
@@ -70,20 +113,99 @@ type KotlinBinTarget struct {
 func NewKotlinBinTarget(file, pkg string) *KotlinBinTarget {
 	return &KotlinBinTarget{
 		KotlinTarget: KotlinTarget{
-			Imports: treeset.NewWith(importStatementComparator),
+			Imports:       treeset.NewWith(importStatementComparator),
+			Plugins:       treeset.NewWith(common.LabelComparator),
+			RuntimeDeps:   treeset.NewWith(common.LabelComparator),
+			ProcessorDeps: treeset.NewWith(common.LabelComparator),
 		},
 		File:    file,
 		Package: pkg,
 	}
 }
 
+/**
+ * Information for kotlin test target including:
+ * - kotlin import statements from the test file
+ * - the package
+ * - the file
+ */
+type KotlinTestTarget struct {
+	KotlinTarget
+
+	File    string
+	Package string
+
+	// The test frameworks imported by the test, see kotlinconfig.TestFrameworks
+	Frameworks *treeset.Set
+}
+
+func NewKotlinTestTarget(file, pkg string) *KotlinTestTarget {
+	return &KotlinTestTarget{
+		KotlinTarget: KotlinTarget{
+			Imports:       treeset.NewWith(importStatementComparator),
+			Plugins:       treeset.NewWith(common.LabelComparator),
+			RuntimeDeps:   treeset.NewWith(common.LabelComparator),
+			ProcessorDeps: treeset.NewWith(common.LabelComparator),
+		},
+		File:       file,
+		Package:    pkg,
+		Frameworks: treeset.NewWithStringComparator(),
+	}
+}
+
+// compileOnlyDepsAttr is the attribute of the dependencies of imports marked compile-only
+// without a replacement label, supported by macros wrapping the kotlin rules.
+const compileOnlyDepsAttr = "compile_only_deps"
+
 // packagesKey is the name of a private attribute set on generated kt_library
 // rules. This attribute contains the KotlinTarget for the target.
 const packagesKey = "_kotlin_package"
 
+// existingDepsKey is the name of a private attribute set on generated rules containing
+// the `deps` of the existing rule, preserved for preservedDepsKinds unless strict deps is enabled.
+const existingDepsKey = "_kotlin_existing_deps"
+
+// The kinds of rules whose existing `deps` are preserved unless strict deps is enabled.
+// The `deps` of libraries are always replaced by the resolved deps.
+var preservedDepsKinds = treeset.NewWithStringComparator(KtJvmBinary, KtJvmTest)
+
+// The name of the library of the sources of the key according to the generation mode,
+// see libraryKey. Libraries of directories, modules and packages of directories providing
+// a single package use the default name of the directory.
+func toLibraryTargetName(cfg *kotlinconfig.KotlinConfig, args language.GenerateArgs, key string, libraryCount int) string {
+	if key != "" {
+		switch cfg.GenerationMode() {
+		case kotlinconfig.GenerationPackage:
+			if libraryCount > 1 {
+				return strings.ReplaceAll(key, ".", "_")
+			}
+		case kotlinconfig.GenerationFile:
+			return strings.ToLower(strings.TrimSuffix(path.Base(key), path.Ext(key)))
+		}
+	}
+
+	return common.ToDefaultTargetName(args, "root")
+}
+
 func toBinaryTargetName(mainFile string) string {
 	base := strings.ToLower(strings.TrimSuffix(path.Base(mainFile), path.Ext(mainFile)))
 
 	// TODO: move target name template to directive
 	return base + "_bin"
 }
+
+func toTestTargetName(testFile string) string {
+	base := strings.ToLower(strings.TrimSuffix(path.Base(testFile), path.Ext(testFile)))
+	base = strings.TrimSuffix(strings.TrimSuffix(base, "test"), "_")
+	if base == "" {
+		base = "test"
+	}
+
+	// TODO: move target name template to directive
+	return base + "_test"
+}
+
+func toLintTargetName(libTargetName string) string {
+	// TODO: move target name template to directive
+	return libTargetName + "_lint"
+}
@@ -2,6 +2,8 @@ package gazelle
 
 import (
 	"testing"
+
+	"aspect.build/cli/gazelle/kotlin/kotlinconfig"
 )
 
 func assertTrue(t *testing.T, b bool, msg string) {
@@ -24,4 +26,19 @@ func TestKotlinNative(t *testing.T) {
 		assertTrue(t, IsNativeImport("javax.xml"), "javax should be native")
 		assertTrue(t, IsNativeImport("org.xml.sax"), "org.xml.sax should be native")
 	})
+
+	t.Run("configured native prefixes", func(t *testing.T) {
+		nativeImports := kotlinconfig.DefaultNativeImports()
+		nativeImports["kotlinx.coroutines"] = false
+		nativeImports["com.mycorp.provided"] = true
+		nativeImports["javax.sql"] = false
+
+		assertTrue(t, isNativeImport("kotlinx.serialization", nativeImports), "kotlinx.* should be native")
+		assertTrue(t, !isNativeImport("kotlinx.coroutines", nativeImports), "kotlinx.coroutines should not be native")
+		assertTrue(t, !isNativeImport("kotlinx.coroutines.flow", nativeImports), "kotlinx.coroutines.* should not be native")
+		assertTrue(t, isNativeImport("com.mycorp.provided.api", nativeImports), "com.mycorp.provided.* should be native")
+		assertTrue(t, !isNativeImport("com.mycorp.providedx", nativeImports), "only complete package names should match")
+		assertTrue(t, !isNativeImport("javax.sql", nativeImports), "javax.sql should not be native")
+		assertTrue(t, isNativeImport("javax.net", nativeImports), "javax.net should be native")
+	})
 }
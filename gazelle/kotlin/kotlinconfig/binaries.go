@@ -0,0 +1,20 @@
+package kotlinconfig
+
+// SetMainClass pins the main_class of the binary generated for a source file,
+// identified by its path relative to the repository root.
+func (c *KotlinConfig) SetMainClass(file, mainClass string) {
+	// Copy the main classes of the parent before modifying.
+	mainClasses := make(map[string]string, len(c.mainClasses)+1)
+	for k, v := range c.mainClasses {
+		mainClasses[k] = v
+	}
+	mainClasses[file] = mainClass
+	c.mainClasses = mainClasses
+}
+
+// MainClass returns the pinned main_class of the binary generated for a source file,
+// identified by its path relative to the repository root, if any.
+func (c *KotlinConfig) MainClass(file string) (string, bool) {
+	mainClass, found := c.mainClasses[file]
+	return mainClass, found
+}
@@ -1,12 +1,361 @@
 package kotlinconfig
 
 import (
-	"path/filepath"
+	"path"
+	"slices"
+	"strings"
 
 	"github.com/bazel-contrib/rules_jvm/java/gazelle/javaconfig"
+	"github.com/bazelbuild/bazel-gazelle/label"
 )
 
-const Directive_KotlinExtension = "kotlin"
+const (
+	Directive_KotlinExtension = "kotlin"
+
+	// Directive_JavaSources controls whether .java files in a directory are
+	// included in the generated kt_jvm_library along with the Kotlin sources.
+	// Can be either "enabled" or "disabled". Defaults to "disabled".
+	Directive_JavaSources = "kotlin_java_sources"
+
+	// Directive_CompilerPlugin configures the kt_compiler_plugin target for a
+	// compiler plugin, added to the `plugins` of rules using the plugin annotations.
+	// Format: `<plugin-id> <label> [annotation...]`. Custom plugins must declare
+	// the fully qualified annotations which require the plugin.
+	Directive_CompilerPlugin = "kotlin_compiler_plugin"
+
+	// Directive_AnnotationProcessor maps an annotation to the annotation processor
+	// processing it, such as a java_plugin or kt_ksp_plugin target, added to the `plugins`
+	// of rules using the annotation, and to the dependencies required by the generated code
+	// added to the `deps` of these rules.
+	// Format: `<annotation> <plugin label> [dep label...]`. May be repeated, an empty
+	// value removes all inherited mappings.
+	Directive_AnnotationProcessor = "kotlin_annotation_processor"
+
+	// Directive_Tags represents a comma separated list of tags added to all
+	// rules generated within the directory and sub-directories. Other tags already
+	// present on existing rules are preserved, tags no longer configured by the
+	// directive are removed unless marked `# keep`.
+	Directive_Tags = "kotlin_tags"
+
+	// Directive_ServiceProvider maps a java.util.ServiceLoader service to a
+	// library providing an implementation, added to the `runtime_deps` of rules
+	// loading the service or declaring it in META-INF/services.
+	// Format: `<service> <label>`. May be repeated for multiple providers.
+	Directive_ServiceProvider = "kotlin_service_provider"
+
+	// Directive_ModuleName sets the `module_name` of generated rules. The value
+	// is a template supporting the {dirname} and {package} variables. An empty
+	// value disables generating `module_name`.
+	Directive_ModuleName = "kotlin_module_name"
+
+	// Directive_Associates controls whether generated binaries and tests are associated
+	// with the library of the same package, giving them access to the
+	// `internal` declarations of the library.
+	// Can be either "enabled" or "disabled". Defaults to "disabled".
+	Directive_Associates = "kotlin_associates"
+
+	// Directive_Lint controls whether a ktlint_test rule linting the sources
+	// is generated for each kt_jvm_library. Other lint macros such as detekt
+	// can be used by mapping the kind with the `map_kind` directive.
+	// Can be either "enabled" or "disabled". Defaults to "disabled".
+	Directive_Lint = "kotlin_lint"
+
+	// Directive_LintConfig sets the `config` label of generated lint rules,
+	// such as an .editorconfig file.
+	Directive_LintConfig = "kotlin_lint_config"
+
+	// Directive_Cleanup controls whether existing kotlin rules are removed from
+	// directories where generation is disabled using the `kotlin` directive.
+	// Can be either "enabled" or "disabled". Defaults to "disabled".
+	Directive_Cleanup = "kotlin_cleanup"
+
+	// Directive_Data represents a glob pattern of files added to the `data` of
+	// generated binaries and tests. Patterns are relative to each BUILD file. May be repeated,
+	// when specified the inherited patterns are replaced. An empty value removes
+	// all inherited patterns.
+	Directive_Data = "kotlin_data"
+
+	// Directive_Resources represents a glob pattern of files added to the `resources` of
+	// generated libraries. Patterns are relative to each BUILD file. May be repeated, when
+	// specified the inherited patterns are replaced. An empty value removes all inherited
+	// patterns.
+	Directive_Resources = "kotlin_resources"
+
+	// Directive_ResourceStripPrefix sets the `resource_strip_prefix` of generated libraries
+	// with resources, such as `src/main/resources`, relative to each BUILD file.
+	// An empty value removes the inherited prefix.
+	Directive_ResourceStripPrefix = "kotlin_resource_strip_prefix"
+
+	// Directive_NameCollision controls what happens when a generated rule name
+	// collides with an existing rule of a different kind.
+	// Can be either "error" or "rename". Defaults to "error".
+	Directive_NameCollision = "kotlin_name_collision"
+
+	// Directive_GenerateTests controls whether a kt_jvm_test rule is generated for
+	// each test file, such as `FooTest.kt`. When disabled test files are included
+	// in the kt_jvm_library of the directory.
+	// Can be either "enabled" or "disabled". Defaults to "disabled".
+	Directive_GenerateTests = "kotlin_generate_tests"
+
+	// Directive_MainClass pins the main_class of the kt_jvm_binary generated for a source
+	// file, for entrypoints whose class name can not be inferred, such as files annotated
+	// with `@file:JvmName`. Format: `<file> <main class>`, the file relative to the directory.
+	// Replaces the main_class of existing binaries, which is otherwise preserved.
+	Directive_MainClass = "kotlin_main_class"
+
+	// Directive_GenerateLibraries controls whether kt_jvm_library rules are generated.
+	// When disabled library sources are not added to any rule, and existing libraries
+	// are only removed if cleanup is enabled.
+	// Can be either "enabled" or "disabled". Defaults to "enabled".
+	Directive_GenerateLibraries = "kotlin_generate_libraries"
+
+	// Directive_TestSources marks the directory and sub-directories as test sources, such
+	// as integration test trees, classifying all files as tests regardless of their names.
+	// Generated libraries and binaries are `testonly` and only used by tests when inferring
+	// `testonly`. Can be either "enabled" or "true", or "disabled" or "false".
+	// Defaults to "disabled".
+	Directive_TestSources = "kotlin_test_srcs"
+
+	// Directive_Testonly marks all rules generated within the directory and sub-directories
+	// as `testonly`, such as test utilities under //testing/..., so production code can
+	// not depend upon them. Libraries are not inferred as `testonly` when enabled.
+	// Can be either "true" or "false". Defaults to "false".
+	Directive_Testonly = "kotlin_testonly"
+
+	// Directive_GenerateBinaries controls whether a kt_jvm_binary rule is generated for
+	// each file declaring a `main` function. When disabled these files are included in
+	// the kt_jvm_library of the directory.
+	// Can be either "enabled" or "disabled". Defaults to "enabled".
+	Directive_GenerateBinaries = "kotlin_generate_binaries"
+
+	// Directive_InferTestonly controls whether generated libraries only depended
+	// upon by kt_jvm_test rules are marked `testonly`.
+	// Can be either "enabled" or "disabled". Defaults to "disabled".
+	Directive_InferTestonly = "kotlin_infer_testonly"
+
+	// Directive_StrictDeps controls whether existing `deps` of binaries and tests not
+	// required by the imports of the sources are removed. Deps marked `# keep` are never
+	// removed. The `deps` of libraries are always replaced by the resolved deps.
+	// Can be either "enabled" or "disabled". Defaults to "disabled".
+	Directive_StrictDeps = "kotlin_strict_deps"
+
+	// Directive_ValidateImportStatements controls what happens when an import
+	// can not be resolved. Can be either "error", "warn" or "off". Defaults to "warn".
+	// With "error" the run fails with a non-zero code once the BUILD files are written.
+	Directive_ValidateImportStatements = "kotlin_validate_import_statements"
+
+	// Directive_ValidateTestonly controls what happens when a rule that is not test
+	// only depends upon a `testonly` target, which bazel rejects.
+	// Can be either "error", "warn" or "off". Defaults to "warn".
+	// With "error" the run fails with a non-zero code once the BUILD files are written.
+	Directive_ValidateTestonly = "kotlin_validate_testonly"
+
+	// Directive_MavenRepository adds a maven_install repository imports are resolved
+	// against. Repositories are queried in the order specified.
+	// Format: `<repository name> <maven_install.json path>`. May be repeated, when
+	// specified the inherited repositories are replaced. An empty value restores the
+	// single repository configured by the java_maven_install_file directive.
+	Directive_MavenRepository = "kotlin_maven_repository"
+
+	// Directive_MavenInstallFile sets the maven_install.json file of the repository named by
+	// the java_maven_repository_name directive that kotlin imports are resolved against, for
+	// subtrees resolving against a different lock file than java. The path is relative to
+	// the repository root. An empty value restores the file of the java_maven_install_file
+	// directive. Ignored when kotlin_maven_repository directives are specified.
+	Directive_MavenInstallFile = "kotlin_maven_install_file"
+
+	// Directive_MavenRepositoryName sets the name of the maven_install repository of the
+	// labels of maven artifacts, such as `@maven//:com_google_guava_guava`, for installs not
+	// named like the java_maven_repository_name. An empty value restores the java name.
+	// Ignored when kotlin_maven_repository directives are specified.
+	Directive_MavenRepositoryName = "kotlin_maven_repository_name"
+
+	// Directive_MavenExcludeArtifact excludes a maven artifact by its `group:artifact`
+	// coordinate from the resolution of kotlin imports, for packages provided by multiple
+	// artifacts. May be repeated, an empty value removes all inherited exclusions.
+	Directive_MavenExcludeArtifact = "kotlin_maven_exclude_artifact"
+
+	// Directive_MavenResolver controls how the maven_install.json files are read.
+	// "rules_jvm" uses the resolver of the rules_jvm java extension, "builtin" reads
+	// the maven_install.json files directly without any rules_jvm setup.
+	// Can be either "rules_jvm" or "builtin". Defaults to "rules_jvm".
+	Directive_MavenResolver = "kotlin_maven_resolver"
+
+	// Directive_GeneratedImport maps imports of code generated at build time, such as
+	// `DaggerAppComponent`, to the target generating the code. `self` maps the imports
+	// to the importing target itself, adding no dependency.
+	// Format: `<pattern> <label>|self`. May be repeated, the first matching pattern is used.
+	Directive_GeneratedImport = "kotlin_generated_import"
+
+	// Directive_ResolveRegexp resolves all imports matching a regular expression to a
+	// label, such as `com\.mycorp\.legacy\..*`. The expression must match the complete
+	// import. Format: `<regexp> <label>`. May be repeated, the first matching expression is used.
+	Directive_ResolveRegexp = "kotlin_resolve_regexp"
+
+	// Directive_NativeImport marks a package prefix, including sub-packages, as provided by
+	// the standard libraries so imports are never resolved to a dependency. A `!` prefix
+	// such as `!kotlinx.coroutines` resolves the package normally. The longest matching
+	// prefix takes precedence. Defaults to `kotlin`, `kotlinx` and the java standard libraries.
+	Directive_NativeImport = "kotlin_native_import"
+
+	// Directive_ExtraDeps adds dependencies to the `deps` of all rules generated within
+	// the directory and sub-directories, merged with the resolved dependencies.
+	// Format: `[kind] <label>...`, the kind such as `kt_jvm_test` restricts the
+	// dependencies to rules of the kind. May be repeated, an empty value removes all
+	// inherited dependencies.
+	Directive_ExtraDeps = "kotlin_extra_deps"
+
+	// Directive_CompileOnly marks imports only required at compile time, such as
+	// annotations or APIs provided at runtime. The resolved dependency is replaced by
+	// the label, such as a `neverlink` wrapper of the library, or moved to the
+	// `compile_only_deps` attribute when no label is specified.
+	// Format: `<pattern> [label]`. May be repeated, the first matching pattern is used.
+	Directive_CompileOnly = "kotlin_compile_only"
+
+	// Directive_PackageFallbackDepth sets how many parent packages of an import not
+	// provided by any rule are searched, such as `com.foo` for `com.foo.bar.Baz` with
+	// a depth of 1. Defaults to 0, never resolving imports to parent packages.
+	Directive_PackageFallbackDepth = "kotlin_package_fallback_depth"
+
+	// Directive_PreferProvider sets the targets preferred when an import is provided by
+	// multiple targets equally close to the importing target, in order of preference.
+	// Format: `<label>...`. May be repeated, an empty value removes all inherited targets.
+	Directive_PreferProvider = "kotlin_prefer_provider"
+
+	// Directive_FallbackDep sets the dependency imports of the directory and sub-directories
+	// not found in any rule, standard library or maven repository resolve to, such as an
+	// uber-target of a monolithic jar during an incremental migration. Imports resolved to
+	// the fallback are listed in the unresolved import report. An empty value removes the fallback.
+	Directive_FallbackDep = "kotlin_fallback_dep"
+
+	// Directive_ResolutionTrace controls whether every resolution step of the imports of
+	// rules within the directory and sub-directories is printed, with timings.
+	// Can be either "enabled" or "disabled". Defaults to "disabled".
+	Directive_ResolutionTrace = "kotlin_resolution_trace"
+
+	// Directive_FollowSymlinks controls whether the source files of symlinked directories
+	// within the directory and sub-directories are collected as if they were sub-directories,
+	// such as generated or vendored sources symlinked into place. Directories already visited
+	// and Bazel packages are skipped. Symlinked files are always collected.
+	// Can be either "enabled" or "disabled". Defaults to "disabled".
+	Directive_FollowSymlinks = "kotlin_follow_symlinks"
+
+	// Directive_SymbolIndex adds an index file of the symbols of a source tree written by
+	// the resolvedump tool, relative to the repository root, which imports not provided by
+	// any rule are resolved against. May be repeated, an empty value removes all inherited
+	// index files.
+	Directive_SymbolIndex = "kotlin_symbol_index"
+
+	// Directive_LabelRewrite rewrites the prefix of resolved dependency labels, such as
+	// `//third_party/` to `@vendored//`, for repositories aliasing or re-exporting targets.
+	// Format: `<prefix> <replacement>`. May be repeated, the first matching prefix is used.
+	// An empty value removes all inherited rewrites.
+	Directive_LabelRewrite = "kotlin_label_rewrite"
+
+	// Directive_TestFileSuffixes sets the suffixes or glob patterns of the names of test
+	// files, such as `Test.kt` or `*Spec.kt`, replacing the inherited suffixes. An empty
+	// value restores the default `*Test.kt`.
+	Directive_TestFileSuffixes = "kotlin_test_file_suffixes"
+
+	// Directive_TestFrameworkDeps adds dependencies to the generated kt_jvm_test rules using
+	// a test framework, such as a test engine or runner, detected from the imports of tests.
+	// Format: `<framework> <label>...` where the framework is one of "junit4", "junit5",
+	// "kotlin-test", "kotest", "testng" or "spek". May be repeated, an empty value
+	// removes all inherited dependencies.
+	Directive_TestFrameworkDeps = "kotlin_test_framework_deps"
+
+	// Directive_GenerationMode controls the granularity of the generated libraries.
+	// Can be "directory" for a library per directory, "package" for a library per kotlin
+	// package of each directory, "module" for a library in the directory of the directive
+	// including the sources of sub-directories which are not Bazel packages, or "file"
+	// for a library per source file. Defaults to "directory".
+	Directive_GenerationMode = "kotlin_generation_mode"
+
+	// Directive_LanguageVersion sets the kotlin language version of the sources of the
+	// directory and sub-directories, such as "1.9". Sources are parsed according to the
+	// version, and the `language_version` attribute of generated rules is set when specified,
+	// for macros forwarding it to the kotlinc options. An empty value removes the version.
+	Directive_LanguageVersion = "kotlin_language_version"
+
+	// Directive_ApiVersion sets the `api_version` attribute of generated rules, such as "1.9".
+	// An empty value removes the version.
+	Directive_ApiVersion = "kotlin_api_version"
+
+	// Directive_ModuleRoot declares the directory as a module root of the "module"
+	// generation mode, generating a library including the sources of the sub-directories
+	// which are not Bazel packages or other module roots. Takes no value.
+	Directive_ModuleRoot = "kotlin_module_root"
+
+	// Directive_ModuleRootMarkers declares the directories of the directory and
+	// sub-directories containing any of the files, such as `build.gradle.kts`, as module
+	// roots like the kotlin_module_root directive. An empty value removes the markers.
+	Directive_ModuleRootMarkers = "kotlin_module_root_markers"
+)
+
+// NameCollisionMode represents what should happen when a generated rule name
+// collides with an existing rule of a different kind.
+type NameCollisionMode int
+
+const (
+	// NameCollisionError has gazelle produce an error when names collide.
+	NameCollisionError NameCollisionMode = iota
+	// NameCollisionRename has gazelle generate the rule with a suffixed name.
+	NameCollisionRename
+)
+
+// GenerationMode represents the granularity of the generated libraries.
+type GenerationMode int
+
+const (
+	// GenerationDirectory has gazelle generate a library per directory.
+	GenerationDirectory GenerationMode = iota
+	// GenerationPackage has gazelle generate a library per kotlin package of each directory.
+	GenerationPackage
+	// GenerationModule has gazelle generate a library in the module root directory including
+	// the sources of sub-directories which are not Bazel packages.
+	GenerationModule
+	// GenerationFile has gazelle generate a library per source file.
+	GenerationFile
+)
+
+// ValidationMode represents what should happen when an import can not be resolved.
+type ValidationMode int
+
+const (
+	// ValidationError has gazelle report the import and fail once all rules are resolved.
+	ValidationError ValidationMode = iota
+	// ValidationWarn has gazelle report the import.
+	ValidationWarn
+	// ValidationOff has gazelle ignore the import silently.
+	ValidationOff
+)
+
+// MavenResolverMode represents how the maven_install.json files are read.
+type MavenResolverMode int
+
+const (
+	// MavenResolverRulesJvm uses the resolver of the rules_jvm java extension.
+	MavenResolverRulesJvm MavenResolverMode = iota
+	// MavenResolverBuiltin reads the maven_install.json files directly.
+	MavenResolverBuiltin
+)
+
+// MavenRepository represents a maven_install repository and its lock file.
+type MavenRepository struct {
+	// The name of the repository, such as "maven"
+	Name string
+
+	// The path of the maven_install.json file
+	InstallFile string
+}
+
+const (
+	// The directory name of the Bazel package.
+	ModuleNameDirnameVar = "{dirname}"
+
+	// The path of the Bazel package with "/" replaced by "_".
+	ModuleNamePackageVar = "{package}"
+)
 
 type KotlinConfig struct {
 	*javaconfig.Config
@@ -14,19 +363,176 @@ type KotlinConfig struct {
 	parent *KotlinConfig
 	rel    string
 
-	generationEnabled bool
+	generationEnabled  bool
+	javaSourcesEnabled bool
+	associatesEnabled  bool
+	lintEnabled        bool
+	cleanupEnabled     bool
+	generateTests      bool
+	generateLibraries  bool
+	generateBinaries   bool
+	testSources        bool
+	testonly           bool
+	inferTestonly      bool
+	strictDeps         bool
+	resolutionTrace    bool
+	followSymlinks     bool
+
+	lintConfig *label.Label
+
+	// The dependency of imports not found, if any
+	fallbackDep *label.Label
+
+	moduleName string
+
+	// The kotlin language and API versions, if set
+	languageVersion string
+	apiVersion      string
+
+	nameCollision NameCollisionMode
+
+	generationMode GenerationMode
+
+	// The directory of the kotlin_generation_mode directive enabling the module mode,
+	// or of the closest kotlin_module_root directive or marker file
+	moduleRoot string
+
+	// The names of the files marking module roots
+	moduleRootMarkers []string
+
+	validateImportStatements ValidationMode
+	validateTestonly         ValidationMode
+	packageFallbackDepth     int
+
+	mavenResolver MavenResolverMode
+
+	// Compiler plugins by id, copied on write
+	compilerPlugins map[string]*CompilerPlugin
+
+	// The annotation processors of annotations, in order. Copied on write
+	annotationProcessors []AnnotationProcessor
+
+	tags []string
+
+	// All tags configured in the directory or parent directories, including
+	// tags since replaced. Copied on write.
+	managedTags []string
+
+	dataPatterns []string
+
+	resourcePatterns    []string
+	resourceStripPrefix string
+
+	// The glob patterns of the names of test files
+	testFilePatterns []string
+
+	// The dependencies of tests by test framework, copied on write
+	testFrameworkDeps map[string][]label.Label
+
+	// The maven_install repositories imports are resolved against, in order
+	mavenRepositories []MavenRepository
+
+	// The absolute path of the maven_install.json file overriding the java_maven_install_file
+	mavenInstallFile string
+
+	// The name of the maven_install repository overriding the java_maven_repository_name
+	mavenRepositoryName string
+
+	// The `group:artifact` coordinates of the excluded maven artifacts, copied on write
+	mavenExcludedArtifacts []string
+
+	// The mappings of imports of generated code, in order. Copied on write
+	generatedImports []GeneratedImport
+
+	// The regular expressions resolving imports, in order. Copied on write
+	resolveRegexps []ResolveRegexp
+
+	// Whether imports of each package prefix are native, copied on write
+	nativeImports map[string]bool
+
+	// The dependencies added to generated rules by kind, "" for all kinds. Copied on write
+	extraDeps map[string][]label.Label
+
+	// The patterns of compile-only imports, in order. Copied on write
+	compileOnlyImports []CompileOnlyImport
+
+	// The targets preferred when multiple targets provide an import, in order. Copied on write
+	preferredProviders []label.Label
+
+	// The rewrites of resolved dependency labels, in order. Copied on write
+	labelRewrites []LabelRewrite
+
+	// The absolute paths of the symbol index files, in order. Copied on write
+	symbolIndexes []string
+
+	// Service provider libraries by fully qualified service name, copied on write
+	serviceProviders map[string][]label.Label
+
+	// The pinned main classes of binaries by source file relative to the repository root, copied on write
+	mainClasses map[string]string
 }
 
 type Configs = map[string]*KotlinConfig
 
 func New(repoRoot string) *KotlinConfig {
 	return &KotlinConfig{
-		Config:            javaconfig.New(repoRoot),
-		generationEnabled: true,
-		parent:            nil,
+		Config:                   javaconfig.New(repoRoot),
+		generationEnabled:        true,
+		javaSourcesEnabled:       false,
+		associatesEnabled:        false,
+		lintEnabled:              false,
+		cleanupEnabled:           false,
+		generateTests:            false,
+		generateLibraries:        true,
+		generateBinaries:         true,
+		testSources:              false,
+		testonly:                 false,
+		inferTestonly:            false,
+		strictDeps:               false,
+		resolutionTrace:          false,
+		followSymlinks:           false,
+		lintConfig:               nil,
+		fallbackDep:              nil,
+		moduleName:               "",
+		languageVersion:          "",
+		apiVersion:               "",
+		nameCollision:            NameCollisionError,
+		generationMode:           GenerationDirectory,
+		moduleRoot:               "",
+		moduleRootMarkers:        []string{},
+		validateImportStatements: ValidationWarn,
+		validateTestonly:         ValidationWarn,
+		packageFallbackDepth:     0,
+		mavenResolver:            MavenResolverRulesJvm,
+		compilerPlugins:          newCompilerPlugins(),
+		annotationProcessors:     []AnnotationProcessor{},
+		tags:                     []string{},
+		managedTags:              []string{},
+		dataPatterns:             []string{},
+		resourcePatterns:         []string{},
+		resourceStripPrefix:      "",
+		testFilePatterns:         DefaultTestFilePatterns(),
+		testFrameworkDeps:        make(map[string][]label.Label),
+		mavenRepositories:        []MavenRepository{},
+		mavenInstallFile:         "",
+		mavenRepositoryName:      "",
+		mavenExcludedArtifacts:   []string{},
+		generatedImports:         []GeneratedImport{},
+		resolveRegexps:           []ResolveRegexp{},
+		nativeImports:            DefaultNativeImports(),
+		extraDeps:                make(map[string][]label.Label),
+		compileOnlyImports:       []CompileOnlyImport{},
+		preferredProviders:       []label.Label{},
+		labelRewrites:            []LabelRewrite{},
+		symbolIndexes:            []string{},
+		serviceProviders:         make(map[string][]label.Label),
+		mainClasses:              make(map[string]string),
+		parent:                   nil,
 	}
 }
 
+// NewChild returns the Config of a sub-directory inheriting all directives of the Config.
+// Slices and maps are shared with the parent and copied before being modified.
 func (c *KotlinConfig) NewChild(childPath string) *KotlinConfig {
 	cCopy := *c
 	cCopy.Config = c.Config.NewChild()
@@ -45,12 +551,407 @@ func (c *KotlinConfig) GenerationEnabled() bool {
 	return c.generationEnabled
 }
 
-// ParentForPackage returns the parent Config for the given Bazel package.
+// SetCleanupEnabled sets whether existing rules are removed when generation is disabled.
+func (c *KotlinConfig) SetCleanupEnabled(enabled bool) {
+	c.cleanupEnabled = enabled
+}
+
+// CleanupEnabled returns whether existing rules are removed when generation is disabled.
+func (c *KotlinConfig) CleanupEnabled() bool {
+	return c.cleanupEnabled
+}
+
+// SetJavaSourcesEnabled sets whether .java files are included in the
+// generated kt_jvm_library.
+func (c *KotlinConfig) SetJavaSourcesEnabled(enabled bool) {
+	c.javaSourcesEnabled = enabled
+}
+
+// JavaSourcesEnabled returns whether .java files are included in the
+// generated kt_jvm_library.
+func (c *KotlinConfig) JavaSourcesEnabled() bool {
+	return c.javaSourcesEnabled
+}
+
+// SetModuleName sets the template of the `module_name` of generated rules.
+func (c *KotlinConfig) SetModuleName(moduleName string) {
+	c.moduleName = moduleName
+}
+
+// RenderModuleName returns the `module_name` of rules generated in the
+// directory, or an empty string if `module_name` is not generated.
+func (c *KotlinConfig) RenderModuleName(dirname string) string {
+	moduleName := strings.ReplaceAll(c.moduleName, ModuleNameDirnameVar, dirname)
+	return strings.ReplaceAll(moduleName, ModuleNamePackageVar, strings.ReplaceAll(c.rel, "/", "_"))
+}
+
+// SetAssociatesEnabled sets whether binaries are associated with the library
+// of the same package.
+func (c *KotlinConfig) SetAssociatesEnabled(enabled bool) {
+	c.associatesEnabled = enabled
+}
+
+// AssociatesEnabled returns whether binaries are associated with the library
+// of the same package.
+func (c *KotlinConfig) AssociatesEnabled() bool {
+	return c.associatesEnabled
+}
+
+// SetNameCollision sets the NameCollisionMode for generated rule names.
+func (c *KotlinConfig) SetNameCollision(mode NameCollisionMode) {
+	c.nameCollision = mode
+}
+
+// NameCollision returns the NameCollisionMode for generated rule names.
+func (c *KotlinConfig) NameCollision() NameCollisionMode {
+	return c.nameCollision
+}
+
+// SetGenerateTests sets whether kt_jvm_test rules are generated for test files.
+func (c *KotlinConfig) SetGenerateTests(enabled bool) {
+	c.generateTests = enabled
+}
+
+// GenerateTests returns whether kt_jvm_test rules are generated for test files.
+func (c *KotlinConfig) GenerateTests() bool {
+	return c.generateTests
+}
+
+// SetGenerateLibraries sets whether kt_jvm_library rules are generated.
+func (c *KotlinConfig) SetGenerateLibraries(enabled bool) {
+	c.generateLibraries = enabled
+}
+
+// GenerateLibraries returns whether kt_jvm_library rules are generated.
+func (c *KotlinConfig) GenerateLibraries() bool {
+	return c.generateLibraries
+}
+
+// SetGenerateBinaries sets whether kt_jvm_binary rules are generated for files declaring `main`.
+func (c *KotlinConfig) SetGenerateBinaries(enabled bool) {
+	c.generateBinaries = enabled
+}
+
+// GenerateBinaries returns whether kt_jvm_binary rules are generated for files declaring `main`.
+func (c *KotlinConfig) GenerateBinaries() bool {
+	return c.generateBinaries
+}
+
+// SetTestSources sets whether all sources of the directory are test sources.
+func (c *KotlinConfig) SetTestSources(enabled bool) {
+	c.testSources = enabled
+}
+
+// TestSources returns whether all sources of the directory are test sources.
+func (c *KotlinConfig) TestSources() bool {
+	return c.testSources
+}
+
+// SetTestonly sets whether all rules generated in the directory are `testonly`.
+func (c *KotlinConfig) SetTestonly(testonly bool) {
+	c.testonly = testonly
+}
+
+// Testonly returns whether all rules generated in the directory are `testonly`,
+// including the rules of test sources.
+func (c *KotlinConfig) Testonly() bool {
+	return c.testonly || c.testSources
+}
+
+// SetGenerationMode sets the GenerationMode of the directory and sub-directories.
+// The directory is the module root of the GenerationModule mode.
+func (c *KotlinConfig) SetGenerationMode(mode GenerationMode) {
+	c.generationMode = mode
+	c.moduleRoot = c.rel
+}
+
+// GenerationMode returns the GenerationMode of the directory.
+func (c *KotlinConfig) GenerationMode() GenerationMode {
+	return c.generationMode
+}
+
+// ModuleRoot returns the directory sources are collected into in the GenerationModule mode.
+func (c *KotlinConfig) ModuleRoot() string {
+	return c.moduleRoot
+}
+
+// SetModuleRoot declares the directory as the module root of the directory and sub-directories.
+func (c *KotlinConfig) SetModuleRoot() {
+	c.moduleRoot = c.rel
+}
+
+// SetModuleRootMarkers sets the names of the files marking module roots.
+func (c *KotlinConfig) SetModuleRootMarkers(markers []string) {
+	c.moduleRootMarkers = markers
+}
+
+// ModuleRootMarkers returns the names of the files marking module roots.
+func (c *KotlinConfig) ModuleRootMarkers() []string {
+	return c.moduleRootMarkers
+}
+
+// SetValidateImportStatements sets the ValidationMode for imports that can not be resolved.
+func (c *KotlinConfig) SetValidateImportStatements(mode ValidationMode) {
+	c.validateImportStatements = mode
+}
+
+// ValidateImportStatements returns the ValidationMode for imports that can not be resolved.
+func (c *KotlinConfig) ValidateImportStatements() ValidationMode {
+	return c.validateImportStatements
+}
+
+// SetValidateTestonly sets the ValidationMode for dependencies upon testonly targets.
+func (c *KotlinConfig) SetValidateTestonly(mode ValidationMode) {
+	c.validateTestonly = mode
+}
+
+// ValidateTestonly returns the ValidationMode for dependencies upon testonly targets.
+func (c *KotlinConfig) ValidateTestonly() ValidationMode {
+	return c.validateTestonly
+}
+
+// SetPackageFallbackDepth sets how many parent packages of unresolved imports are searched.
+func (c *KotlinConfig) SetPackageFallbackDepth(depth int) {
+	c.packageFallbackDepth = depth
+}
+
+// PackageFallbackDepth returns how many parent packages of unresolved imports are searched.
+func (c *KotlinConfig) PackageFallbackDepth() int {
+	return c.packageFallbackDepth
+}
+
+// SetInferTestonly sets whether libraries only used by tests are marked `testonly`.
+func (c *KotlinConfig) SetInferTestonly(enabled bool) {
+	c.inferTestonly = enabled
+}
+
+// InferTestonly returns whether libraries only used by tests are marked `testonly`.
+func (c *KotlinConfig) InferTestonly() bool {
+	return c.inferTestonly
+}
+
+// SetStrictDeps sets whether existing deps not required by imports are removed.
+func (c *KotlinConfig) SetStrictDeps(enabled bool) {
+	c.strictDeps = enabled
+}
+
+// StrictDeps returns whether existing deps not required by imports are removed.
+func (c *KotlinConfig) StrictDeps() bool {
+	return c.strictDeps
+}
+
+// SetResolutionTrace sets whether the resolution steps of imports are printed.
+func (c *KotlinConfig) SetResolutionTrace(enabled bool) {
+	c.resolutionTrace = enabled
+}
+
+// ResolutionTrace returns whether the resolution steps of imports are printed.
+func (c *KotlinConfig) ResolutionTrace() bool {
+	return c.resolutionTrace
+}
+
+// SetFollowSymlinks sets whether the source files of symlinked directories are collected.
+func (c *KotlinConfig) SetFollowSymlinks(enabled bool) {
+	c.followSymlinks = enabled
+}
+
+// FollowSymlinks returns whether the source files of symlinked directories are collected.
+func (c *KotlinConfig) FollowSymlinks() bool {
+	return c.followSymlinks
+}
+
+// SetLintEnabled sets whether lint rules are generated for libraries.
+func (c *KotlinConfig) SetLintEnabled(enabled bool) {
+	c.lintEnabled = enabled
+}
+
+// LintEnabled returns whether lint rules are generated for libraries.
+func (c *KotlinConfig) LintEnabled() bool {
+	return c.lintEnabled
+}
+
+// SetLanguageVersion sets the kotlin language version of the sources, "" if unset.
+func (c *KotlinConfig) SetLanguageVersion(version string) {
+	c.languageVersion = version
+}
+
+// LanguageVersion returns the kotlin language version of the sources, if set.
+func (c *KotlinConfig) LanguageVersion() string {
+	return c.languageVersion
+}
+
+// SetApiVersion sets the kotlin API version of generated rules, "" if unset.
+func (c *KotlinConfig) SetApiVersion(version string) {
+	c.apiVersion = version
+}
+
+// ApiVersion returns the kotlin API version of generated rules, if set.
+func (c *KotlinConfig) ApiVersion() string {
+	return c.apiVersion
+}
+
+// SetFallbackDep sets the dependency of imports not found, nil to leave them unresolved.
+func (c *KotlinConfig) SetFallbackDep(dep *label.Label) {
+	c.fallbackDep = dep
+}
+
+// FallbackDep returns the dependency of imports not found, if any.
+func (c *KotlinConfig) FallbackDep() *label.Label {
+	return c.fallbackDep
+}
+
+// SetLintConfig sets the `config` of generated lint rules, nil to not set `config`.
+func (c *KotlinConfig) SetLintConfig(config *label.Label) {
+	c.lintConfig = config
+}
+
+// LintConfig returns the `config` of generated lint rules, if any.
+func (c *KotlinConfig) LintConfig() *label.Label {
+	return c.lintConfig
+}
+
+// SetTags sets the tags added to all generated rules, replacing any inherited tags.
+func (c *KotlinConfig) SetTags(tags []string) {
+	c.tags = tags
+
+	managedTags := append([]string{}, c.managedTags...)
+	for _, tag := range tags {
+		if !slices.Contains(managedTags, tag) {
+			managedTags = append(managedTags, tag)
+		}
+	}
+	c.managedTags = managedTags
+}
+
+// IsManagedTag returns whether the tag was configured for the directory or a
+// parent directory, even if since replaced. Managed tags of existing rules are
+// removed when no longer configured.
+func (c *KotlinConfig) IsManagedTag(tag string) bool {
+	return slices.Contains(c.managedTags, tag)
+}
+
+// Tags returns the tags added to all generated rules.
+func (c *KotlinConfig) Tags() []string {
+	return c.tags
+}
+
+// SetDataPatterns sets the glob patterns of files added to the `data` of
+// generated binaries and tests, replacing any inherited patterns.
+func (c *KotlinConfig) SetDataPatterns(patterns []string) {
+	c.dataPatterns = patterns
+}
+
+// DataPatterns returns the glob patterns of files added to the `data` of generated binaries and tests.
+func (c *KotlinConfig) DataPatterns() []string {
+	return c.dataPatterns
+}
+
+// SetResourcePatterns sets the glob patterns of files added to the `resources` of
+// generated libraries, replacing any inherited patterns.
+func (c *KotlinConfig) SetResourcePatterns(patterns []string) {
+	c.resourcePatterns = patterns
+}
+
+// ResourcePatterns returns the glob patterns of files added to the `resources` of generated libraries.
+func (c *KotlinConfig) ResourcePatterns() []string {
+	return c.resourcePatterns
+}
+
+// SetResourceStripPrefix sets the `resource_strip_prefix` of generated libraries,
+// relative to each BUILD file.
+func (c *KotlinConfig) SetResourceStripPrefix(prefix string) {
+	c.resourceStripPrefix = prefix
+}
+
+// ResourceStripPrefix returns the `resource_strip_prefix` of generated libraries,
+// relative to each BUILD file.
+func (c *KotlinConfig) ResourceStripPrefix() string {
+	return c.resourceStripPrefix
+}
+
+// SetMavenResolver sets the MavenResolverMode used to read the maven_install.json files.
+func (c *KotlinConfig) SetMavenResolver(mode MavenResolverMode) {
+	c.mavenResolver = mode
+}
+
+// MavenResolver returns the MavenResolverMode used to read the maven_install.json files.
+func (c *KotlinConfig) MavenResolver() MavenResolverMode {
+	return c.mavenResolver
+}
+
+// SetMavenRepositories sets the maven_install repositories imports are resolved
+// against, replacing any inherited repositories.
+func (c *KotlinConfig) SetMavenRepositories(repositories []MavenRepository) {
+	c.mavenRepositories = repositories
+}
+
+// SetKotlinMavenInstallFile sets the absolute path of the maven_install.json file kotlin
+// imports are resolved against, overriding the java_maven_install_file unless empty.
+func (c *KotlinConfig) SetKotlinMavenInstallFile(installFile string) {
+	c.mavenInstallFile = installFile
+}
+
+// SetKotlinMavenRepositoryName sets the name of the maven_install repository of the labels
+// of maven artifacts, overriding the java_maven_repository_name unless empty.
+func (c *KotlinConfig) SetKotlinMavenRepositoryName(name string) {
+	c.mavenRepositoryName = name
+}
+
+// AddMavenExcludedArtifact excludes a maven artifact by its `group:artifact` coordinate
+// from the resolution of imports.
+func (c *KotlinConfig) AddMavenExcludedArtifact(coordinate string) {
+	c.mavenExcludedArtifacts = append(append([]string{}, c.mavenExcludedArtifacts...), coordinate)
+}
+
+// ResetMavenExcludedArtifacts removes all inherited maven artifact exclusions.
+func (c *KotlinConfig) ResetMavenExcludedArtifacts() {
+	c.mavenExcludedArtifacts = []string{}
+}
+
+// MavenExcludedArtifacts returns the `group:artifact` coordinates of the maven artifacts
+// excluded by the kotlin_maven_exclude_artifact directive. Artifacts excluded by the
+// java_exclude_artifact directive are returned by ExcludedArtifacts.
+func (c *KotlinConfig) MavenExcludedArtifacts() []string {
+	return c.mavenExcludedArtifacts
+}
+
+// MavenRepositories returns the maven_install repositories imports are resolved
+// against in order, by default the repository named by the kotlin_maven_repository_name
+// or java_maven_repository_name using the kotlin_maven_install_file or java_maven_install_file.
+func (c *KotlinConfig) MavenRepositories() []MavenRepository {
+	if len(c.mavenRepositories) > 0 {
+		return c.mavenRepositories
+	}
+
+	installFile := c.mavenInstallFile
+	if installFile == "" {
+		installFile = c.MavenInstallFile()
+	}
+
+	name := c.mavenRepositoryName
+	if name == "" {
+		name = c.MavenRepositoryName()
+	}
+
+	return []MavenRepository{{
+		Name:        name,
+		InstallFile: installFile,
+	}}
+}
+
+// ParentForPackage returns the Config of the closest ancestor directory of the Bazel
+// package with a Config, such as the root Config when none of the intermediate
+// directories were configured. Returns nil for the root package.
 func ParentForPackage(c Configs, pkg string) *KotlinConfig {
-	dir := filepath.Dir(pkg)
-	if dir == "." {
-		dir = ""
+	for pkg != "" {
+		pkg = path.Dir(pkg)
+		if pkg == "." {
+			pkg = ""
+		}
+
+		if parent, exists := c[pkg]; exists {
+			return parent
+		}
 	}
-	parent := (map[string]*KotlinConfig)(c)[dir]
-	return parent
+	return nil
 }
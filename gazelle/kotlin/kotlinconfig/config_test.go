@@ -0,0 +1,166 @@
+package kotlinconfig
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+)
+
+func TestParentForPackage(t *testing.T) {
+	root := New("/repo")
+	a := root.NewChild("a")
+	abc := a.NewChild("a/b/c")
+
+	configs := Configs{
+		"":      root,
+		"a":     a,
+		"a/b/c": abc,
+	}
+
+	for pkg, expected := range map[string]*KotlinConfig{
+		"":          nil,
+		"a":         root,
+		"a/b":       a,
+		"a/b/c":     a,
+		"a/b/c/d/e": abc,
+		"x/y/z":     root,
+	} {
+		if actual := ParentForPackage(configs, pkg); actual != expected {
+			t.Errorf("ParentForPackage(%q): expected %p, got %p", pkg, expected, actual)
+		}
+	}
+}
+
+func TestNewChildInheritance(t *testing.T) {
+	root := New("/repo")
+	root.SetGenerationEnabled(false)
+	root.SetNameCollision(NameCollisionRename)
+	root.SetGenerationMode(GenerationModule)
+	root.SetValidateImportStatements(ValidationError)
+	root.SetPackageFallbackDepth(2)
+	root.SetTags([]string{"manual"})
+	root.SetTestFileSuffixes([]string{"Spec.kt"})
+	root.AddGeneratedImport(GeneratedImport{Pattern: "com.generated.*"})
+	root.AddLabelRewrite(LabelRewrite{Prefix: "//third_party/", Replacement: "@vendored//"})
+	root.AddServiceProvider("com.foo.Service", label.New("", "foo", "impl"))
+
+	// Deep packages without intermediate configs inherit from the closest ancestor.
+	configs := Configs{"": root}
+	deep := ParentForPackage(configs, "a/b/c").NewChild("a/b/c")
+	configs["a/b/c"] = deep
+
+	t.Run("inherited", func(t *testing.T) {
+		if deep.GenerationEnabled() {
+			t.Errorf("expected generation to be disabled")
+		}
+		if deep.NameCollision() != NameCollisionRename {
+			t.Errorf("expected the name collision mode to be inherited")
+		}
+		if deep.GenerationMode() != GenerationModule || deep.ModuleRoot() != "" {
+			t.Errorf("expected the module of the root, got mode %v of %q", deep.GenerationMode(), deep.ModuleRoot())
+		}
+		if deep.ValidateImportStatements() != ValidationError {
+			t.Errorf("expected the validation mode to be inherited")
+		}
+		if deep.PackageFallbackDepth() != 2 {
+			t.Errorf("expected the package fallback depth to be inherited")
+		}
+		if !slices.Equal(deep.Tags(), []string{"manual"}) {
+			t.Errorf("expected the tags to be inherited, got %v", deep.Tags())
+		}
+		if !deep.IsTestFile("FooSpec.kt") {
+			t.Errorf("expected the test file suffixes to be inherited")
+		}
+		if deep.GeneratedImport("com.generated.Foo") == nil {
+			t.Errorf("expected the generated imports to be inherited")
+		}
+		if l, _ := deep.RewriteLabel(label.New("", "third_party/guava", "guava")); l.Repo != "vendored" {
+			t.Errorf("expected the label rewrites to be inherited, got %v", l)
+		}
+		if len(deep.ServiceProviders("com.foo.Service")) != 1 {
+			t.Errorf("expected the service providers to be inherited")
+		}
+	})
+
+	t.Run("overridden", func(t *testing.T) {
+		child := deep.NewChild("a/b/c/d")
+		child.SetGenerationMode(GenerationModule)
+		child.SetTags([]string{"other"})
+		child.AddGeneratedImport(GeneratedImport{Pattern: "com.other.*"})
+		child.AddServiceProvider("com.foo.Service", label.New("", "bar", "impl"))
+
+		if child.ModuleRoot() != "a/b/c/d" || deep.ModuleRoot() != "" {
+			t.Errorf("expected a nested module root, got %q and %q", child.ModuleRoot(), deep.ModuleRoot())
+		}
+		if !slices.Equal(deep.Tags(), []string{"manual"}) {
+			t.Errorf("expected the tags of the parent to be unchanged, got %v", deep.Tags())
+		}
+		if deep.GeneratedImport("com.other.Foo") != nil {
+			t.Errorf("expected the generated imports of the parent to be unchanged")
+		}
+		if len(deep.ServiceProviders("com.foo.Service")) != 1 || len(child.ServiceProviders("com.foo.Service")) != 2 {
+			t.Errorf("expected the service providers of the parent to be unchanged")
+		}
+	})
+}
+
+func TestMavenRepositories(t *testing.T) {
+	root := New("/repo")
+	if repositories := root.MavenRepositories(); len(repositories) != 1 || repositories[0].InstallFile != "/repo/maven_install.json" {
+		t.Errorf("expected the java_maven_install_file, got %v", repositories)
+	}
+
+	child := root.NewChild("legacy")
+	child.SetKotlinMavenInstallFile("/repo/legacy/maven_install.json")
+	grandchild := child.NewChild("legacy/app")
+	if repositories := grandchild.MavenRepositories(); len(repositories) != 1 || repositories[0].InstallFile != "/repo/legacy/maven_install.json" {
+		t.Errorf("expected the inherited kotlin_maven_install_file, got %v", repositories)
+	}
+	if repositories := root.MavenRepositories(); repositories[0].InstallFile != "/repo/maven_install.json" {
+		t.Errorf("expected the install file of the parent to be unchanged, got %v", repositories)
+	}
+
+	grandchild.SetKotlinMavenInstallFile("")
+	if repositories := grandchild.MavenRepositories(); repositories[0].InstallFile != "/repo/maven_install.json" {
+		t.Errorf("expected the java_maven_install_file to be restored, got %v", repositories)
+	}
+
+	grandchild.SetKotlinMavenRepositoryName("kotlin_maven")
+	if repositories := grandchild.MavenRepositories(); repositories[0].Name != "kotlin_maven" || child.MavenRepositories()[0].Name != "maven" {
+		t.Errorf("expected the kotlin_maven_repository_name, got %v", repositories)
+	}
+
+	grandchild.SetMavenRepositories([]MavenRepository{{Name: "android_maven", InstallFile: "/repo/android_maven_install.json"}})
+	if repositories := grandchild.MavenRepositories(); repositories[0].Name != "android_maven" {
+		t.Errorf("expected the kotlin_maven_repository directives to take precedence, got %v", repositories)
+	}
+}
+
+func TestDump(t *testing.T) {
+	root := New("/repo")
+	root.SetGenerateTests(true)
+	root.SetGenerationMode(GenerationModule)
+
+	child := root.NewChild("app")
+	child.SetTags([]string{"manual"})
+
+	dump := child.Dump()
+	for _, expected := range []string{
+		"kotlin enabled",
+		"kotlin_generate_tests enabled",
+		"kotlin_generation_mode module",
+		"# module root: //",
+		"kotlin_tags manual",
+		"kotlin_validate_import_statements warn",
+		"kotlin_fallback_dep",
+	} {
+		if !slices.Contains(dump, expected) {
+			t.Errorf("expected %q in the dump of the child, got: %v", expected, dump)
+		}
+	}
+
+	if slices.Contains(root.Dump(), "kotlin_tags manual") {
+		t.Errorf("expected the tags of the child not to be in the dump of the root")
+	}
+}
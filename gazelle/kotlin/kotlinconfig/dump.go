@@ -0,0 +1,203 @@
+package kotlinconfig
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+)
+
+// String returns the directive value of the mode.
+func (m NameCollisionMode) String() string {
+	switch m {
+	case NameCollisionRename:
+		return "rename"
+	default:
+		return "error"
+	}
+}
+
+// String returns the directive value of the mode.
+func (m GenerationMode) String() string {
+	switch m {
+	case GenerationPackage:
+		return "package"
+	case GenerationModule:
+		return "module"
+	case GenerationFile:
+		return "file"
+	default:
+		return "directory"
+	}
+}
+
+// String returns the directive value of the mode.
+func (m ValidationMode) String() string {
+	switch m {
+	case ValidationError:
+		return "error"
+	case ValidationOff:
+		return "off"
+	default:
+		return "warn"
+	}
+}
+
+// String returns the directive value of the mode.
+func (m MavenResolverMode) String() string {
+	switch m {
+	case MavenResolverBuiltin:
+		return "builtin"
+	default:
+		return "rules_jvm"
+	}
+}
+
+// Dump returns the effective value of every directive of the Config, including the
+// values inherited from parent directories and the defaults, as directives such as
+// "kotlin_generate_tests enabled". Repeatable directives are listed once per value.
+func (c *KotlinConfig) Dump() []string {
+	d := &configDump{}
+
+	d.add(Directive_KotlinExtension, enabledString(c.generationEnabled))
+	d.add(Directive_GenerationMode, c.generationMode.String())
+	if c.generationMode == GenerationModule {
+		d.add("#", "module root: //"+c.moduleRoot)
+	}
+	d.add(Directive_ModuleRootMarkers, strings.Join(c.moduleRootMarkers, " "))
+	d.add(Directive_JavaSources, enabledString(c.javaSourcesEnabled))
+	d.add(Directive_GenerateLibraries, enabledString(c.generateLibraries))
+	d.add(Directive_GenerateBinaries, enabledString(c.generateBinaries))
+	d.add(Directive_GenerateTests, enabledString(c.generateTests))
+	d.add(Directive_TestFileSuffixes, strings.Join(c.testFilePatterns, " "))
+	d.add(Directive_TestSources, enabledString(c.testSources))
+	d.add(Directive_Testonly, fmt.Sprint(c.testonly))
+	d.add(Directive_InferTestonly, enabledString(c.inferTestonly))
+	d.add(Directive_Associates, enabledString(c.associatesEnabled))
+	d.add(Directive_Cleanup, enabledString(c.cleanupEnabled))
+	d.add(Directive_FollowSymlinks, enabledString(c.followSymlinks))
+	d.add(Directive_NameCollision, c.nameCollision.String())
+	d.add(Directive_ModuleName, c.moduleName)
+	d.add(Directive_LanguageVersion, c.languageVersion)
+	d.add(Directive_ApiVersion, c.apiVersion)
+	d.add(Directive_Lint, enabledString(c.lintEnabled))
+	d.addLabel(Directive_LintConfig, c.lintConfig)
+	d.add(Directive_Tags, strings.Join(c.tags, " "))
+	d.addAll(Directive_Data, c.dataPatterns)
+	d.addAll(Directive_Resources, c.resourcePatterns)
+	d.add(Directive_ResourceStripPrefix, c.resourceStripPrefix)
+
+	for _, file := range sortedKeys(c.mainClasses) {
+		d.add(Directive_MainClass, file+" "+c.mainClasses[file])
+	}
+	for _, plugin := range c.CompilerPlugins() {
+		if plugin.Label != nil {
+			d.add(Directive_CompilerPlugin, plugin.Id+" "+plugin.Label.String())
+		}
+	}
+	for _, processor := range c.annotationProcessors {
+		d.add(Directive_AnnotationProcessor, processor.Annotation+" "+labelsString(append([]label.Label{processor.Plugin}, processor.Deps...)))
+	}
+	for _, service := range c.Services() {
+		d.add(Directive_ServiceProvider, service+" "+labelsString(c.serviceProviders[service]))
+	}
+
+	d.add(Directive_StrictDeps, enabledString(c.strictDeps))
+	d.add(Directive_ValidateImportStatements, c.validateImportStatements.String())
+	d.add(Directive_ValidateTestonly, c.validateTestonly.String())
+	d.add(Directive_ResolutionTrace, enabledString(c.resolutionTrace))
+	d.add(Directive_PackageFallbackDepth, fmt.Sprint(c.packageFallbackDepth))
+	d.addLabel(Directive_FallbackDep, c.fallbackDep)
+	d.add(Directive_PreferProvider, labelsString(c.preferredProviders))
+
+	for _, prefix := range sortedKeys(c.nativeImports) {
+		if c.nativeImports[prefix] {
+			d.add(Directive_NativeImport, prefix)
+		} else {
+			d.add(Directive_NativeImport, "!"+prefix)
+		}
+	}
+	for _, kind := range sortedKeys(c.extraDeps) {
+		d.add(Directive_ExtraDeps, strings.TrimSpace(kind+" "+labelsString(c.extraDeps[kind])))
+	}
+	for _, framework := range sortedKeys(c.testFrameworkDeps) {
+		d.add(Directive_TestFrameworkDeps, framework+" "+labelsString(c.testFrameworkDeps[framework]))
+	}
+	for _, generated := range c.generatedImports {
+		target := "self"
+		if generated.Label != nil {
+			target = generated.Label.String()
+		}
+		d.add(Directive_GeneratedImport, generated.Pattern+" "+target)
+	}
+	for _, resolve := range c.resolveRegexps {
+		d.add(Directive_ResolveRegexp, resolve.Regexp.String()+" "+resolve.Label.String())
+	}
+	for _, compileOnly := range c.compileOnlyImports {
+		if compileOnly.Label != nil {
+			d.add(Directive_CompileOnly, compileOnly.Pattern+" "+compileOnly.Label.String())
+		} else {
+			d.add(Directive_CompileOnly, compileOnly.Pattern)
+		}
+	}
+	for _, rewrite := range c.labelRewrites {
+		d.add(Directive_LabelRewrite, rewrite.Prefix+" "+rewrite.Replacement)
+	}
+	d.addAll(Directive_SymbolIndex, c.symbolIndexes)
+
+	d.add(Directive_MavenResolver, c.mavenResolver.String())
+	for _, repository := range c.MavenRepositories() {
+		d.add(Directive_MavenRepository, repository.Name+" "+repository.InstallFile)
+	}
+	d.addAll(Directive_MavenExcludeArtifact, c.mavenExcludedArtifacts)
+
+	return d.lines
+}
+
+// The lines of a dumped Config.
+type configDump struct {
+	lines []string
+}
+
+func (d *configDump) add(directive, value string) {
+	d.lines = append(d.lines, strings.TrimSpace(directive+" "+value))
+}
+
+func (d *configDump) addAll(directive string, values []string) {
+	for _, value := range values {
+		d.add(directive, value)
+	}
+}
+
+func (d *configDump) addLabel(directive string, l *label.Label) {
+	if l != nil {
+		d.add(directive, l.String())
+	} else {
+		d.add(directive, "")
+	}
+}
+
+func enabledString(enabled bool) string {
+	if enabled {
+		return "enabled"
+	}
+	return "disabled"
+}
+
+func labelsString(labels []label.Label) string {
+	s := make([]string, len(labels))
+	for i, l := range labels {
+		s[i] = l.String()
+	}
+	return strings.Join(s, " ")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
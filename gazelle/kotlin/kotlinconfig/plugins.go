@@ -0,0 +1,143 @@
+package kotlinconfig
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+)
+
+// CompilerPlugin is a kotlin compiler plugin (a kt_compiler_plugin target) and
+// the annotations which require the plugin.
+type CompilerPlugin struct {
+	// The plugin identifier used to configure the plugin via directives.
+	Id string
+
+	// The kt_compiler_plugin target. Plugins without a label are never added
+	// to generated rules.
+	Label *label.Label
+
+	// The fully qualified names of annotations requiring the plugin.
+	Annotations []string
+}
+
+// The compiler plugins known by default. Only the annotations are known, the
+// kt_compiler_plugin target must be configured using the kotlin_compiler_plugin directive.
+var builtinCompilerPlugins = []*CompilerPlugin{
+	{
+		Id: "kotlinx-serialization",
+		Annotations: []string{
+			"kotlinx.serialization.Serializable",
+		},
+	},
+	{
+		// The allopen plugin with the "spring" preset annotations
+		Id: "allopen",
+		Annotations: []string{
+			"org.springframework.stereotype.Component",
+			"org.springframework.stereotype.Controller",
+			"org.springframework.stereotype.Repository",
+			"org.springframework.stereotype.Service",
+			"org.springframework.web.bind.annotation.RestController",
+			"org.springframework.context.annotation.Configuration",
+			"org.springframework.boot.autoconfigure.SpringBootApplication",
+			"org.springframework.transaction.annotation.Transactional",
+			"org.springframework.scheduling.annotation.Async",
+			"org.springframework.cache.annotation.Cacheable",
+			"org.springframework.boot.test.context.SpringBootTest",
+		},
+	},
+	{
+		// The noarg plugin with the "jpa" preset annotations
+		Id: "noarg",
+		Annotations: []string{
+			"javax.persistence.Entity",
+			"javax.persistence.Embeddable",
+			"javax.persistence.MappedSuperclass",
+			"jakarta.persistence.Entity",
+			"jakarta.persistence.Embeddable",
+			"jakarta.persistence.MappedSuperclass",
+		},
+	},
+}
+
+func newCompilerPlugins() map[string]*CompilerPlugin {
+	plugins := make(map[string]*CompilerPlugin, len(builtinCompilerPlugins))
+	for _, p := range builtinCompilerPlugins {
+		plugins[p.Id] = p
+	}
+	return plugins
+}
+
+// SetCompilerPlugin configures the kt_compiler_plugin target of a known plugin, or
+// adds a custom plugin when annotations are specified for an unknown plugin.
+// Annotations specified for a known plugin are added to the plugin annotations.
+func (c *KotlinConfig) SetCompilerPlugin(id string, pluginLabel label.Label, annotations []string) error {
+	existing := c.compilerPlugins[id]
+	if existing == nil && len(annotations) == 0 {
+		return fmt.Errorf("unknown compiler plugin %q: custom plugins must declare at least one annotation", id)
+	}
+
+	plugin := &CompilerPlugin{
+		Id:          id,
+		Label:       &pluginLabel,
+		Annotations: annotations,
+	}
+	if existing != nil {
+		plugin.Annotations = append(append([]string{}, existing.Annotations...), annotations...)
+	}
+
+	// Copy the plugins of the parent before modifying.
+	plugins := make(map[string]*CompilerPlugin, len(c.compilerPlugins)+1)
+	for k, v := range c.compilerPlugins {
+		plugins[k] = v
+	}
+	plugins[id] = plugin
+	c.compilerPlugins = plugins
+
+	return nil
+}
+
+// CompilerPlugins returns all compiler plugins with a kt_compiler_plugin target, sorted by id.
+func (c *KotlinConfig) CompilerPlugins() []*CompilerPlugin {
+	plugins := make([]*CompilerPlugin, 0, len(c.compilerPlugins))
+	for _, p := range c.compilerPlugins {
+		if p.Label != nil {
+			plugins = append(plugins, p)
+		}
+	}
+
+	sort.Slice(plugins, func(i, j int) bool {
+		return plugins[i].Id < plugins[j].Id
+	})
+
+	return plugins
+}
+
+// AnnotationProcessor maps an annotation to the annotation processor processing it.
+type AnnotationProcessor struct {
+	// The fully qualified name of the annotation, such as `dagger.Component`
+	Annotation string
+
+	// The plugin running the processor, such as a java_plugin or kt_ksp_plugin target
+	Plugin label.Label
+
+	// The dependencies of the code generated by the processor
+	Deps []label.Label
+}
+
+// AddAnnotationProcessor adds the annotation processor of an annotation, after any inherited processors.
+func (c *KotlinConfig) AddAnnotationProcessor(processor AnnotationProcessor) {
+	// Copy the processors of the parent before modifying.
+	c.annotationProcessors = append(append([]AnnotationProcessor{}, c.annotationProcessors...), processor)
+}
+
+// ResetAnnotationProcessors removes all inherited annotation processors.
+func (c *KotlinConfig) ResetAnnotationProcessors() {
+	c.annotationProcessors = []AnnotationProcessor{}
+}
+
+// AnnotationProcessors returns the annotation processors of annotations, in order.
+func (c *KotlinConfig) AnnotationProcessors() []AnnotationProcessor {
+	return c.annotationProcessors
+}
@@ -0,0 +1,218 @@
+package kotlinconfig
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+)
+
+// GeneratedImport maps imports of code generated at build time, such as Dagger
+// components or KSP output, to the target generating the code.
+type GeneratedImport struct {
+	// The glob pattern matching the fully qualified imports, such as `com.foo.Dagger*`.
+	// `*` matches any sequence of characters including "."
+	Pattern string
+
+	// The target generating the code, nil if generated by the importing target itself.
+	Label *label.Label
+}
+
+// Matches returns whether the fully qualified import matches the pattern.
+func (g *GeneratedImport) Matches(imp string) bool {
+	return matchesImportPattern(g.Pattern, imp)
+}
+
+// Whether the fully qualified import matches the glob pattern.
+func matchesImportPattern(pattern, imp string) bool {
+	// Imports have no "/", path.Match matches any character other than "/"
+	matched, _ := path.Match(pattern, imp)
+	return matched
+}
+
+// AddGeneratedImport adds a mapping of imports of generated code, after any inherited mappings.
+func (c *KotlinConfig) AddGeneratedImport(generated GeneratedImport) {
+	// Copy the mappings of the parent before modifying.
+	c.generatedImports = append(append([]GeneratedImport{}, c.generatedImports...), generated)
+}
+
+// GeneratedImport returns the first mapping of generated code matching the import, if any.
+func (c *KotlinConfig) GeneratedImport(imp string) *GeneratedImport {
+	for i := range c.generatedImports {
+		if c.generatedImports[i].Matches(imp) {
+			return &c.generatedImports[i]
+		}
+	}
+	return nil
+}
+
+// ResolveRegexp resolves all imports matching a regular expression to a label.
+type ResolveRegexp struct {
+	// The regular expression matching the complete fully qualified imports
+	Regexp *regexp.Regexp
+
+	Label label.Label
+}
+
+// AddResolveRegexp adds a regular expression resolving imports, after any inherited expressions.
+func (c *KotlinConfig) AddResolveRegexp(resolve ResolveRegexp) {
+	// Copy the expressions of the parent before modifying.
+	c.resolveRegexps = append(append([]ResolveRegexp{}, c.resolveRegexps...), resolve)
+}
+
+// ResolveRegexp returns the label of the first regular expression matching the import, if any.
+func (c *KotlinConfig) ResolveRegexp(imp string) *label.Label {
+	for i := range c.resolveRegexps {
+		if c.resolveRegexps[i].Regexp.MatchString(imp) {
+			return &c.resolveRegexps[i].Label
+		}
+	}
+	return nil
+}
+
+// DefaultNativeImports returns the package prefixes provided by the kotlin standard
+// libraries. The java standard libraries are always native unless configured otherwise.
+func DefaultNativeImports() map[string]bool {
+	return map[string]bool{
+		"kotlin":  true,
+		"kotlinx": true,
+	}
+}
+
+// SetNativeImport sets whether imports of the package prefix, including sub-packages,
+// are provided by the standard libraries and never resolved to a dependency.
+func (c *KotlinConfig) SetNativeImport(prefix string, native bool) {
+	// Copy the prefixes of the parent before modifying.
+	nativeImports := make(map[string]bool, len(c.nativeImports)+1)
+	for k, v := range c.nativeImports {
+		nativeImports[k] = v
+	}
+	nativeImports[prefix] = native
+	c.nativeImports = nativeImports
+}
+
+// NativeImports returns whether imports of each package prefix are native.
+func (c *KotlinConfig) NativeImports() map[string]bool {
+	return c.nativeImports
+}
+
+// AddExtraDeps adds dependencies to all generated rules of the kind, or of all kinds if
+// the kind is empty, after any inherited dependencies.
+func (c *KotlinConfig) AddExtraDeps(kind string, deps []label.Label) {
+	// Copy the dependencies of the parent before modifying.
+	extraDeps := make(map[string][]label.Label, len(c.extraDeps)+1)
+	for k, v := range c.extraDeps {
+		extraDeps[k] = v
+	}
+	extraDeps[kind] = append(append([]label.Label{}, extraDeps[kind]...), deps...)
+	c.extraDeps = extraDeps
+}
+
+// ResetExtraDeps removes all inherited extra dependencies.
+func (c *KotlinConfig) ResetExtraDeps() {
+	c.extraDeps = make(map[string][]label.Label)
+}
+
+// ExtraDeps returns the dependencies added to all generated rules of the kind.
+func (c *KotlinConfig) ExtraDeps(kind string) []label.Label {
+	return append(append([]label.Label{}, c.extraDeps[""]...), c.extraDeps[kind]...)
+}
+
+// CompileOnlyImport marks imports only required at compile time, such as annotations
+// or APIs provided by the runtime environment.
+type CompileOnlyImport struct {
+	// The glob pattern matching the fully qualified imports, such as `javax.annotation.*`.
+	Pattern string
+
+	// The label replacing the resolved dependency, such as a `neverlink` wrapper of the
+	// library. Nil to move the resolved dependency to the compile-only attribute.
+	Label *label.Label
+}
+
+// Matches returns whether the fully qualified import matches the pattern.
+func (c *CompileOnlyImport) Matches(imp string) bool {
+	return matchesImportPattern(c.Pattern, imp)
+}
+
+// AddCompileOnlyImport adds a pattern of compile-only imports, after any inherited patterns.
+func (c *KotlinConfig) AddCompileOnlyImport(compileOnly CompileOnlyImport) {
+	// Copy the patterns of the parent before modifying.
+	c.compileOnlyImports = append(append([]CompileOnlyImport{}, c.compileOnlyImports...), compileOnly)
+}
+
+// CompileOnlyImport returns the first compile-only pattern matching the import, if any.
+func (c *KotlinConfig) CompileOnlyImport(imp string) *CompileOnlyImport {
+	for i := range c.compileOnlyImports {
+		if c.compileOnlyImports[i].Matches(imp) {
+			return &c.compileOnlyImports[i]
+		}
+	}
+	return nil
+}
+
+// AddPreferredProviders adds targets preferred when multiple targets provide an import,
+// after any inherited targets.
+func (c *KotlinConfig) AddPreferredProviders(providers []label.Label) {
+	// Copy the targets of the parent before modifying.
+	c.preferredProviders = append(append([]label.Label{}, c.preferredProviders...), providers...)
+}
+
+// ResetPreferredProviders removes all inherited preferred targets.
+func (c *KotlinConfig) ResetPreferredProviders() {
+	c.preferredProviders = []label.Label{}
+}
+
+// PreferredProviders returns the targets preferred when multiple targets provide an import, in order.
+func (c *KotlinConfig) PreferredProviders() []label.Label {
+	return c.preferredProviders
+}
+
+// LabelRewrite rewrites the prefix of resolved dependency labels.
+type LabelRewrite struct {
+	// The prefix of the labels such as `//third_party/`, matched against the shortest
+	// form of the label such as `//third_party/guava` for `//third_party/guava:guava`
+	Prefix string
+
+	// The replacement of the prefix such as `@vendored//`
+	Replacement string
+}
+
+// AddLabelRewrite adds a rewrite of resolved dependency labels, after any inherited rewrites.
+func (c *KotlinConfig) AddLabelRewrite(rewrite LabelRewrite) {
+	// Copy the rewrites of the parent before modifying.
+	c.labelRewrites = append(append([]LabelRewrite{}, c.labelRewrites...), rewrite)
+}
+
+// ResetLabelRewrites removes all inherited rewrites.
+func (c *KotlinConfig) ResetLabelRewrites() {
+	c.labelRewrites = []LabelRewrite{}
+}
+
+// RewriteLabel rewrites a resolved dependency label using the first rewrite matching
+// the label, returning the label unchanged if none match.
+func (c *KotlinConfig) RewriteLabel(l label.Label) (label.Label, error) {
+	s := l.String()
+	for _, rewrite := range c.labelRewrites {
+		if strings.HasPrefix(s, rewrite.Prefix) {
+			return label.Parse(rewrite.Replacement + strings.TrimPrefix(s, rewrite.Prefix))
+		}
+	}
+	return l, nil
+}
+
+// AddSymbolIndex adds the absolute path of a symbol index file, after any inherited files.
+func (c *KotlinConfig) AddSymbolIndex(indexFile string) {
+	// Copy the files of the parent before modifying.
+	c.symbolIndexes = append(append([]string{}, c.symbolIndexes...), indexFile)
+}
+
+// ResetSymbolIndexes removes all inherited symbol index files.
+func (c *KotlinConfig) ResetSymbolIndexes() {
+	c.symbolIndexes = []string{}
+}
+
+// SymbolIndexes returns the absolute paths of the symbol index files, in order.
+func (c *KotlinConfig) SymbolIndexes() []string {
+	return c.symbolIndexes
+}
@@ -0,0 +1,35 @@
+package kotlinconfig
+
+import (
+	"sort"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+)
+
+// AddServiceProvider adds a library providing an implementation of a
+// java.util.ServiceLoader service, identified by the fully qualified name
+// of the service interface.
+func (c *KotlinConfig) AddServiceProvider(service string, provider label.Label) {
+	// Copy the providers of the parent before modifying.
+	providers := make(map[string][]label.Label, len(c.serviceProviders)+1)
+	for k, v := range c.serviceProviders {
+		providers[k] = v
+	}
+	providers[service] = append(append([]label.Label{}, providers[service]...), provider)
+	c.serviceProviders = providers
+}
+
+// ServiceProviders returns the libraries providing implementations of the service.
+func (c *KotlinConfig) ServiceProviders(service string) []label.Label {
+	return c.serviceProviders[service]
+}
+
+// Services returns the fully qualified names of all services with providers.
+func (c *KotlinConfig) Services() []string {
+	services := make([]string, 0, len(c.serviceProviders))
+	for s := range c.serviceProviders {
+		services = append(services, s)
+	}
+	sort.Strings(services)
+	return services
+}
@@ -0,0 +1,98 @@
+package kotlinconfig
+
+import (
+	"path"
+	"strings"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+)
+
+// TestFrameworks are the test frameworks detected from the imports of test files,
+// by the package prefix of their imports.
+var TestFrameworks = map[string]string{
+	"org.junit":         "junit4",
+	"org.junit.jupiter": "junit5",
+	"kotlin.test":       "kotlin-test",
+	"io.kotest":         "kotest",
+	"org.testng":        "testng",
+	"org.spekframework": "spek",
+}
+
+// IsTestFramework returns whether the name is one of the TestFrameworks.
+func IsTestFramework(name string) bool {
+	for _, framework := range TestFrameworks {
+		if framework == name {
+			return true
+		}
+	}
+	return false
+}
+
+// TestFrameworkOf returns the test framework of an import, using the longest package
+// prefix of TestFrameworks matching the import, or an empty string if none match.
+func TestFrameworkOf(impt string) string {
+	framework, longest := "", ""
+	for prefix, f := range TestFrameworks {
+		if (impt == prefix || strings.HasPrefix(impt, prefix+".")) && len(prefix) > len(longest) {
+			framework, longest = f, prefix
+		}
+	}
+	return framework
+}
+
+// DefaultTestFilePatterns returns the glob patterns of the names of test files
+// used unless configured by the kotlin_test_file_suffixes directive.
+func DefaultTestFilePatterns() []string {
+	return []string{"*Test.kt"}
+}
+
+// SetTestFileSuffixes sets the suffixes or glob patterns of the names of test files.
+// Suffixes such as `Test.kt` match any name ending with the suffix.
+func (c *KotlinConfig) SetTestFileSuffixes(suffixes []string) {
+	patterns := make([]string, 0, len(suffixes))
+	for _, suffix := range suffixes {
+		if !strings.ContainsAny(suffix, "*?[") {
+			suffix = "*" + suffix
+		}
+		patterns = append(patterns, suffix)
+	}
+	c.testFilePatterns = patterns
+}
+
+// TestFilePatterns returns the glob patterns of the names of test files.
+func (c *KotlinConfig) TestFilePatterns() []string {
+	return c.testFilePatterns
+}
+
+// IsTestFile returns whether the file is a test file according to its name.
+func (c *KotlinConfig) IsTestFile(file string) bool {
+	name := path.Base(file)
+	for _, pattern := range c.testFilePatterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// AddTestFrameworkDeps adds dependencies to the generated tests using the test framework,
+// after any inherited dependencies.
+func (c *KotlinConfig) AddTestFrameworkDeps(framework string, deps []label.Label) {
+	// Copy the dependencies of the parent before modifying.
+	frameworkDeps := make(map[string][]label.Label, len(c.testFrameworkDeps)+1)
+	for k, v := range c.testFrameworkDeps {
+		frameworkDeps[k] = v
+	}
+	frameworkDeps[framework] = append(append([]label.Label{}, frameworkDeps[framework]...), deps...)
+	c.testFrameworkDeps = frameworkDeps
+}
+
+// ResetTestFrameworkDeps removes all inherited test framework dependencies.
+func (c *KotlinConfig) ResetTestFrameworkDeps() {
+	c.testFrameworkDeps = make(map[string][]label.Label)
+}
+
+// TestFrameworkDeps returns the dependencies added to the generated tests using the test framework.
+func (c *KotlinConfig) TestFrameworkDeps(framework string) []label.Label {
+	return c.testFrameworkDeps[framework]
+}
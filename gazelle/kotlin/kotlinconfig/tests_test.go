@@ -0,0 +1,63 @@
+package kotlinconfig
+
+import (
+	"testing"
+)
+
+func TestTestFileSuffixes(t *testing.T) {
+	root := New("/repo")
+
+	t.Run("defaults", func(t *testing.T) {
+		for file, expected := range map[string]bool{
+			"FooTest.kt":      true,
+			"foo/BarIT.kt":    false,
+			"Foo.kt":          false,
+			"TestUtils.kt":    false,
+			"FooTest.kt.orig": false,
+			"foo/FooSpec.kt":  false,
+		} {
+			if actual := root.IsTestFile(file); actual != expected {
+				t.Errorf("IsTestFile(%q): expected %v, got %v", file, expected, actual)
+			}
+		}
+	})
+
+	t.Run("inheritance", func(t *testing.T) {
+		child := root.NewChild("specs")
+		child.SetTestFileSuffixes([]string{"*Spec.kt", "IT.kt"})
+		grandchild := child.NewChild("specs/unit")
+
+		if !grandchild.IsTestFile("FooSpec.kt") || !grandchild.IsTestFile("FooIT.kt") {
+			t.Errorf("expected the suffixes of the parent to be inherited")
+		}
+		if grandchild.IsTestFile("FooTest.kt") {
+			t.Errorf("expected the inherited suffixes to replace the defaults")
+		}
+		if root.IsTestFile("FooSpec.kt") {
+			t.Errorf("expected the suffixes of the parent to be unchanged")
+		}
+
+		grandchild.SetTestFileSuffixes(DefaultTestFilePatterns())
+		if !grandchild.IsTestFile("FooTest.kt") || grandchild.IsTestFile("FooIT.kt") || !child.IsTestFile("FooSpec.kt") {
+			t.Errorf("expected the defaults to be restored without modifying the parent")
+		}
+	})
+}
+
+func TestTestFrameworkOf(t *testing.T) {
+	for impt, expected := range map[string]string{
+		"org.junit.Test":                  "junit4",
+		"org.junit.jupiter.api.Test":      "junit5",
+		"org.junit.jupiter.api":           "junit5",
+		"kotlin.test.assertEquals":        "kotlin-test",
+		"io.kotest.core.spec.style":       "kotest",
+		"org.testng.annotations.Test":     "testng",
+		"org.junitx.Foo":                  "",
+		"kotlin.collections.List":         "",
+		"com.example.org.junit.jupiter.X": "",
+	} {
+		if actual := TestFrameworkOf(impt); actual != expected {
+			t.Errorf("TestFrameworkOf(%q): expected %q, got %q", impt, expected, actual)
+		}
+	}
+}
@@ -1,8 +1,12 @@
 package gazelle
 
 import (
-	jvm_maven "github.com/bazel-contrib/rules_jvm/java/gazelle/private/maven"
+	"time"
+
+	"aspect.build/cli/gazelle/kotlin/maven"
+	"aspect.build/cli/gazelle/kotlin/symbolindex"
 	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
 	"github.com/bazelbuild/bazel-gazelle/language"
 	"github.com/bazelbuild/bazel-gazelle/rule"
 	"github.com/emirpasic/gods/sets/treeset"
@@ -13,20 +17,115 @@ const LanguageName = "kotlin"
 const (
 	KtJvmLibrary              = "kt_jvm_library"
 	KtJvmBinary               = "kt_jvm_binary"
+	KtJvmTest                 = "kt_jvm_test"
+	KtlintTest                = "ktlint_test"
 	RulesKotlinRepositoryName = "io_bazel_rules_kotlin"
 )
 
 var sourceRuleKinds = treeset.NewWithStringComparator(KtJvmLibrary)
 
+// All rule kinds generated by the extension.
+var generatedRuleKinds = treeset.NewWithStringComparator(KtJvmLibrary, KtJvmBinary, KtJvmTest, KtlintTest)
+
+// The rule kinds of other languages compiling their srcs. Kotlin and java sources
+// in the srcs of these rules are never added to generated rules.
+var sourceOwnerKinds = treeset.NewWithStringComparator(
+	"java_library",
+	"java_binary",
+	"java_test",
+	"android_library",
+	"android_binary",
+	"android_local_test",
+	"kt_android_library",
+	"kt_android_local_test",
+)
+
 var _ language.Language = (*kotlinLang)(nil)
 
 // The Gazelle extension for TypeScript rules.
 // TypeScript satisfies the language.Language interface including the
 // Configurer and Resolver types.
 type kotlinLang struct {
+	language.BaseLifecycleManager
+
 	// TODO: extend rules_jvm extension instead of duplicating?
-	mavenResolver    *jvm_maven.Resolver
-	mavenInstallFile string
+	// The Maven resolvers by maven_install.json path
+	mavenResolvers map[mavenResolverKey]maven.Resolver
+
+	// The symbol index files by absolute path
+	symbolIndexes map[string]*symbolindex.Index
+
+	// The file persisting maven resolutions between runs, if set, and the digests of the maven_install.json files
+	resolutionCacheFile string
+	resolutionCache     *resolutionCache
+	lockFileDigests     map[string]string
+
+	// The workspace configuration file of kotlin directives, if set
+	configFile string
+
+	// The directives of the -kotlin-directive flags, and the directives of the flags and
+	// environment applied to all packages
+	directiveFlags directiveFlags
+	flagDirectives []rule.Directive
+
+	// The file to write the RuleChange list to, if set
+	changeReportFile string
+	changes          map[label.Label]*RuleChange
+
+	// The rules generated in this run and the libraries only depended upon by tests
+	generatedRules    []generatedRule
+	testonlyLibraries map[label.Label]bool
+
+	// The `testonly` rules of the visited packages, and the number of dependencies
+	// upon testonly rules failing validation in this run
+	testonlyRules      map[label.Label]bool
+	testonlyViolations int
+
+	// The `visibility` of rules and `default_visibility` of packages, where known
+	ruleVisibility    map[label.Label][]label.Label
+	defaultVisibility map[string][]label.Label
+
+	// The proto_library rules and their .proto files, and the java_proto_library-like
+	// rules and the proto_library rules they compile, to resolve proto generated code
+	protoLibraries map[label.Label][]string
+	protoRules     map[label.Label][]label.Label
+	protoImports   map[string][]label.Label
+
+	// Whether the effective configuration of each visited directory is printed
+	printConfig bool
+
+	// The maximum number of files parsed in parallel, 0 for the default
+	parallelism int
+
+	// The maximum size and parse duration of a kotlin file, unlimited if 0
+	maxFileSize  int
+	parseTimeout time.Duration
+
+	// Whether only some packages of the repository are visited in this run
+	partialRun bool
+
+	// The resolution steps of the import being resolved, if tracing is enabled
+	trace *resolutionTrace
+
+	// The number of unresolved imports failing validation in this run
+	unresolvedImports int
+
+	// The number of imports resolved to the kotlin_fallback_dep in this run
+	fallbackImports int
+
+	// The targets with imports failing to resolve in this run, such as imports provided by multiple targets
+	resolutionErrors []resolutionErrors
+
+	// The file to write the unresolved imports to, if set
+	unresolvedReportFile string
+	unresolved           []UnresolvedImport
+
+	// The file and format to write the dependency graph to, if set, the resolved rules
+	// and the imports resolved to each dependency of the rule being resolved
+	depGraphFile    string
+	depGraphFormat  string
+	depGraph        []DepGraphTarget
+	depGraphImports map[label.Label]map[label.Label][]string
 }
 
 // NewLanguage initializes a new TypeScript that satisfies the language.Language
@@ -37,28 +136,93 @@ func NewLanguage() language.Language {
 
 var kotlinKinds = map[string]rule.KindInfo{
 	KtJvmLibrary: {
-		MatchAny: false,
+		MatchAny:   false,
+		MatchAttrs: []string{"srcs"},
 		NonEmptyAttrs: map[string]bool{
 			"srcs": true,
 		},
 		SubstituteAttrs: map[string]bool{},
 		MergeableAttrs: map[string]bool{
-			"srcs": true,
+			"srcs":                  true,
+			"api_version":           true,
+			"language_version":      true,
+			"module_name":           true,
+			"plugins":               true,
+			"resources":             true,
+			"resource_strip_prefix": true,
+			"runtime_deps":          true,
+			"tags":                  true,
 		},
 		ResolveAttrs: map[string]bool{
-			"deps": true,
+			"deps":              true,
+			"compile_only_deps": true,
+			"exports":           true,
+			"testonly":          true,
 		},
 	},
 
 	KtJvmBinary: {
+		MatchAny:   false,
+		MatchAttrs: []string{"srcs"},
+		NonEmptyAttrs: map[string]bool{
+			"srcs": true,
+		},
+		SubstituteAttrs: map[string]bool{},
+		MergeableAttrs: map[string]bool{
+			"srcs":             true,
+			"associates":       true,
+			"api_version":      true,
+			"data":             true,
+			"language_version": true,
+			"main_class":       true,
+			"module_name":      true,
+			"plugins":          true,
+			"runtime_deps":     true,
+			"tags":             true,
+		},
+		ResolveAttrs: map[string]bool{
+			"deps":              true,
+			"compile_only_deps": true,
+		},
+	},
+
+	KtJvmTest: {
+		MatchAny:   false,
+		MatchAttrs: []string{"srcs"},
+		NonEmptyAttrs: map[string]bool{
+			"srcs": true,
+		},
+		SubstituteAttrs: map[string]bool{},
+		MergeableAttrs: map[string]bool{
+			"srcs":             true,
+			"associates":       true,
+			"api_version":      true,
+			"data":             true,
+			"language_version": true,
+			"module_name":      true,
+			"plugins":          true,
+			"runtime_deps":     true,
+			"tags":             true,
+			"test_class":       true,
+		},
+		ResolveAttrs: map[string]bool{
+			"deps":              true,
+			"compile_only_deps": true,
+		},
+	},
+
+	KtlintTest: {
 		MatchAny: false,
 		NonEmptyAttrs: map[string]bool{
-			"srcs":       true,
-			"main_class": true,
+			"srcs": true,
 		},
 		SubstituteAttrs: map[string]bool{},
-		MergeableAttrs:  map[string]bool{},
-		ResolveAttrs:    map[string]bool{},
+		MergeableAttrs: map[string]bool{
+			"srcs":   true,
+			"config": true,
+			"tags":   true,
+		},
+		ResolveAttrs: map[string]bool{},
 	},
 }
 
@@ -68,6 +232,13 @@ var kotlinLoads = []rule.LoadInfo{
 		Symbols: []string{
 			KtJvmLibrary,
 			KtJvmBinary,
+			KtJvmTest,
+		},
+	},
+	{
+		Name: "@" + RulesKotlinRepositoryName + "//kotlin:lint.bzl",
+		Symbols: []string{
+			KtlintTest,
 		},
 	},
 }
@@ -0,0 +1,185 @@
+package maven
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	BazelLog "aspect.build/cli/pkg/logger"
+	"github.com/bazel-contrib/rules_jvm/java/gazelle/private/types"
+	"github.com/bazelbuild/bazel-gazelle/label"
+)
+
+// Resolver resolves a java package to the label of the maven_install artifact
+// providing it. Implemented by both the rules_jvm resolver and the resolver
+// reading the maven_install.json file directly.
+type Resolver interface {
+	Resolve(pkg types.PackageName, excludedArtifacts map[string]struct{}, mavenRepositoryName string) (label.Label, error)
+}
+
+// AmbiguousPackageError is returned when a package is provided by multiple
+// artifacts of a maven_install repository.
+type AmbiguousPackageError struct {
+	Package string
+
+	// The labels of the artifacts providing the package, sorted
+	Artifacts []label.Label
+}
+
+func (e *AmbiguousPackageError) Error() string {
+	names := make([]string, len(e.Artifacts))
+	for i, a := range e.Artifacts {
+		names[i] = a.String()
+	}
+	return fmt.Sprintf("package %q provided by multiple maven artifacts (%s)", e.Package, strings.Join(names, ", "))
+}
+
+// An artifact of a maven_install.json file.
+type artifact struct {
+	// The group and artifact id such as "com.google.guava:guava"
+	coordinate string
+
+	// The name of the target of the artifact within the maven_install repository
+	name string
+}
+
+type installFileResolver struct {
+	// The artifacts providing each java package
+	packages map[string][]artifact
+}
+
+var _ Resolver = (*installFileResolver)(nil)
+
+// The subset of the maven_install.json v1 and v2 formats required to index packages.
+type installFile struct {
+	// v1: the artifacts and their packages
+	DependencyTree *struct {
+		Dependencies []struct {
+			Coord    string   `json:"coord"`
+			Packages []string `json:"packages"`
+		} `json:"dependencies"`
+	} `json:"dependency_tree"`
+
+	// v2: the packages of each artifact by coordinate
+	Packages map[string][]string `json:"packages"`
+}
+
+// NewResolver returns a Resolver reading the maven_install.json file directly,
+// without the setup required by the rules_jvm resolver. A missing file resolves nothing.
+func NewResolver(installFilePath string) (Resolver, error) {
+	r := &installFileResolver{
+		packages: make(map[string][]artifact),
+	}
+
+	content, err := os.ReadFile(installFilePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			BazelLog.Debugf("maven_install file %q not found, not loading maven dependencies", installFilePath)
+			return r, nil
+		}
+		return nil, fmt.Errorf("failed to read %q: %w", installFilePath, err)
+	}
+
+	var f installFile
+	if err := json.Unmarshal(content, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse %q: %w", installFilePath, err)
+	}
+
+	if f.DependencyTree != nil {
+		for _, dep := range f.DependencyTree.Dependencies {
+			// Coordinates with the version last: group:artifact[:packaging[:classifier]]:version
+			parts := strings.Split(dep.Coord, ":")
+			if len(parts) < 3 {
+				continue
+			}
+			r.add(parts[:len(parts)-1], dep.Packages)
+		}
+	}
+
+	for coord, pkgs := range f.Packages {
+		// Coordinates without the version: group:artifact[:packaging[:classifier]]
+		r.add(strings.Split(coord, ":"), pkgs)
+	}
+
+	for _, artifacts := range r.packages {
+		sort.Slice(artifacts, func(i, j int) bool {
+			return artifacts[i].name < artifacts[j].name
+		})
+	}
+
+	return r, nil
+}
+
+func (r *installFileResolver) add(coord []string, pkgs []string) {
+	if len(coord) < 2 || len(pkgs) == 0 {
+		return
+	}
+
+	a := artifact{
+		coordinate: coord[0] + ":" + coord[1],
+		name:       coord[0] + "_" + coord[1],
+	}
+
+	// The classifier of the artifact such as "linux-x86_64", after the packaging
+	if len(coord) == 4 {
+		a.name += "_" + coord[3]
+	}
+	a.name = toTargetName(a.name)
+
+	for _, pkg := range pkgs {
+		r.packages[pkg] = append(r.packages[pkg], a)
+	}
+}
+
+func (r *installFileResolver) Resolve(pkg types.PackageName, excludedArtifacts map[string]struct{}, mavenRepositoryName string) (label.Label, error) {
+	candidates := make([]artifact, 0)
+	for _, a := range r.packages[pkg.Name] {
+		if isExcluded(a, excludedArtifacts, mavenRepositoryName) {
+			continue
+		}
+		candidates = append(candidates, a)
+	}
+
+	switch len(candidates) {
+	case 0:
+		return label.NoLabel, fmt.Errorf("package %q not found in the maven artifacts", pkg.Name)
+	case 1:
+		return label.New(mavenRepositoryName, "", candidates[0].name), nil
+	}
+
+	artifacts := make([]label.Label, len(candidates))
+	for i, a := range candidates {
+		artifacts[i] = label.New(mavenRepositoryName, "", a.name)
+	}
+	return label.NoLabel, &AmbiguousPackageError{Package: pkg.Name, Artifacts: artifacts}
+}
+
+// Whether the artifact is excluded by its coordinate or label.
+func isExcluded(a artifact, excludedArtifacts map[string]struct{}, mavenRepositoryName string) bool {
+	if _, excluded := excludedArtifacts[a.coordinate]; excluded {
+		return true
+	}
+
+	_, excluded := excludedArtifacts[label.New(mavenRepositoryName, "", a.name).String()]
+	return excluded
+}
+
+// ArtifactLabel returns the label of a `group:artifact` coordinate within the maven_install
+// repository, such as `@maven//:com_google_guava_guava` for `com.google.guava:guava`.
+func ArtifactLabel(mavenRepositoryName, coordinate string) label.Label {
+	return label.New(mavenRepositoryName, "", toTargetName(coordinate))
+}
+
+// Convert an artifact coordinate to the target name used by rules_jvm_external,
+// replacing all characters other than letters and digits with "_".
+func toTargetName(coord string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, coord)
+}
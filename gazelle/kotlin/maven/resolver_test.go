@@ -0,0 +1,146 @@
+package maven
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bazel-contrib/rules_jvm/java/gazelle/private/types"
+	"github.com/bazelbuild/bazel-gazelle/label"
+)
+
+const v1InstallFile = `{
+  "dependency_tree": {
+    "dependencies": [
+      {
+        "coord": "com.google.guava:guava:30.0-jre",
+        "packages": ["com.google.common.base", "com.google.common.collect"]
+      },
+      {
+        "coord": "com.google.guava:guava:jar:sources:30.0-jre",
+        "packages": []
+      },
+      {
+        "coord": "io.netty:netty-transport-native-epoll:jar:linux-x86_64:4.1.0",
+        "packages": ["io.netty.channel.epoll"]
+      },
+      {
+        "coord": "com.google.collections:google-collections:1.0",
+        "packages": ["com.google.common.collect"]
+      }
+    ],
+    "version": "0.1.0"
+  }
+}`
+
+const v2InstallFile = `{
+  "version": "2",
+  "artifacts": {
+    "junit:junit": {"version": "4.13.1"}
+  },
+  "packages": {
+    "junit:junit": ["junit.framework", "org.junit"]
+  }
+}`
+
+func writeInstallFile(t *testing.T, content string) string {
+	p := filepath.Join(t.TempDir(), "maven_install.json")
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func labelsString(labels []label.Label) string {
+	names := make([]string, len(labels))
+	for i, l := range labels {
+		names[i] = l.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+func TestResolve(t *testing.T) {
+	t.Run("v1 install file", func(t *testing.T) {
+		r, err := NewResolver(writeInstallFile(t, v1InstallFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for pkg, expected := range map[string]string{
+			"com.google.common.base": "@maven//:com_google_guava_guava",
+			"io.netty.channel.epoll": "@maven//:io_netty_netty_transport_native_epoll_linux_x86_64",
+		} {
+			l, err := r.Resolve(types.NewPackageName(pkg), nil, "maven")
+			if err != nil {
+				t.Errorf("Resolve(%q) failed: %v", pkg, err)
+			} else if l.String() != expected {
+				t.Errorf("Resolve(%q)...\nactual:  %q;\nexpected: %q", pkg, l.String(), expected)
+			}
+		}
+	})
+
+	t.Run("v2 install file", func(t *testing.T) {
+		r, err := NewResolver(writeInstallFile(t, v2InstallFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		l, err := r.Resolve(types.NewPackageName("org.junit"), nil, "test_maven")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l.String() != "@test_maven//:junit_junit" {
+			t.Errorf("Resolve(org.junit)...\nactual:  %q;\nexpected: %q", l.String(), "@test_maven//:junit_junit")
+		}
+	})
+
+	t.Run("multiple artifacts", func(t *testing.T) {
+		r, err := NewResolver(writeInstallFile(t, v1InstallFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = r.Resolve(types.NewPackageName("com.google.common.collect"), nil, "maven")
+		ambiguous, isAmbiguous := err.(*AmbiguousPackageError)
+		if !isAmbiguous {
+			t.Fatalf("expected an AmbiguousPackageError, got: %v", err)
+		}
+		expected := "@maven//:com_google_collections_google_collections, @maven//:com_google_guava_guava"
+		if actual := labelsString(ambiguous.Artifacts); actual != expected {
+			t.Errorf("Artifacts...\nactual:  %q;\nexpected: %q", actual, expected)
+		}
+
+		excluded := map[string]struct{}{"com.google.collections:google-collections": {}}
+		l, err := r.Resolve(types.NewPackageName("com.google.common.collect"), excluded, "maven")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l.String() != "@maven//:com_google_guava_guava" {
+			t.Errorf("Resolve(com.google.common.collect)...\nactual:  %q;\nexpected: %q", l.String(), "@maven//:com_google_guava_guava")
+		}
+	})
+
+	t.Run("missing install file", func(t *testing.T) {
+		r, err := NewResolver(filepath.Join(t.TempDir(), "maven_install.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := r.Resolve(types.NewPackageName("org.junit"), nil, "maven"); err == nil {
+			t.Error("expected an error for a package not in the install file")
+		}
+	})
+}
+
+func TestArtifactLabel(t *testing.T) {
+	for coordinate, expected := range map[string]string{
+		"com.google.guava:guava":                   "@maven//:com_google_guava_guava",
+		"io.netty:netty-transport-native-epoll":    "@maven//:io_netty_netty_transport_native_epoll",
+		"org.jetbrains.kotlinx:kotlinx-coroutines": "@maven//:org_jetbrains_kotlinx_kotlinx_coroutines",
+	} {
+		if actual := ArtifactLabel("maven", coordinate).String(); actual != expected {
+			t.Errorf("ArtifactLabel(%q)...\nactual:  %q;\nexpected: %q", coordinate, actual, expected)
+		}
+	}
+}
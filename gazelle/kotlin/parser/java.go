@@ -0,0 +1,95 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// A minimal parser for the header of .java files included in kotlin targets.
+//
+// Only the package declaration, import statements and top-level type declarations
+// are extracted which is all that is required to resolve the dependencies of mixed
+// java+kotlin targets.
+// Java files are always library sources, main() methods are not detected.
+type javaParser struct {
+	Parser
+}
+
+var (
+	javaCommentsRe = regexp.MustCompile(`(?s)/\*.*?\*/|//[^\n]*`)
+	javaPackageRe  = regexp.MustCompile(`(?m)^\s*package\s+([\w.]+)\s*;`)
+	javaImportRe   = regexp.MustCompile(`(?m)^\s*import\s+(static\s+)?([\w.]+?)(\.\*)?\s*;`)
+
+	// Type declarations which are not indented are assumed to be top-level.
+	javaTypeRe = regexp.MustCompile(`(?m)^(?:(?:public|protected|private|abstract|final|sealed|non-sealed|static|strictfp)\s+)*(?:class|interface|enum|record|@interface)\s+(\w+)`)
+)
+
+func NewJavaParser() Parser {
+	p := javaParser{}
+
+	return &p
+}
+
+func (p *javaParser) Parse(filePath, source string) (*ParseResult, []error) {
+	var result = &ParseResult{
+		File:            filePath,
+		Imports:         make([]string, 0),
+		ImportedSymbols: make([]string, 0),
+		StarImports:     make([]string, 0),
+		Declarations:    make([]string, 0),
+	}
+
+	source = javaCommentsRe.ReplaceAllString(source, "")
+
+	if m := javaPackageRe.FindStringSubmatch(source); m != nil {
+		result.Package = m[1]
+	}
+
+	for _, m := range javaImportRe.FindAllStringSubmatch(source, -1) {
+		isStatic := m[1] != ""
+		isStar := m[3] != ""
+
+		// Trim the class and static member names to align with kotlin
+		// imports which are resolved by package.
+		trim := 1
+		if isStar {
+			trim = 0
+		}
+		if isStatic {
+			trim++
+		}
+
+		if impt := trimIdentifier(m[2], trim); impt != "" {
+			result.Imports = append(result.Imports, impt)
+		}
+
+		if isStar && !isStatic {
+			result.StarImports = append(result.StarImports, m[2])
+		}
+
+		// The imported class, or the class of the static members
+		if !isStar || isStatic {
+			if symbol := trimIdentifier(m[2], trim-1); symbol != "" {
+				result.ImportedSymbols = append(result.ImportedSymbols, symbol)
+			}
+		}
+	}
+
+	for _, m := range javaTypeRe.FindAllStringSubmatch(source, -1) {
+		result.Declarations = append(result.Declarations, m[1])
+	}
+
+	return result, nil
+}
+
+// Remove the last n segments of a dot separated identifier.
+func trimIdentifier(id string, n int) string {
+	for i := 0; i < n; i++ {
+		dot := strings.LastIndex(id, ".")
+		if dot == -1 {
+			return ""
+		}
+		id = id[:dot]
+	}
+	return id
+}
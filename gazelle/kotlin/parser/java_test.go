@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"testing"
+)
+
+var javaTestCases = []struct {
+	desc, java string
+	filename   string
+	pkg        string
+	imports    []string
+	symbols    []string
+	stars      []string
+	decls      []string
+}{
+	{
+		desc:     "empty",
+		java:     "",
+		filename: "Empty.java",
+		pkg:      "",
+		imports:  []string{},
+		symbols:  []string{},
+		stars:    []string{},
+		decls:    []string{},
+	},
+	{
+		desc: "simple",
+		java: `
+package a.b;
+
+import c.D;
+import e.f.*;
+import static g.H.i;
+import static j.K.*;
+
+public final class X {
+	class Inner {}
+}
+
+interface Y {}
+`,
+		filename: "Simple.java",
+		pkg:      "a.b",
+		imports:  []string{"c", "e.f", "g", "j"},
+		symbols:  []string{"c.D", "g.H", "j.K"},
+		stars:    []string{"e.f"},
+		decls:    []string{"X", "Y"},
+	},
+	{
+		desc: "comments",
+		java: `
+/* package x.y; */
+package /* z */ a;
+// import b.C;
+import d.E; // import f.G;
+`,
+		filename: "Comments.java",
+		pkg:      "a",
+		imports:  []string{"d"},
+		symbols:  []string{"d.E"},
+		stars:    []string{},
+		decls:    []string{},
+	},
+}
+
+func TestJavaParser(t *testing.T) {
+	for _, tc := range javaTestCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			res, _ := NewJavaParser().Parse(tc.filename, tc.java)
+
+			if !equal(res.Imports, tc.imports) {
+				t.Errorf("Imports...\nactual:  %#v;\nexpected: %#v\njava code:\n%v", res.Imports, tc.imports, tc.java)
+			}
+
+			if res.Package != tc.pkg {
+				t.Errorf("Package....\nactual:  %#v;\nexpected: %#v\njava code:\n%v", res.Package, tc.pkg, tc.java)
+			}
+
+			if !equal(res.ImportedSymbols, tc.symbols) {
+				t.Errorf("ImportedSymbols...\nactual:  %#v;\nexpected: %#v\njava code:\n%v", res.ImportedSymbols, tc.symbols, tc.java)
+			}
+
+			if !equal(res.StarImports, tc.stars) {
+				t.Errorf("StarImports...\nactual:  %#v;\nexpected: %#v\njava code:\n%v", res.StarImports, tc.stars, tc.java)
+			}
+
+			if !equal(res.Declarations, tc.decls) {
+				t.Errorf("Declarations...\nactual:  %#v;\nexpected: %#v\njava code:\n%v", res.Declarations, tc.decls, tc.java)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package parser
 import (
 	"fmt"
 	"os"
+	"regexp"
+	"slices"
 	"strings"
 
 	treeutils "aspect.build/cli/gazelle/common/treesitter"
@@ -11,18 +13,83 @@ import (
 )
 
 type ParseResult struct {
-	File    string
-	Imports []string
-	Package string
-	HasMain bool
+	File        string
+	Imports     []string
+	Package     string
+	HasMain     bool
+	Annotations []string
+
+	// The fully qualified names of the symbols imported by non-star imports such as `com.foo.Bar`
+	ImportedSymbols []string
+
+	// The packages imported by star imports such as `com.foo.*`
+	StarImports []string
+
+	// The fully qualified names of the symbols imported with an alias, by alias,
+	// such as `Baz` for `import com.foo.Bar as Baz`
+	ImportAliases map[string]string
+
+	// The imported symbols referenced in the file by simple name or alias, such as
+	// types, called functions, annotations or ServiceLoader types
+	UsedImports []string
+
+	// The names of top-level declarations such as classes, objects and functions
+	Declarations []string
+
+	// The types loaded using java.util.ServiceLoader such as `ServiceLoader.load(Foo::class.java)`
+	ServiceLoaderTypes []string
+
+	// The types referenced by public declarations such as parameter, return and property
+	// types or supertypes. Qualified types such as `Foo.Bar` are joined with ".".
+	PublicTypes []string
+
+	// The simple names referenced anywhere in the file as types, called functions or
+	// receivers such as `Foo` in `Foo.create()`, which may be declared in the same package
+	// without an import.
+	References []string
+
+	// The fully qualified symbols referenced without an import, such as `com.foo.Bar` in
+	// `com.foo.Bar.create()` or `val x: com.foo.Bar`
+	QualifiedReferences []string
+}
+
+// The registered query for all annotations such as `@Foo`, `@foo.Bar(x)` or `@field:Baz`.
+const annotationsQuery = "annotations"
+
+// The registered queries for the simple names referenced as types, called functions and navigation receivers.
+var referenceQueries = []string{"type_references", "call_references", "navigation_references"}
+
+// The registered queries for the dotted names that may be fully qualified references, such as `com.foo.Bar.create`.
+var qualifiedReferenceQueries = []string{"qualified_type_references", "qualified_navigation_references"}
+
+// The leading dotted name of a type or navigation expression, excluding any type
+// arguments, calls or safe calls such as `com.foo.Bar` in `com.foo.Bar<T>` or `com.foo.Bar?.x`.
+var dottedNameRegex = regexp.MustCompile(`^[A-Za-z_]\w*(?:\s*\.\s*[A-Za-z_]\w*)*`)
+
+// The registered query for all calls, filtered to java.util.ServiceLoader calls using serviceLoaderRegex.
+const callsQuery = "calls"
+
+func init() {
+	treeutils.MustRegisterQuery(treeutils.Kotlin, annotationsQuery, `(annotation) @annotation`)
+	treeutils.MustRegisterQuery(treeutils.Kotlin, "type_references", `(user_type . (type_identifier) @ref)`)
+	treeutils.MustRegisterQuery(treeutils.Kotlin, "call_references", `(call_expression . (simple_identifier) @ref)`)
+	treeutils.MustRegisterQuery(treeutils.Kotlin, "navigation_references", `(navigation_expression . (simple_identifier) @ref)`)
+	treeutils.MustRegisterQuery(treeutils.Kotlin, "qualified_type_references", `(user_type) @ref`)
+	treeutils.MustRegisterQuery(treeutils.Kotlin, "qualified_navigation_references", `(navigation_expression) @ref`)
+	treeutils.MustRegisterQuery(treeutils.Kotlin, callsQuery, `(call_expression) @call`)
 }
 
+var serviceLoaderRegex = regexp.MustCompile(`^(?:java\.util\.)?ServiceLoader\s*\.\s*load(?:Installed)?\s*\(\s*([\w.]+)\s*::\s*class\s*\.\s*java\b`)
+
 type Parser interface {
 	Parse(filePath, source string) (*ParseResult, []error)
 }
 
 type treeSitterParser struct {
 	Parser
+
+	// The kotlin language version of the sources such as "1.9", the latest if empty
+	languageVersion string
 }
 
 func NewParser() Parser {
@@ -31,10 +98,41 @@ func NewParser() Parser {
 	return &p
 }
 
+// NewParserForLanguageVersion returns a Parser of sources of the kotlin language version
+// such as "1.9", or of the latest version if empty.
+func NewParserForLanguageVersion(languageVersion string) Parser {
+	p := treeSitterParser{languageVersion: languageVersion}
+
+	return &p
+}
+
+// Whether the language version supports the feature introduced in the version.
+func (p *treeSitterParser) supports(major, minor int) bool {
+	if p.languageVersion == "" {
+		return true
+	}
+
+	var m, n int
+	if _, err := fmt.Sscanf(p.languageVersion, "%d.%d", &m, &n); err != nil {
+		return true
+	}
+	return m > major || (m == major && n >= minor)
+}
+
 func (p *treeSitterParser) Parse(filePath, source string) (*ParseResult, []error) {
 	var result = &ParseResult{
-		File:    filePath,
-		Imports: make([]string, 0),
+		File:                filePath,
+		Imports:             make([]string, 0),
+		Annotations:         make([]string, 0),
+		ServiceLoaderTypes:  make([]string, 0),
+		ImportedSymbols:     make([]string, 0),
+		StarImports:         make([]string, 0),
+		Declarations:        make([]string, 0),
+		PublicTypes:         make([]string, 0),
+		References:          make([]string, 0),
+		QualifiedReferences: make([]string, 0),
+		ImportAliases:       make(map[string]string),
+		UsedImports:         make([]string, 0),
 	}
 
 	errs := make([]error, 0)
@@ -47,6 +145,11 @@ func (p *treeSitterParser) Parse(filePath, source string) (*ParseResult, []error
 	}
 
 	if tree != nil {
+		defer tree.Close()
+
+		// The source code normalized to UTF-8
+		sourceCode = tree.(treeutils.TreeAst).SourceCode()
+
 		rootNode := tree.(treeutils.TreeAst).SitterTree.RootNode()
 
 		// Extract imports from the root nodes
@@ -69,6 +172,17 @@ func (p *treeSitterParser) Parse(filePath, source string) (*ParseResult, []error
 								}
 
 								result.Imports = append(result.Imports, readIdentifier(nodeK, sourceCode, !isStar))
+
+								if isStar {
+									result.StarImports = append(result.StarImports, readIdentifier(nodeK, sourceCode, false))
+								} else {
+									symbol := readIdentifier(nodeK, sourceCode, false)
+									result.ImportedSymbols = append(result.ImportedSymbols, symbol)
+
+									if alias := readImportAlias(nodeJ, sourceCode); alias != "" {
+										result.ImportAliases[alias] = symbol
+									}
+								}
 							}
 						}
 					}
@@ -81,13 +195,66 @@ func (p *treeSitterParser) Parse(filePath, source string) (*ParseResult, []error
 
 				result.Package = readIdentifier(getLoneChild(nodeI, "identifier"), sourceCode, false)
 			} else if nodeI.Type() == "function_declaration" {
+				// `main` functions without parameters are entrypoints since kotlin 1.3
 				nodeJ := getLoneChild(nodeI, "simple_identifier")
-				if nodeJ.Content(sourceCode) == "main" {
+				if nodeJ.Content(sourceCode) == "main" && (p.supports(1, 3) || hasValueParameters(nodeI)) {
 					result.HasMain = true
 				}
+
+				result.Declarations = append(result.Declarations, nodeJ.Content(sourceCode))
+			} else if nodeI.Type() == "class_declaration" || nodeI.Type() == "object_declaration" || nodeI.Type() == "type_alias" {
+				if nodeJ := treeutils.GetNodeChildByType(nodeI, "type_identifier"); nodeJ != nil {
+					result.Declarations = append(result.Declarations, nodeJ.Content(sourceCode))
+				}
+			} else if nodeI.Type() == "property_declaration" {
+				if nodeJ := treeutils.GetNodeChildByType(nodeI, "variable_declaration"); nodeJ != nil {
+					if nodeK := treeutils.GetNodeChildByType(nodeJ, "simple_identifier"); nodeK != nil {
+						result.Declarations = append(result.Declarations, nodeK.Content(sourceCode))
+					}
+				}
+			}
+
+			collectPublicTypes(nodeI, sourceCode, result)
+		}
+
+		// Extract the annotation names from anywhere within the file
+		for r := range tree.QueryNamed(annotationsQuery) {
+			if name := readAnnotationName(r.Captures()["annotation"]); name != "" {
+				result.Annotations = append(result.Annotations, name)
+			}
+		}
+
+		// Extract the types loaded using ServiceLoader from anywhere within the file
+		for r := range tree.QueryNamed(callsQuery) {
+			match := serviceLoaderRegex.FindStringSubmatch(r.Captures()["call"])
+			if match != nil && !slices.Contains(result.ServiceLoaderTypes, match[1]) {
+				result.ServiceLoaderTypes = append(result.ServiceLoaderTypes, match[1])
+			}
+		}
+
+		// Extract the simple names referenced from anywhere within the file
+		for _, query := range referenceQueries {
+			for _, ref := range tree.QueryNamedStrings(query, "ref") {
+				if !slices.Contains(result.References, ref) {
+					result.References = append(result.References, ref)
+				}
 			}
 		}
 
+		// Extract the fully qualified names referenced without an import
+		qualifiedNames := make([]string, 0)
+		for _, query := range qualifiedReferenceQueries {
+			qualifiedNames = append(qualifiedNames, tree.QueryNamedStrings(query, "ref")...)
+		}
+		qualifiedNames = append(qualifiedNames, result.Annotations...)
+		for _, name := range qualifiedNames {
+			if ref := readQualifiedReference(name, result); ref != "" && !slices.Contains(result.QualifiedReferences, ref) {
+				result.QualifiedReferences = append(result.QualifiedReferences, ref)
+			}
+		}
+
+		result.UsedImports = usedImports(result)
+
 		treeErrors := tree.QueryErrors()
 		if treeErrors != nil {
 			errs = append(errs, treeErrors...)
@@ -97,10 +264,107 @@ func (p *treeSitterParser) Parse(filePath, source string) (*ParseResult, []error
 	return result, errs
 }
 
+// Nodes of the declaration signatures that may contain types, excluding bodies and expressions.
+var signatureNodeTypes = map[string]bool{
+	"user_type":                  true,
+	"nullable_type":              true,
+	"function_type":              true,
+	"function_type_parameters":   true,
+	"parenthesized_type":         true,
+	"type_arguments":             true,
+	"type_projection":            true,
+	"function_value_parameters":  true,
+	"parameter":                  true,
+	"primary_constructor":        true,
+	"class_parameter":            true,
+	"variable_declaration":       true,
+	"delegation_specifier":       true,
+	"constructor_invocation":     true,
+	"type_parameters":            true,
+	"type_parameter":             true,
+	"type_constraints":           true,
+	"type_constraint":            true,
+	"multi_variable_declaration": true,
+}
+
+// Collect the types referenced by the signature of a declaration and its
+// members, if the declaration is not private or internal.
+func collectPublicTypes(node *sitter.Node, sourceCode []byte, result *ParseResult) {
+	switch node.Type() {
+	case "function_declaration", "property_declaration", "class_declaration", "object_declaration", "type_alias", "companion_object":
+	default:
+		return
+	}
+
+	if !isPublic(node, sourceCode) {
+		return
+	}
+
+	for i := 0; i < int(node.NamedChildCount()); i++ {
+		child := node.NamedChild(i)
+
+		if child.Type() == "class_body" {
+			for j := 0; j < int(child.NamedChildCount()); j++ {
+				collectPublicTypes(child.NamedChild(j), sourceCode, result)
+			}
+		} else if signatureNodeTypes[child.Type()] {
+			collectTypes(child, sourceCode, result)
+		}
+	}
+}
+
+func collectTypes(node *sitter.Node, sourceCode []byte, result *ParseResult) {
+	// Private or internal constructors and constructor properties
+	if (node.Type() == "primary_constructor" || node.Type() == "class_parameter") && !isPublic(node, sourceCode) {
+		return
+	}
+
+	if node.Type() == "user_type" {
+		var name strings.Builder
+		for i := 0; i < int(node.NamedChildCount()); i++ {
+			if nodeI := node.NamedChild(i); nodeI.Type() == "type_identifier" {
+				if name.Len() > 0 {
+					name.WriteString(".")
+				}
+				name.WriteString(nodeI.Content(sourceCode))
+			}
+		}
+
+		if name.Len() > 0 && !slices.Contains(result.PublicTypes, name.String()) {
+			result.PublicTypes = append(result.PublicTypes, name.String())
+		}
+	}
+
+	for i := 0; i < int(node.NamedChildCount()); i++ {
+		if child := node.NamedChild(i); signatureNodeTypes[child.Type()] {
+			collectTypes(child, sourceCode, result)
+		}
+	}
+}
+
+// Whether a declaration is visible outside of the module, declarations are public by default.
+func isPublic(node *sitter.Node, sourceCode []byte) bool {
+	if modifiers := treeutils.GetNodeChildByType(node, "modifiers"); modifiers != nil {
+		for i := 0; i < int(modifiers.NamedChildCount()); i++ {
+			if m := modifiers.NamedChild(i); m.Type() == "visibility_modifier" {
+				visibility := m.Content(sourceCode)
+				return visibility != "private" && visibility != "internal"
+			}
+		}
+	}
+	return true
+}
+
 type KotlinImports struct {
 	imports *treeset.Set
 }
 
+// Whether the function declaration has any value parameters.
+func hasValueParameters(function *sitter.Node) bool {
+	parameters := treeutils.GetNodeChildByType(function, "function_value_parameters")
+	return parameters != nil && parameters.NamedChildCount() > 0
+}
+
 func getLoneChild(node *sitter.Node, name string) *sitter.Node {
 	for i := 0; i < int(node.NamedChildCount()); i++ {
 		if node.NamedChild(i).Type() == name {
@@ -144,3 +408,103 @@ func readIdentifier(node *sitter.Node, sourceCode []byte, ignoreLast bool) strin
 
 	return s.String()
 }
+
+// Read the alias of an import header such as `Baz` in `import com.foo.Bar as Baz`, if any.
+func readImportAlias(importHeader *sitter.Node, sourceCode []byte) string {
+	alias := treeutils.GetNodeChildByType(importHeader, "import_alias")
+	if alias == nil {
+		return ""
+	}
+
+	for i := 0; i < int(alias.NamedChildCount()); i++ {
+		if name := alias.NamedChild(i); name.Type() == "type_identifier" || name.Type() == "simple_identifier" {
+			return name.Content(sourceCode)
+		}
+	}
+
+	return ""
+}
+
+// ImportedName returns the name a symbol imported by the file is referenced by,
+// the alias if imported with an alias.
+func (p *ParseResult) ImportedName(symbol string) string {
+	for alias, aliased := range p.ImportAliases {
+		if aliased == symbol {
+			return alias
+		}
+	}
+	return symbol[strings.LastIndex(symbol, ".")+1:]
+}
+
+// The imported symbols referenced by simple name or alias anywhere within the file.
+// Qualified references such as `Foo.Bar` reference the import of `Foo`.
+func usedImports(result *ParseResult) []string {
+	referenced := make(map[string]bool)
+	for _, names := range [][]string{result.References, result.PublicTypes, result.Annotations, result.ServiceLoaderTypes} {
+		for _, name := range names {
+			if dot := strings.Index(name, "."); dot != -1 {
+				name = name[:dot]
+			}
+			referenced[name] = true
+		}
+	}
+
+	used := make([]string, 0)
+	for _, symbol := range result.ImportedSymbols {
+		if referenced[result.ImportedName(symbol)] {
+			used = append(used, symbol)
+		}
+	}
+
+	return used
+}
+
+// Read the fully qualified symbol referenced by a dotted name such as `com.foo.Bar` in
+// `com.foo.Bar.create`, the lowercase package segments followed by the capitalized symbol.
+// Names starting with an imported or declared name, such as `foo.Bar` for `import x.foo`,
+// are not fully qualified.
+func readQualifiedReference(name string, result *ParseResult) string {
+	dotted := dottedNameRegex.FindString(name)
+	segments := strings.Split(strings.Join(strings.Fields(dotted), ""), ".")
+
+	first := segments[0]
+	if len(segments) < 2 || !isLowerIdentifier(first) || slices.Contains(result.Declarations, first) {
+		return ""
+	}
+	for _, symbol := range result.ImportedSymbols {
+		if result.ImportedName(symbol) == first {
+			return ""
+		}
+	}
+
+	for i := 1; i < len(segments); i++ {
+		if !isLowerIdentifier(segments[i]) {
+			return strings.Join(segments[:i+1], ".")
+		}
+	}
+
+	return ""
+}
+
+// Whether the identifier starts with a lowercase letter, such as a package segment.
+func isLowerIdentifier(s string) bool {
+	return s != "" && s[0] >= 'a' && s[0] <= 'z'
+}
+
+// Read the name of an annotation from the annotation source code, removing
+// the '@', use-site targets and any arguments.
+func readAnnotationName(annotation string) string {
+	name := strings.TrimSpace(strings.TrimPrefix(annotation, "@"))
+
+	// Use-site targets such as @field:Foo or @get:Foo
+	if i := strings.Index(name, ":"); i != -1 {
+		name = name[i+1:]
+	}
+
+	// Arguments and type arguments such as @Foo(x) or @Foo<T>
+	if i := strings.IndexAny(name, "(<"); i != -1 {
+		name = name[:i]
+	}
+
+	return strings.Join(strings.Fields(name), "")
+}
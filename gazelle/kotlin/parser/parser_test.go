@@ -2,6 +2,9 @@ package parser
 
 import (
 	"testing"
+
+	treeutils "aspect.build/cli/gazelle/common/treesitter"
+	"aspect.build/cli/gazelle/common/treesitter/treesittertest"
 )
 
 var testCases = []struct {
@@ -104,6 +107,189 @@ fun main() {}
 			t.Errorf("main method should be detected with imports")
 		}
 	})
+
+	t.Run("main detection of language versions", func(t *testing.T) {
+		res, _ := NewParserForLanguageVersion("1.2").Parse("main.kt", "fun main() {}")
+		if res.HasMain {
+			t.Errorf("main method without parameters should not be detected before kotlin 1.3")
+		}
+
+		res, _ = NewParserForLanguageVersion("1.2").Parse("main.kt", "fun main(args: Array<String>) {}")
+		if !res.HasMain {
+			t.Errorf("main method with parameters should be detected before kotlin 1.3")
+		}
+
+		res, _ = NewParserForLanguageVersion("1.9").Parse("main.kt", "fun main() {}")
+		if !res.HasMain {
+			t.Errorf("main method without parameters should be detected since kotlin 1.3")
+		}
+	})
+
+	t.Run("annotations", func(t *testing.T) {
+		res, _ := NewParser().Parse("x.kt", `
+package my.demo
+
+import kotlinx.serialization.Serializable
+
+@Serializable
+data class Data(@field:Json(name = "a") val a: Int)
+
+@javax.persistence.Entity
+class E {
+	@Deprecated("x")
+	fun f() {}
+}
+		`)
+
+		expected := []string{"Serializable", "Json", "javax.persistence.Entity", "Deprecated"}
+		if !equal(res.Annotations, expected) {
+			t.Errorf("Annotations...\nactual:  %#v;\nexpected: %#v", res.Annotations, expected)
+		}
+	})
+
+	t.Run("declarations and imported symbols", func(t *testing.T) {
+		res, _ := NewParser().Parse("x.kt", `
+package my.demo
+
+import a.b.C
+import d.e.F as G
+
+class A
+interface I
+object O
+typealias T = String
+fun f() {}
+val p = 1
+
+class B {
+	fun nested() {}
+}
+		`)
+
+		expectedSymbols := []string{"a.b.C", "d.e.F"}
+		if !equal(res.ImportedSymbols, expectedSymbols) {
+			t.Errorf("ImportedSymbols...\nactual:  %#v;\nexpected: %#v", res.ImportedSymbols, expectedSymbols)
+		}
+
+		expectedDecls := []string{"A", "I", "O", "T", "f", "p", "B"}
+		if !equal(res.Declarations, expectedDecls) {
+			t.Errorf("Declarations...\nactual:  %#v;\nexpected: %#v", res.Declarations, expectedDecls)
+		}
+	})
+
+	t.Run("service loader", func(t *testing.T) {
+		res, _ := NewParser().Parse("x.kt", `
+package my.demo
+
+import java.util.ServiceLoader
+
+val codecs = ServiceLoader.load(Codec::class.java).toList()
+val drivers = java.util.ServiceLoader.load(java.sql.Driver::class.java, loader)
+
+fun f() {
+	ServiceLoader.loadInstalled(Codec::class.java)
+	other.load(Ignored::class.java)
+}
+		`)
+
+		expected := []string{"Codec", "java.sql.Driver"}
+		if !equal(res.ServiceLoaderTypes, expected) {
+			t.Errorf("ServiceLoaderTypes...\nactual:  %#v;\nexpected: %#v", res.ServiceLoaderTypes, expected)
+		}
+	})
+
+	t.Run("public types", func(t *testing.T) {
+		res, _ := NewParser().Parse("x.kt", `
+package my.demo
+
+fun f(a: Foo, b: List<Bar>): Baz? = Impl()
+private fun g(a: Hidden): Hidden = TODO()
+internal val i: Hidden = Hidden()
+val p: Map<String, x.Qualified> = mapOf()
+
+open class C(val a: Ctor) : Base(), Iface<Arg> {
+	fun m(x: Member): Result = TODO()
+	private fun n(x: Hidden) {}
+	companion object {
+		fun make(): Made = TODO()
+	}
+}
+
+private class P(val x: Hidden)
+class Q private constructor(val x: Hidden)
+class R(private val x: Hidden, internal val y: Hidden, val z: Prop)
+typealias T = Aliased
+		`)
+
+		expected := []string{
+			"Foo", "List", "Bar", "Baz", "Map", "String", "x.Qualified",
+			"Ctor", "Base", "Iface", "Arg", "Member", "Result", "Made", "Prop", "Aliased",
+		}
+		if !equal(res.PublicTypes, expected) {
+			t.Errorf("PublicTypes...\nactual:  %#v;\nexpected: %#v", res.PublicTypes, expected)
+		}
+	})
+
+	t.Run("references", func(t *testing.T) {
+		res, _ := NewParser().Parse("x.kt", `
+package my.demo
+
+class C : Base() {
+	private val items = mutableListOf<Item>()
+
+	fun f(x: a.Qualified) = Helper.create(x)
+}
+		`)
+
+		expected := []string{"Base", "Item", "a", "mutableListOf", "Helper"}
+		if !equal(res.References, expected) {
+			t.Errorf("References...\nactual:  %#v;\nexpected: %#v", res.References, expected)
+		}
+	})
+
+	t.Run("qualified references", func(t *testing.T) {
+		res, _ := NewParser().Parse("x.kt", `
+package my.demo
+
+import a.b.foo
+
+val config = Config()
+
+@com.example.annotations.Marker
+fun f(x: com.example.model.Item<String>): Any {
+	foo.Bar.create()
+	config.Value
+	items.size
+	return com.example.util.Strings.join(x)
+}
+		`)
+
+		expected := []string{"com.example.annotations.Marker", "com.example.model.Item", "com.example.util.Strings"}
+		if !equal(res.QualifiedReferences, expected) {
+			t.Errorf("QualifiedReferences...\nactual:  %#v;\nexpected: %#v", res.QualifiedReferences, expected)
+		}
+	})
+
+	t.Run("import aliases", func(t *testing.T) {
+		res, _ := NewParser().Parse("x.kt", `
+package my.demo
+
+import a.b.Foo as Bar
+import a.b.Unused
+import c.d.create as make
+
+fun f(): Bar = make()
+		`)
+
+		if res.ImportAliases["Bar"] != "a.b.Foo" || res.ImportAliases["make"] != "c.d.create" || len(res.ImportAliases) != 2 {
+			t.Errorf("ImportAliases...\nactual:  %#v", res.ImportAliases)
+		}
+
+		expected := []string{"a.b.Foo", "c.d.create"}
+		if !equal(res.UsedImports, expected) {
+			t.Errorf("UsedImports...\nactual:  %#v;\nexpected: %#v", res.UsedImports, expected)
+		}
+	})
 }
 
 func equal[T comparable](a, b []T) bool {
@@ -117,3 +303,37 @@ func equal[T comparable](a, b []T) bool {
 	}
 	return true
 }
+
+func TestQueries(t *testing.T) {
+	source := `
+package my.demo
+
+import com.foo.Bar
+
+@Serializable
+class Data(val bar: Bar) {
+	fun f() = com.foo.Baz.create(bar).also { println(it) }
+}
+`
+
+	t.Run("annotations", func(t *testing.T) {
+		captures := treesittertest.RunNamedQueries(t, treeutils.Kotlin, source, annotationsQuery)
+		treesittertest.AssertCaptured(t, captures, "annotation", "@Serializable")
+	})
+
+	t.Run("references", func(t *testing.T) {
+		captures := treesittertest.RunNamedQueries(t, treeutils.Kotlin, source, referenceQueries...)
+		treesittertest.AssertCaptured(t, captures, "ref", "Serializable", "Bar", "println", "com")
+	})
+
+	t.Run("qualified references", func(t *testing.T) {
+		captures := treesittertest.RunNamedQueries(t, treeutils.Kotlin, source, qualifiedReferenceQueries...)
+		treesittertest.AssertMatchCount(t, captures, "qualified_type_references", 2)
+		treesittertest.AssertMatchCount(t, captures, "qualified_navigation_references", 4)
+	})
+
+	t.Run("calls", func(t *testing.T) {
+		captures := treesittertest.RunNamedQueries(t, treeutils.Kotlin, source, callsQuery)
+		treesittertest.AssertMatchCount(t, captures, callsQuery, 3)
+	})
+}
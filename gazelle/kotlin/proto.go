@@ -0,0 +1,201 @@
+package gazelle
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	BazelLog "aspect.build/cli/pkg/logger"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"github.com/emirpasic/gods/sets/treeset"
+)
+
+const (
+	ProtoLibrary         = "proto_library"
+	JavaProtoLibrary     = "java_proto_library"
+	JavaLiteProtoLibrary = "java_lite_proto_library"
+	KtJvmProtoLibrary    = "kt_jvm_proto_library"
+)
+
+// Rule kinds compiling the proto_library rules in their deps to java or kotlin code.
+// Never generated or indexed by the kotlin extension, only recorded while generating
+// so imports of the generated code can be resolved.
+var protoLibraryKinds = treeset.NewWithStringComparator(JavaProtoLibrary, JavaLiteProtoLibrary, KtJvmProtoLibrary)
+
+var protoPackageRe = regexp.MustCompile(`(?m)^\s*package\s+([\w.]+)\s*;`)
+var protoOptionRe = regexp.MustCompile(`(?m)^\s*option\s+(java_package|java_outer_classname)\s*=\s*"([\w.]*)"\s*;`)
+var protoTypeRe = regexp.MustCompile(`(?m)^(?:message|enum|service)\s+(\w+)`)
+
+// Record the proto_library and java_proto_library-like rules of the package, existing
+// or generated by other extensions such as the proto extension in this run.
+func (kt *kotlinLang) recordProtoRules(args language.GenerateArgs) {
+	rules := make([]*rule.Rule, 0, len(args.OtherGen))
+	if args.File != nil {
+		rules = append(rules, args.File.Rules...)
+	}
+	rules = append(rules, args.OtherGen...)
+
+	for _, r := range rules {
+		l := label.New("", args.Rel, r.Name())
+
+		if r.Kind() == ProtoLibrary {
+			srcs := make([]string, 0)
+			for _, src := range r.AttrStrings("srcs") {
+				srcs = append(srcs, filepath.Join(args.Dir, src))
+			}
+
+			if kt.protoLibraries == nil {
+				kt.protoLibraries = make(map[label.Label][]string)
+			}
+			kt.protoLibraries[l] = srcs
+		} else if protoLibraryKinds.Contains(r.Kind()) {
+			deps := make([]label.Label, 0)
+			for _, dep := range r.AttrStrings("deps") {
+				if d, err := label.Parse(dep); err == nil {
+					deps = append(deps, d.Abs("", args.Rel))
+				}
+			}
+
+			if kt.protoRules == nil {
+				kt.protoRules = make(map[label.Label][]label.Label)
+			}
+			kt.protoRules[l] = deps
+		}
+	}
+}
+
+// The java_proto_library-like rules providing each java package and outer class,
+// built once all packages have been generated.
+func (kt *kotlinLang) protoImportIndex() map[string][]label.Label {
+	if kt.protoImports != nil {
+		return kt.protoImports
+	}
+
+	kt.protoImports = make(map[string][]label.Label)
+
+	protoRules := make([]label.Label, 0, len(kt.protoRules))
+	for l := range kt.protoRules {
+		protoRules = append(protoRules, l)
+	}
+	slices.SortFunc(protoRules, func(a, b label.Label) int {
+		return strings.Compare(a.String(), b.String())
+	})
+
+	for _, l := range protoRules {
+		for _, dep := range kt.protoRules[l] {
+			srcs, found := kt.protoLibraries[dep]
+			if !found {
+				BazelLog.Debugf("proto_library %q of %q not found in the visited packages", dep.String(), l.String())
+				continue
+			}
+
+			for _, src := range srcs {
+				for _, imp := range protoJavaImports(src) {
+					if !slices.Contains(kt.protoImports[imp], l) {
+						kt.protoImports[imp] = append(kt.protoImports[imp], l)
+					}
+				}
+			}
+		}
+	}
+
+	return kt.protoImports
+}
+
+// Resolve an import of proto generated code by symbol before falling back to the package.
+func (kt *kotlinLang) resolveProtoImport(impt ImportStatement) (ResolutionType, *label.Label, error) {
+	index := kt.protoImportIndex()
+
+	for _, imp := range []string{impt.Symbol, impt.Imp} {
+		matches := index[imp]
+		if imp == "" || len(matches) == 0 {
+			continue
+		}
+
+		if len(matches) > 1 {
+			return Resolution_Error, nil, fmt.Errorf(
+				"Import %q from %q resolved to multiple proto targets (%s)"+
+					" - this must be fixed using the \"gazelle:resolve\" directive",
+				imp, impt.SourcePath, labelListString(matches))
+		}
+
+		return Resolution_Label, &matches[0], nil
+	}
+
+	return Resolution_NotFound, nil, nil
+}
+
+// protoJavaImports returns the java package of the code generated for a .proto file
+// and the fully qualified name of the outer class containing the generated types.
+func protoJavaImports(protoPath string) []string {
+	content, err := os.ReadFile(protoPath)
+	if err != nil {
+		BazelLog.Debugf("failed to read proto file %q: %v", protoPath, err)
+		return nil
+	}
+
+	var pkg, outerClass string
+	if m := protoPackageRe.FindSubmatch(content); m != nil {
+		pkg = string(m[1])
+	}
+
+	for _, m := range protoOptionRe.FindAllSubmatch(content, -1) {
+		switch string(m[1]) {
+		case "java_package":
+			pkg = string(m[2])
+		case "java_outer_classname":
+			outerClass = string(m[2])
+		}
+	}
+
+	// The outer class defaults to the camel-cased file name, suffixed if conflicting with a type
+	if outerClass == "" {
+		outerClass = toProtoOuterClassName(path.Base(filepath.ToSlash(protoPath)))
+
+		for _, m := range protoTypeRe.FindAllSubmatch(content, -1) {
+			if string(m[1]) == outerClass {
+				outerClass += "OuterClass"
+				break
+			}
+		}
+	}
+
+	if pkg == "" {
+		return []string{outerClass}
+	}
+
+	return []string{pkg, pkg + "." + outerClass}
+}
+
+// toProtoOuterClassName converts a .proto file name to the default outer class name
+// the same way as protoc, for example "foo_bar.proto" to "FooBar".
+func toProtoOuterClassName(protoFile string) string {
+	name := strings.TrimSuffix(protoFile, ".proto")
+
+	var b strings.Builder
+	upper := true
+	for _, c := range name {
+		switch {
+		case unicode.IsLetter(c):
+			if upper {
+				c = unicode.ToUpper(c)
+			}
+			b.WriteRune(c)
+			upper = false
+		case unicode.IsDigit(c):
+			b.WriteRune(c)
+			upper = true
+		default:
+			upper = true
+		}
+	}
+
+	return b.String()
+}
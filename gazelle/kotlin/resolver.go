@@ -1,17 +1,23 @@
 package gazelle
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"math"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	common "aspect.build/cli/gazelle/common"
 	"aspect.build/cli/gazelle/kotlin/kotlinconfig"
+	"aspect.build/cli/gazelle/kotlin/maven"
 	BazelLog "aspect.build/cli/pkg/logger"
 	"github.com/bazelbuild/bazel-gazelle/config"
 	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/language"
 	"github.com/bazelbuild/bazel-gazelle/repo"
 	"github.com/bazelbuild/bazel-gazelle/resolve"
 	"github.com/bazelbuild/bazel-gazelle/rule"
@@ -21,6 +27,7 @@ import (
 )
 
 var _ resolve.Resolver = (*kotlinLang)(nil)
+var _ language.LifecycleManager = (*kotlinLang)(nil)
 
 const (
 	Resolution_Error        = -1
@@ -32,6 +39,12 @@ const (
 
 type ResolutionType = int
 
+// The name of the rules_jvm java gazelle extension.
+const JavaLanguageName = "java"
+
+// The languages of the indexed import specs kotlin imports are resolved against, in order.
+var indexedLanguages = []string{LanguageName, JavaLanguageName}
+
 func (*kotlinLang) Name() string {
 	return LanguageName
 }
@@ -43,7 +56,7 @@ func (kt *kotlinLang) Imports(c *config.Config, r *rule.Rule, f *rule.File) []re
 	if r.PrivateAttr(packagesKey) != nil {
 		target, isLib := r.PrivateAttr(packagesKey).(*KotlinLibTarget)
 		if isLib {
-			provides := make([]resolve.ImportSpec, 0, target.Packages.Size())
+			provides := make([]resolve.ImportSpec, 0, target.Packages.Size()+target.Declarations.Size())
 			for _, pkg := range target.Packages.Values() {
 				provides = append(provides, resolve.ImportSpec{
 					Lang: LanguageName,
@@ -51,7 +64,16 @@ func (kt *kotlinLang) Imports(c *config.Config, r *rule.Rule, f *rule.File) []re
 				})
 			}
 
-			if len(provides) > 0 {
+			// Top-level declarations so symbols of packages spanning multiple targets can be resolved
+			for _, decl := range target.Declarations.Values() {
+				provides = append(provides, resolve.ImportSpec{
+					Lang: LanguageName,
+					Imp:  decl.(string),
+				})
+			}
+
+			// Libraries only embedding associates are indexed with the imports of the associates
+			if len(provides) > 0 || len(r.AttrStrings("associates")) > 0 {
 				return provides
 			}
 		}
@@ -60,110 +82,842 @@ func (kt *kotlinLang) Imports(c *config.Config, r *rule.Rule, f *rule.File) []re
 	return nil
 }
 
+// Embeds returns the libraries associated with a library, such as the implementation
+// library of a facade. The rule index attributes the imports provided by the associated
+// libraries to the associating library. Associates of binaries and tests are not
+// embedded, they are not depended upon.
 func (kt *kotlinLang) Embeds(r *rule.Rule, from label.Label) []label.Label {
-	return []label.Label{}
+	if r.Kind() != KtJvmLibrary {
+		return []label.Label{}
+	}
+
+	embeds := make([]label.Label, 0)
+	for _, associate := range r.AttrStrings("associates") {
+		l, err := label.Parse(associate)
+		if err != nil {
+			BazelLog.Warnf("Failed to parse associate %q of %q: %v", associate, from.String(), err)
+			continue
+		}
+		embeds = append(embeds, l.Abs(from.Repo, from.Pkg))
+	}
+
+	return embeds
 }
 
 func (kt *kotlinLang) Resolve(c *config.Config, ix *resolve.RuleIndex, rc *repo.RemoteCache, r *rule.Rule, importData interface{}, from label.Label) {
 	start := time.Now()
 	BazelLog.Infof("Resolve(%s): //%s:%s", LanguageName, from.Pkg, r.Name())
 
-	if r.Kind() == KtJvmLibrary || r.Kind() == KtJvmBinary {
+	if r.Kind() == KtJvmLibrary || r.Kind() == KtJvmBinary || r.Kind() == KtJvmTest {
 		var target KotlinTarget
+		var libTarget *KotlinLibTarget
+		var testTarget *KotlinTestTarget
 
-		if r.Kind() == KtJvmLibrary {
-			target = importData.(*KotlinLibTarget).KotlinTarget
-		} else {
-			target = importData.(*KotlinBinTarget).KotlinTarget
+		switch t := importData.(type) {
+		case *KotlinLibTarget:
+			target = t.KotlinTarget
+			libTarget = t
+		case *KotlinBinTarget:
+			target = t.KotlinTarget
+		case *KotlinTestTarget:
+			target = t.KotlinTarget
+			testTarget = t
 		}
 
-		deps, err := kt.resolveImports(c, ix, target.Imports, from)
-		if err != nil {
-			log.Fatalf("Resolution Error: %v", err)
-			os.Exit(1)
+		deps, compileOnlyDeps, errs := kt.resolveImports(c, ix, target.Imports, from)
+		if len(errs) > 0 {
+			kt.recordResolutionErrors(from, errs)
+		}
+
+		// The deps of binaries and tests are only added to unless strict deps is enabled,
+		// deps of libraries are replaced by gazelle when merging.
+		cfg := c.Exts[LanguageName].(kotlinconfig.Configs)[from.Pkg]
+		// The existing deps only preserved, not added by the configuration, for the dependency graph
+		preservedDeps := common.NewLabelSet(from)
+		if !cfg.StrictDeps() && preservedDepsKinds.Contains(r.Kind()) {
+			addExistingLabels(deps, r, existingDepsKey, from)
+			addExistingLabels(preservedDeps, r, existingDepsKey, from)
+		}
+
+		// Deps of the code generated by annotation processors
+		for _, v := range target.ProcessorDeps.Values() {
+			processorDep := v.(label.Label)
+			deps.Add(&processorDep)
+			preservedDeps.Remove(&processorDep)
+		}
+
+		// Deps of the test frameworks used by tests, such as test engines
+		if testTarget != nil {
+			for _, framework := range testTarget.Frameworks.Values() {
+				for _, frameworkDep := range cfg.TestFrameworkDeps(framework.(string)) {
+					deps.Add(&frameworkDep)
+					preservedDeps.Remove(&frameworkDep)
+				}
+			}
+		}
+
+		// Deps configured for all generated rules of the kind
+		for _, extraDep := range cfg.ExtraDeps(r.Kind()) {
+			deps.Add(&extraDep)
+			preservedDeps.Remove(&extraDep)
+		}
+
+		// Associated libraries are available without a dependency
+		for _, associate := range r.AttrStrings("associates") {
+			if l, err := label.Parse(associate); err == nil {
+				l = l.Abs(from.Repo, from.Pkg)
+				deps.Remove(&l)
+			}
 		}
 
 		if !deps.Empty() {
 			r.SetAttr("deps", deps.Labels())
 		}
+
+		// Libraries also required at runtime by other imports are only in `deps`
+		for _, dep := range deps.Labels() {
+			compileOnlyDeps.Remove(&dep)
+		}
+		if !compileOnlyDeps.Empty() {
+			r.SetAttr(compileOnlyDepsAttr, compileOnlyDeps.Labels())
+		}
+
+		if r.Kind() == KtJvmLibrary {
+			kt.resolveTestonly(c, ix, r, from)
+		}
+
+		kt.validateTestonlyDeps(c, ix, r, deps, from)
+
+		if r.Kind() == KtJvmLibrary {
+
+			// Deps surfaced in the public API of the library
+			if libTarget != nil {
+				exports := kt.resolveExports(c, ix, libTarget, from)
+				if !exports.Empty() {
+					r.SetAttr("exports", exports.Labels())
+				}
+			}
+		}
+
+		kt.recordDepGraphTarget(r, from, deps, compileOnlyDeps, preservedDeps)
+	}
+
+	if kt.changeReportFile != "" {
+		kt.recordResolvedChanges(r, from)
 	}
 
 	BazelLog.Infof("Resolve(%s): //%s:%s DONE in %s", LanguageName, from.Pkg, r.Name(), time.Since(start).String())
 }
 
+// Before resets the source files claimed by previous runs within the same process.
+func (kt *kotlinLang) Before(ctx context.Context) {
+	common.ResetSourceOwners()
+}
+
+// AfterResolvingDeps writes the change and unresolved import reports, the dependency graph and the resolution cache, if enabled, and
+// reports the imports failing to resolve and the failed validations. The run fails once the BUILD files are written, see Failure.
+func (kt *kotlinLang) AfterResolvingDeps(ctx context.Context) {
+	kt.writeChangeReport()
+	kt.writeUnresolvedReport()
+	kt.writeDepGraph()
+
+	if kt.resolutionCache != nil {
+		kt.resolutionCache.save(kt.resolutionCacheFile)
+	}
+
+	if kt.unresolvedImports > 0 {
+		fmt.Fprintf(os.Stderr, "Failed to validate kotlin dependencies: %d import(s) could not be resolved\n", kt.unresolvedImports)
+	}
+	if kt.fallbackImports > 0 {
+		fmt.Fprintf(os.Stderr, "%d kotlin import(s) could not be resolved and depend upon the kotlin_fallback_dep\n", kt.fallbackImports)
+	}
+	if kt.testonlyViolations > 0 {
+		fmt.Fprintf(os.Stderr, "Failed to validate kotlin dependencies: %d dependencies upon testonly targets\n", kt.testonlyViolations)
+	}
+	if len(kt.resolutionErrors) > 0 {
+		kt.printResolutionErrors()
+	}
+}
+
+// Failure returns the error failing the run once the BUILD files are written, if any import
+// failed to resolve, or if imports were not resolved or testonly targets depended upon where
+// validation is set to "error". The deps failing to resolve are omitted from the BUILD files.
+func (kt *kotlinLang) Failure() error {
+	if kt.unresolvedImports > 0 || kt.testonlyViolations > 0 || len(kt.resolutionErrors) > 0 {
+		return fmt.Errorf("failed to resolve or validate kotlin dependencies")
+	}
+	return nil
+}
+
+// Add the labels of the existing rule recorded at generation in the private attribute.
+func addExistingLabels(labels *common.LabelSet, r *rule.Rule, key string, from label.Label) {
+	existing, _ := r.PrivateAttr(key).([]string)
+	for _, s := range existing {
+		l, err := label.Parse(s)
+		if err != nil {
+			BazelLog.Warnf("Failed to parse existing label %q of %q: %v", s, from.String(), err)
+			continue
+		}
+
+		l = l.Abs(from.Repo, from.Pkg)
+		labels.Add(&l)
+	}
+}
+
+// Resolve the imports of a target to the labels of its dependencies and compile-only
+// dependencies, and the errors of imports failing to resolve. Imports not provided by
+// rules or the standard libraries are resolved against the maven repositories in one
+// batch, resolving each package once.
 func (kt *kotlinLang) resolveImports(
 	c *config.Config,
 	ix *resolve.RuleIndex,
 	imports *treeset.Set,
 	from label.Label,
-) (*common.LabelSet, error) {
+) (*common.LabelSet, *common.LabelSet, []error) {
 	deps := common.NewLabelSet(from)
+	compileOnlyDeps := common.NewLabelSet(from)
+	errs := make([]error, 0)
+	cfg := c.Exts[LanguageName].(kotlinconfig.Configs)[from.Pkg]
+
+	// The imports to resolve against the maven repositories, and their traces
+	mavenImports := make([]ImportStatement, 0)
+	mavenTraces := make([]*resolutionTrace, 0)
 
 	it := imports.Iterator()
 	for it.Next() {
 		mod := it.Value().(ImportStatement)
 
-		resolutionType, dep, err := kt.resolveImport(c, ix, mod, from)
+		kt.startTrace(cfg, mod, from)
+
+		if mod.IsStar {
+			if starDeps := kt.resolveStarImport(c, ix, mod, from); starDeps != nil {
+				kt.endTrace(Resolution_Label, starDeps, nil)
+
+				if len(starDeps) > 1 {
+					fmt.Printf("Resolution warning: star import %q from %q is provided by multiple targets (%s) - depending on all of them\n",
+						mod.Imp, mod.SourcePath, labelListString(starDeps))
+				}
+
+				for i := range starDeps {
+					rewritten := rewriteLabel(c, cfg, starDeps[i])
+					kt.recordDepGraphImport(mod, from, rewritten)
+					deps.Add(&rewritten)
+				}
+				continue
+			}
+		}
+
+		resolutionType, dep, err := kt.resolveLocalImport(c, ix, mod, from)
+		if resolutionType == Resolution_NotFound && err == nil && !mod.IsSamePackage {
+			mavenImports = append(mavenImports, mod)
+			mavenTraces = append(mavenTraces, kt.trace)
+			kt.trace = nil
+			continue
+		}
+
+		kt.endTrace(resolutionType, labelList(dep), err)
+
 		if err != nil {
-			return nil, err
+			// Only the dependency of the import is skipped, reported once all rules are resolved
+			errs = append(errs, err)
+			continue
+		}
+
+		kt.addResolvedImport(c, cfg, mod, resolutionType, dep, from, deps, compileOnlyDeps)
+	}
+
+	// Each package resolved once, imports of packages provided by multiple artifacts reported once
+	mavenResolutions := make(map[string]mavenResolution)
+	ambiguousImports := make(map[string][]ImportStatement)
+	for i, mod := range mavenImports {
+		kt.trace = mavenTraces[i]
+
+		resolution, resolved := mavenResolutions[mod.Imp]
+		if !resolved {
+			resolution.resolutionType, resolution.dep, resolution.err = kt.resolveMavenPackage(c, cfg, mod.Imp)
+			mavenResolutions[mod.Imp] = resolution
+		} else {
+			kt.traceStep("maven artifacts: %s already resolved for the target", mod.Imp)
+		}
+
+		kt.endTrace(resolution.resolutionType, labelList(resolution.dep), resolution.err)
+
+		var ambiguous *maven.AmbiguousPackageError
+		if errors.As(resolution.err, &ambiguous) {
+			BazelLog.Debugf("import '%s' for target '%s' is ambiguous: %v", mod.Imp, from.String(), ambiguous)
+
+			kt.recordUnresolvedImport(mod, from, ambiguous.Artifacts)
+			ambiguousImports[mod.Imp] = append(ambiguousImports[mod.Imp], mod)
+			continue
 		}
 
-		if resolutionType == Resolution_NotFound {
-			BazelLog.Debugf("import '%s' for target '%s' not found", mod.Imp, from.String())
+		if resolution.err != nil {
+			errs = append(errs, resolution.err)
+			continue
+		}
 
-			notFound := fmt.Errorf(
-				"Import %[1]q from %[2]q is an unknown dependency. Possible solutions:\n"+
-					"\t1. Instruct Gazelle to resolve to a known dependency using a directive:\n"+
-					"\t\t# gazelle:resolve [src-lang] kotlin import-string label\n",
-				mod.Imp, mod.SourcePath,
-			)
+		kt.addResolvedImport(c, cfg, mod, resolution.resolutionType, resolution.dep, from, deps, compileOnlyDeps)
+	}
 
-			fmt.Printf("Resolution error %v\n", notFound)
+	// Imports provided by multiple maven artifacts are reported as unresolved, once per package
+	for _, mod := range mavenImports {
+		ambiguousMods, isAmbiguous := ambiguousImports[mod.Imp]
+		if !isAmbiguous {
 			continue
 		}
+		delete(ambiguousImports, mod.Imp)
 
-		if resolutionType == Resolution_NativeKotlin || resolutionType == Resolution_None {
+		validation := cfg.ValidateImportStatements()
+		if validation == kotlinconfig.ValidationOff {
 			continue
 		}
 
-		if dep != nil {
-			deps.Add(dep)
+		if validation == kotlinconfig.ValidationError {
+			kt.unresolvedImports += len(ambiguousMods)
+		}
+
+		var ambiguous *maven.AmbiguousPackageError
+		errors.As(mavenResolutions[mod.Imp].err, &ambiguous)
+
+		sources := make([]string, 0, len(ambiguousMods))
+		for _, ambiguousMod := range ambiguousMods {
+			if source := strconv.Quote(ambiguousMod.SourcePath); !slices.Contains(sources, source) {
+				sources = append(sources, source)
+			}
 		}
+
+		ambiguousErr := fmt.Errorf(
+			"Import %[1]q from %[2]s is provided by multiple maven artifacts (%[3]s). Possible solutions:\n"+
+				"\t1. Instruct Gazelle to resolve to one of the artifacts using a directive:\n"+
+				"\t\t# gazelle:resolve kotlin %[1]s %[4]s\n",
+			mod.Imp, strings.Join(sources, ", "), labelListString(ambiguous.Artifacts), ambiguous.Artifacts[0].String(),
+		)
+
+		fmt.Printf("Resolution error %v\n", ambiguousErr)
 	}
 
-	return deps, nil
+	return deps, compileOnlyDeps, errs
+}
+
+// The result of resolving a package against the maven repositories.
+type mavenResolution struct {
+	resolutionType ResolutionType
+	dep            *label.Label
+	err            error
 }
 
+// Add the dependency an import resolved to, or report the import if not found.
+func (kt *kotlinLang) addResolvedImport(
+	c *config.Config,
+	cfg *kotlinconfig.KotlinConfig,
+	mod ImportStatement,
+	resolutionType ResolutionType,
+	dep *label.Label,
+	from label.Label,
+	deps, compileOnlyDeps *common.LabelSet,
+) {
+	// Fully qualified references are only resolved if found, possibly not a reference
+	if resolutionType == Resolution_NotFound && mod.IsQualifiedReference {
+		BazelLog.Debugf("qualified reference '%s' for target '%s' not found", mod.Symbol, from.String())
+		return
+	}
+
+	if resolutionType == Resolution_NotFound {
+		if fallback := cfg.FallbackDep(); fallback != nil {
+			BazelLog.Debugf("import '%s' for target '%s' not found, resolved to the fallback %s", mod.Imp, from.String(), fallback.String())
+
+			kt.recordFallbackImport(mod, from, *fallback)
+			kt.recordDepGraphImport(mod, from, *fallback)
+			deps.Add(fallback)
+			return
+		}
+
+		BazelLog.Debugf("import '%s' for target '%s' not found", mod.Imp, from.String())
+
+		kt.recordUnresolvedImport(mod, from, nil)
+
+		validation := cfg.ValidateImportStatements()
+		if validation == kotlinconfig.ValidationOff {
+			return
+		}
+
+		if validation == kotlinconfig.ValidationError {
+			kt.unresolvedImports++
+		}
+
+		notFound := fmt.Errorf(
+			"Import %[1]q from %[2]q is an unknown dependency. Possible solutions:\n"+
+				"\t1. Instruct Gazelle to resolve to a known dependency using a directive:\n"+
+				"\t\t# gazelle:resolve [src-lang] kotlin import-string label\n",
+			mod.Imp, mod.SourcePath,
+		)
+
+		fmt.Printf("Resolution error %v\n", notFound)
+		return
+	}
+
+	if resolutionType == Resolution_NativeKotlin || resolutionType == Resolution_None || dep == nil {
+		return
+	}
+
+	rewritten := rewriteLabel(c, cfg, *dep)
+
+	// Dependencies only required at compile time
+	imp := mod.Imp
+	if mod.Symbol != "" {
+		imp = mod.Symbol
+	}
+	if compileOnly := cfg.CompileOnlyImport(imp); compileOnly != nil {
+		if compileOnly.Label != nil {
+			kt.recordDepGraphImport(mod, from, *compileOnly.Label)
+			deps.Add(compileOnly.Label)
+		} else {
+			kt.recordDepGraphImport(mod, from, rewritten)
+			compileOnlyDeps.Add(&rewritten)
+		}
+		return
+	}
+
+	kt.recordDepGraphImport(mod, from, rewritten)
+	deps.Add(&rewritten)
+}
+
+// The list of the label, empty if nil.
+func labelList(l *label.Label) []label.Label {
+	if l == nil {
+		return nil
+	}
+	return []label.Label{*l}
+}
+
+// Resolve an import to the rules, the standard libraries or the maven repositories.
 func (kt *kotlinLang) resolveImport(
 	c *config.Config,
 	ix *resolve.RuleIndex,
 	impt ImportStatement,
 	from label.Label,
 ) (ResolutionType, *label.Label, error) {
-	imptSpec := impt.ImportSpec
+	resolutionType, dep, err := kt.resolveLocalImport(c, ix, impt, from)
+	if resolutionType != Resolution_NotFound || err != nil || impt.IsSamePackage {
+		return resolutionType, dep, err
+	}
+
+	cfg := c.Exts[LanguageName].(kotlinconfig.Configs)[from.Pkg]
+	return kt.resolveMavenPackage(c, cfg, impt.Imp)
+}
+
+// Resolve an import to the rules or the standard libraries, returning Resolution_NotFound
+// for imports to resolve against the maven repositories.
+func (kt *kotlinLang) resolveLocalImport(
+	c *config.Config,
+	ix *resolve.RuleIndex,
+	impt ImportStatement,
+	from label.Label,
+) (ResolutionType, *label.Label, error) {
+	// Names referenced without an import, only resolved if declared by another target
+	if impt.IsSamePackage {
+		return kt.resolveSamePackageReference(c, ix, impt, from)
+	}
+
+	cfgs := c.Exts[LanguageName].(kotlinconfig.Configs)
+	cfg, _ := cfgs[from.Pkg]
+
+	// Code generated at build time, such as Dagger components
+	imp := impt.Imp
+	if impt.Symbol != "" {
+		imp = impt.Symbol
+	}
+	if generated := cfg.GeneratedImport(imp); generated != nil {
+		kt.traceStep("kotlin_generated_import %s: matched", generated.Pattern)
+		if generated.Label == nil || generated.Label.Equal(from) {
+			return Resolution_None, nil, nil
+		}
+		return Resolution_Label, generated.Label, nil
+	}
+
+	// Regular expressions resolving families of imports
+	if resolved := resolveRegexpImport(c, cfg, impt); resolved != nil {
+		kt.traceStep("kotlin_resolve_regexp: matched %s", resolved.String())
+		if resolved.Equal(from) {
+			return Resolution_None, nil, nil
+		}
+		return Resolution_Label, resolved, nil
+	}
+
+	// Kotlin rules, falling back to rules indexed by the java extension
+	for _, lang := range indexedLanguages {
+		// Fully qualified symbols such as classes, before falling back to the package
+		if impt.Symbol != "" {
+			symbolSpec := resolve.ImportSpec{Lang: lang, Imp: impt.Symbol}
+			if resolutionType, dep, err := kt.resolveIndexedImport(c, ix, symbolSpec, impt, from); resolutionType != Resolution_NotFound {
+				return resolutionType, dep, err
+			}
+		}
 
+		packageSpec := resolve.ImportSpec{Lang: lang, Imp: impt.Imp}
+		if resolutionType, dep, err := kt.resolveIndexedImport(c, ix, packageSpec, impt, from); resolutionType != Resolution_NotFound {
+			return resolutionType, dep, err
+		}
+	}
+
+	// Rules providing a parent package, up to the configured depth
+	parent := impt.Imp
+	for depth := 0; depth < cfg.PackageFallbackDepth(); depth++ {
+		dot := strings.LastIndex(parent, ".")
+		if dot == -1 {
+			break
+		}
+		parent = parent[:dot]
+		kt.traceStep("parent package %s", parent)
+
+		for _, lang := range indexedLanguages {
+			parentSpec := resolve.ImportSpec{Lang: lang, Imp: parent}
+			if resolutionType, dep, err := kt.resolveIndexedImport(c, ix, parentSpec, impt, from); resolutionType != Resolution_NotFound {
+				return resolutionType, dep, err
+			}
+		}
+	}
+
+	// Source trees without rules indexed by the symbol index files
+	if resolutionType, dep, err := kt.resolveSymbolIndexImport(c, cfg, impt, from); resolutionType != Resolution_NotFound {
+		return resolutionType, dep, err
+	}
+
+	// Code generated by java_proto_library-like rules
+	if resolutionType, dep, err := kt.resolveProtoImport(impt); resolutionType != Resolution_NotFound {
+		kt.traceStep("proto rules providing %s: found", imp)
+		return resolutionType, dep, err
+	}
+	kt.traceStep("proto rules providing %s: not found", imp)
+
+	// Native kotlin imports
+	if isNativeImport(impt.Imp, cfg.NativeImports()) {
+		kt.traceStep("kotlin and java standard libraries: native")
+		return Resolution_NativeKotlin, nil, nil
+	}
+	kt.traceStep("kotlin and java standard libraries: not native")
+
+	return Resolution_NotFound, nil, nil
+}
+
+// Resolve an import against the symbol index files in order, the fully qualified symbol
+// before falling back to the package.
+func (kt *kotlinLang) resolveSymbolIndexImport(
+	c *config.Config,
+	cfg *kotlinconfig.KotlinConfig,
+	impt ImportStatement,
+	from label.Label,
+) (ResolutionType, *label.Label, error) {
+	for _, indexFile := range cfg.SymbolIndexes() {
+		idx := kt.symbolIndexes[indexFile]
+
+		var matches []label.Label
+		if impt.Symbol != "" {
+			matches = idx.ResolveSymbol(impt.Symbol)
+		}
+		if len(matches) == 0 {
+			matches = idx.ResolvePackage(impt.Imp)
+		}
+		if len(matches) == 0 {
+			kt.traceStep("symbol index %s: not found", indexFile)
+			continue
+		}
+		kt.traceStep("symbol index %s: %s", indexFile, labelListString(matches))
+
+		// Prevent from adding itself as a dependency.
+		for _, match := range matches {
+			if match.Equal(from) {
+				return Resolution_None, nil, nil
+			}
+		}
+
+		if len(matches) > 1 {
+			matches = rankProviders(c, cfg, matches, from)
+		}
+		if len(matches) > 1 {
+			return Resolution_Error, nil, fmt.Errorf(
+				"Import %q from %q resolved to multiple targets of the symbol index %s (%s)"+
+					" - this must be fixed using the \"gazelle:resolve\" or \"gazelle:kotlin_prefer_provider\" directive",
+				impt.Imp, impt.SourcePath, indexFile, labelListString(matches))
+		}
+
+		return Resolution_Label, &matches[0], nil
+	}
+
+	return Resolution_NotFound, nil, nil
+}
+
+// Resolve a package against the maven repositories in order.
+func (kt *kotlinLang) resolveMavenPackage(c *config.Config, cfg *kotlinconfig.KotlinConfig, pkg string) (ResolutionType, *label.Label, error) {
+	jvm_import := jvm_types.NewPackageName(pkg)
+
+	// Maven imports, querying each repository in order
+	for _, repository := range cfg.MavenRepositories() {
+		l, mavenError := kt.cachedMavenResolve(cfg, repository, jvm_import.Name, func() (label.Label, error) {
+			return kt.resolveMavenImport(cfg, repository, jvm_import)
+		})
+		if mavenError == nil {
+			l = toApparentRepoLabel(c, l)
+			kt.traceStep("maven artifacts of @%s: %s", repository.Name, l.String())
+			return Resolution_Label, &l, nil
+		}
+		kt.traceStep("maven artifacts of @%s: %v", repository.Name, mavenError)
+
+		var ambiguous *maven.AmbiguousPackageError
+		if errors.As(mavenError, &ambiguous) {
+			artifacts := make([]label.Label, len(ambiguous.Artifacts))
+			for i, artifact := range ambiguous.Artifacts {
+				artifacts[i] = toApparentRepoLabel(c, artifact)
+			}
+			return Resolution_Error, nil, &maven.AmbiguousPackageError{Package: ambiguous.Package, Artifacts: artifacts}
+		}
+
+		BazelLog.Debugf("Maven resolution error in @%s: %v", repository.Name, mavenError)
+	}
+
+	return Resolution_NotFound, nil, nil
+}
+
+// Resolve a name referenced without an import to another target declaring it in the
+// same package. Returns Resolution_None if not declared by exactly one other target.
+func (kt *kotlinLang) resolveSamePackageReference(
+	c *config.Config,
+	ix *resolve.RuleIndex,
+	impt ImportStatement,
+	from label.Label,
+) (ResolutionType, *label.Label, error) {
+	for _, lang := range indexedLanguages {
+		symbolSpec := resolve.ImportSpec{Lang: lang, Imp: impt.Symbol}
+
+		matches := ix.FindRulesByImportWithConfig(c, symbolSpec, lang)
+		for _, match := range matches {
+			// Declared by the referencing target itself
+			if match.IsSelfImport(from) {
+				return Resolution_None, nil, nil
+			}
+		}
+
+		if len(matches) > 1 {
+			BazelLog.Debugf("%q referenced from %q is declared by multiple targets (%s)", impt.Symbol, impt.SourcePath, targetListFromResults(matches))
+			return Resolution_None, nil, nil
+		}
+
+		if len(matches) == 1 {
+			return Resolution_Label, &matches[0].Label, nil
+		}
+	}
+
+	return Resolution_None, nil, nil
+}
+
+// Resolve an import using the kotlin_resolve_regexp directives, matching the symbol before
+// the package. Imports resolved by an exact `resolve` directive are never matched.
+func resolveRegexpImport(c *config.Config, cfg *kotlinconfig.KotlinConfig, impt ImportStatement) *label.Label {
+	candidates := make([]string, 0, 2)
+	if impt.Symbol != "" {
+		candidates = append(candidates, impt.Symbol)
+	}
+	candidates = append(candidates, impt.Imp)
+
+	for _, lang := range indexedLanguages {
+		for _, candidate := range candidates {
+			if _, ok := resolve.FindRuleWithOverride(c, resolve.ImportSpec{Lang: lang, Imp: candidate}, LanguageName); ok {
+				return nil
+			}
+		}
+	}
+
+	for _, candidate := range candidates {
+		if resolved := cfg.ResolveRegexp(candidate); resolved != nil {
+			return resolved
+		}
+	}
+
+	return nil
+}
+
+// Rewrite a resolved dependency label using the kotlin_label_rewrite directives.
+func rewriteLabel(c *config.Config, cfg *kotlinconfig.KotlinConfig, l label.Label) label.Label {
+	mainRepoLabel := toMainRepoLabel(c, l)
+
+	rewritten, err := cfg.RewriteLabel(mainRepoLabel)
+	if err != nil {
+		BazelLog.Warnf("Failed to rewrite label %q: %v", l.String(), err)
+		return l
+	}
+
+	// Labels not rewritten keep the repository of the index
+	if rewritten == mainRepoLabel {
+		return l
+	}
+	return rewritten
+}
+
+// Convert the repository of a maven label to the apparent repository name within the main
+// module when using bzlmod, using the module mapping gazelle applies to the loads of
+// module aware languages.
+// Canonical names such as `rules_jvm_external~~maven~maven` are converted to the name
+// of the repository created by the maven extension, such as `maven`.
+func toApparentRepoLabel(c *config.Config, l label.Label) label.Label {
+	if l.Repo == "" {
+		return l
+	}
+
+	if sep := strings.LastIndexAny(l.Repo, "~+"); sep != -1 {
+		l.Repo = l.Repo[sep+1:]
+	}
+
+	if c.ModuleToApparentName != nil {
+		if apparentName := c.ModuleToApparentName(l.Repo); apparentName != "" {
+			l.Repo = apparentName
+		}
+	}
+
+	return l
+}
+
+// Resolve a package against a maven_install repository. Returns a *maven.AmbiguousPackageError
+// if the package is provided by multiple artifacts of the repository.
+func (kt *kotlinLang) resolveMavenImport(cfg *kotlinconfig.KotlinConfig, repository kotlinconfig.MavenRepository, pkg jvm_types.PackageName) (label.Label, error) {
+	mavenResolver, exists := kt.mavenResolvers[mavenResolverKey{mode: cfg.MavenResolver(), installFile: repository.InstallFile}]
+	if !exists {
+		return label.NoLabel, fmt.Errorf("no Maven resolver for %q", repository.InstallFile)
+	}
+
+	excluded := excludedArtifacts(cfg, repository)
+
+	l, err := mavenResolver.Resolve(pkg, excluded, repository.Name)
+	if err == nil || cfg.MavenResolver() == kotlinconfig.MavenResolverBuiltin {
+		return l, err
+	}
+
+	// Errors of the rules_jvm resolver are not structured, determine whether the
+	// package is provided by multiple artifacts using the builtin resolver.
+	kt.initMavenResolver(kotlinconfig.MavenResolverBuiltin, repository.InstallFile)
+	builtinResolver := kt.mavenResolvers[mavenResolverKey{mode: kotlinconfig.MavenResolverBuiltin, installFile: repository.InstallFile}]
+
+	var ambiguous *maven.AmbiguousPackageError
+	if _, builtinErr := builtinResolver.Resolve(pkg, excluded, repository.Name); errors.As(builtinErr, &ambiguous) {
+		return label.NoLabel, ambiguous
+	}
+
+	return l, err
+}
+
+// The maven artifacts excluded from the resolution of imports against the repository,
+// by the java_exclude_artifact labels and the kotlin_maven_exclude_artifact coordinates.
+// Coordinates are added both as is and as labels of the repository, as the rules_jvm
+// resolver only excludes labels.
+func excludedArtifacts(cfg *kotlinconfig.KotlinConfig, repository kotlinconfig.MavenRepository) map[string]struct{} {
+	coordinates := cfg.MavenExcludedArtifacts()
+	if len(coordinates) == 0 {
+		return cfg.ExcludedArtifacts()
+	}
+
+	excluded := make(map[string]struct{}, len(cfg.ExcludedArtifacts())+2*len(coordinates))
+	for artifact := range cfg.ExcludedArtifacts() {
+		excluded[artifact] = struct{}{}
+	}
+	for _, coordinate := range coordinates {
+		excluded[coordinate] = struct{}{}
+		excluded[maven.ArtifactLabel(repository.Name, coordinate).String()] = struct{}{}
+	}
+	return excluded
+}
+
+// Resolve a star import to all targets in the rule index providing the package.
+// Returns nil if the package is not in the index and must be resolved as a normal import.
+func (kt *kotlinLang) resolveStarImport(
+	c *config.Config,
+	ix *resolve.RuleIndex,
+	impt ImportStatement,
+	from label.Label,
+) []label.Label {
+	// Gazelle overrides
+	if override, ok := resolve.FindRuleWithOverride(c, impt.ImportSpec, LanguageName); ok {
+		return []label.Label{override}
+	}
+
+	// Kotlin rules, falling back to rules indexed by the java extension
+	var matches []resolve.FindResult
+	for _, lang := range indexedLanguages {
+		if matches = ix.FindRulesByImportWithConfig(c, resolve.ImportSpec{Lang: lang, Imp: impt.Imp}, lang); len(matches) > 0 {
+			break
+		}
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+
+	providers := make([]label.Label, 0, len(matches))
+	for _, match := range matches {
+		// Prevent from adding itself as a dependency.
+		if !match.IsSelfImport(from) {
+			providers = append(providers, match.Label)
+		}
+	}
+
+	return providers
+}
+
+// Resolve the import spec using gazelle overrides and the rule index.
+func (kt *kotlinLang) resolveIndexedImport(
+	c *config.Config,
+	ix *resolve.RuleIndex,
+	imptSpec resolve.ImportSpec,
+	impt ImportStatement,
+	from label.Label,
+) (ResolutionType, *label.Label, error) {
 	// Gazelle overrides
 	// TODO: generalize into gazelle/common
 	if override, ok := resolve.FindRuleWithOverride(c, imptSpec, LanguageName); ok {
+		kt.traceStep("resolve directive %s %s: %s", imptSpec.Lang, imptSpec.Imp, override.String())
 		return Resolution_Label, &override, nil
 	}
 
+	// The index only matches rules indexed by the language of the spec.
 	// TODO: generalize into gazelle/common
-	if matches := ix.FindRulesByImportWithConfig(c, imptSpec, LanguageName); len(matches) > 0 {
+	if matches := ix.FindRulesByImportWithConfig(c, imptSpec, imptSpec.Lang); len(matches) > 0 {
 		filteredMatches := make([]label.Label, 0, len(matches))
+		invisibleMatches := make([]label.Label, 0)
 		for _, match := range matches {
 			// Prevent from adding itself as a dependency.
-			if !match.IsSelfImport(from) {
-				filteredMatches = append(filteredMatches, match.Label)
+			if match.IsSelfImport(from) {
+				continue
+			}
+
+			// Bazel rejects dependencies upon targets not visible from the importing target.
+			if !kt.isVisible(c, match.Label, from) {
+				BazelLog.Debugf("%q providing %q is not visible from %q", match.Label.String(), imptSpec.Imp, from.String())
+				invisibleMatches = append(invisibleMatches, match.Label)
+				continue
 			}
+
+			filteredMatches = append(filteredMatches, match.Label)
+		}
+
+		// Only provided by targets that are not visible
+		if len(filteredMatches) == 0 && len(invisibleMatches) == len(matches) {
+			return Resolution_Error, nil, fmt.Errorf(
+				"Import %q from %q is only provided by targets not visible from %q (%s)"+
+					" - the visibility of the targets must include the importing target",
+				imptSpec.Imp, impt.SourcePath, label.New("", from.Pkg, from.Name).String(), labelListString(toMainRepoLabels(c, invisibleMatches)))
+		}
+
+		kt.traceStep("%s rules providing %s: %s, %d not visible", imptSpec.Lang, imptSpec.Imp, targetListFromResults(matches), len(invisibleMatches))
+
+		// Multiple results, prefer the closest to the importing target
+		if len(filteredMatches) > 1 {
+			cfg := c.Exts[LanguageName].(kotlinconfig.Configs)[from.Pkg]
+			filteredMatches = rankProviders(c, cfg, filteredMatches, from)
 		}
 
 		// Too many results, don't know which is correct
 		if len(filteredMatches) > 1 {
 			return Resolution_Error, nil, fmt.Errorf(
 				"Import %q from %q resolved to multiple targets (%s)"+
-					" - this must be fixed using the \"gazelle:resolve\" directive",
-				impt.Imp, impt.SourcePath, targetListFromResults(matches))
+					" - this must be fixed using the \"gazelle:resolve\" or \"gazelle:kotlin_prefer_provider\" directive",
+				imptSpec.Imp, impt.SourcePath, labelListString(toMainRepoLabels(c, filteredMatches)))
 		}
 
 		// The matches were self imports, no dependency is needed
@@ -176,26 +930,60 @@ func (kt *kotlinLang) resolveImport(
 		return Resolution_Label, &match, nil
 	}
 
-	// Native kotlin imports
-	if IsNativeImport(impt.Imp) {
-		return Resolution_NativeKotlin, nil, nil
-	}
+	kt.traceStep("%s rules providing %s: not found", imptSpec.Lang, imptSpec.Imp)
 
-	jvm_import := jvm_types.NewPackageName(impt.Imp)
+	return Resolution_NotFound, nil, nil
+}
 
-	cfgs := c.Exts[LanguageName].(kotlinconfig.Configs)
-	cfg, _ := cfgs[from.Pkg]
+// Rank the targets providing an import, returning the best ranked targets: targets in the
+// same package as the importing target, then in the nearest ancestor package, then the
+// first of the targets preferred by the kotlin_prefer_provider directive. Multiple
+// targets are returned if tied.
+func rankProviders(c *config.Config, cfg *kotlinconfig.KotlinConfig, providers []label.Label, from label.Label) []label.Label {
+	best, bestRank := make([]label.Label, 0, len(providers)), -1
+	for _, provider := range providers {
+		rank := providerDistanceRank(toMainRepoLabel(c, provider), toMainRepoLabel(c, from))
+		if rank > bestRank {
+			best, bestRank = best[:0], rank
+		}
+		if rank == bestRank {
+			best = append(best, provider)
+		}
+	}
 
-	// Maven imports
-	if mavenResolver := kt.mavenResolver; mavenResolver != nil {
-		if l, mavenError := (*mavenResolver).Resolve(jvm_import, cfg.ExcludedArtifacts(), cfg.MavenRepositoryName()); mavenError == nil {
-			return Resolution_Label, &l, nil
-		} else {
-			BazelLog.Debugf("Maven resolution error: %v", mavenError)
+	if len(best) > 1 {
+		for _, preferred := range cfg.PreferredProviders() {
+			for _, provider := range best {
+				if toMainRepoLabel(c, provider) == preferred {
+					return []label.Label{provider}
+				}
+			}
 		}
 	}
 
-	return Resolution_NotFound, nil, nil
+	return best
+}
+
+// The rank of a provider by its distance to the importing target: the highest for the
+// same package, the number of package segments for ancestor packages and 0 otherwise.
+func providerDistanceRank(provider, from label.Label) int {
+	if provider.Repo != from.Repo {
+		return 0
+	}
+
+	if provider.Pkg == from.Pkg {
+		return math.MaxInt
+	}
+
+	if provider.Pkg == "" {
+		return 1
+	}
+
+	if strings.HasPrefix(from.Pkg, provider.Pkg+"/") {
+		return strings.Count(provider.Pkg, "/") + 2
+	}
+
+	return 0
 }
 
 // targetListFromResults returns a string with the human-readable list of
@@ -208,3 +996,39 @@ func targetListFromResults(results []resolve.FindResult) string {
 	}
 	return strings.Join(list, ", ")
 }
+
+// labelListString returns a string with the human-readable list of labels.
+func labelListString(labels []label.Label) string {
+	list := make([]string, len(labels))
+	for i, l := range labels {
+		list[i] = l.String()
+	}
+	return strings.Join(list, ", ")
+}
+
+// A target with imports failing to resolve.
+type resolutionErrors struct {
+	from label.Label
+	errs []error
+}
+
+// Record the errors of the imports of a target failing to resolve, reported once all rules are resolved.
+func (kt *kotlinLang) recordResolutionErrors(from label.Label, errs []error) {
+	kt.resolutionErrors = append(kt.resolutionErrors, resolutionErrors{from: from, errs: errs})
+}
+
+// Print the errors of all targets with imports failing to resolve, grouped by target.
+func (kt *kotlinLang) printResolutionErrors() {
+	count := 0
+	for _, r := range kt.resolutionErrors {
+		count += len(r.errs)
+	}
+
+	fmt.Fprintf(os.Stderr, "Failed to resolve kotlin dependencies: %d import(s) of %d target(s) failed to resolve\n", count, len(kt.resolutionErrors))
+	for _, r := range kt.resolutionErrors {
+		fmt.Fprintf(os.Stderr, "%s\n", label.New("", r.from.Pkg, r.from.Name).String())
+		for _, err := range r.errs {
+			fmt.Fprintf(os.Stderr, "\t%v\n", err)
+		}
+	}
+}
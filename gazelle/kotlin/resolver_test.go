@@ -0,0 +1,136 @@
+package gazelle
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/repo"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+// A resolver indexing java_library rules by package similar to the rules_jvm java extension.
+type fakeJavaResolver struct{}
+
+func (*fakeJavaResolver) Name() string { return JavaLanguageName }
+
+func (*fakeJavaResolver) Imports(c *config.Config, r *rule.Rule, f *rule.File) []resolve.ImportSpec {
+	specs := make([]resolve.ImportSpec, 0)
+	for _, pkg := range r.AttrStrings("packages") {
+		specs = append(specs, resolve.ImportSpec{Lang: JavaLanguageName, Imp: pkg})
+	}
+	return specs
+}
+
+func (*fakeJavaResolver) Embeds(r *rule.Rule, from label.Label) []label.Label { return nil }
+
+func (*fakeJavaResolver) Resolve(c *config.Config, ix *resolve.RuleIndex, rc *repo.RemoteCache, r *rule.Rule, imports interface{}, from label.Label) {
+}
+
+func TestResolveJavaRules(t *testing.T) {
+	c := config.New()
+	(&resolve.Configurer{}).RegisterFlags(flag.NewFlagSet("test", flag.ContinueOnError), "update", c)
+	kt := NewLanguage().(*kotlinLang)
+	kt.Configure(c, "", nil)
+	kt.Configure(c, "app", nil)
+
+	java := &fakeJavaResolver{}
+	ix := resolve.NewRuleIndex(func(r *rule.Rule, pkgRel string) resolve.Resolver {
+		if r.Kind() == "java_library" {
+			return java
+		}
+		return kt
+	})
+
+	javaLib := rule.NewRule("java_library", "util")
+	javaLib.SetAttr("packages", []string{"com.example.util"})
+	ix.AddRule(c, javaLib, rule.EmptyFile("java/BUILD", "java"))
+	ix.Finish()
+
+	target := NewKotlinLibTarget()
+	target.Imports.Add(ImportStatement{
+		ImportSpec: resolve.ImportSpec{Lang: LanguageName, Imp: "com.example.util"},
+		Symbol:     "com.example.util.Strings",
+		SourcePath: "app.kt",
+	})
+
+	deps, _, errs := kt.resolveImports(c, ix, target.Imports, label.New("", "app", "app"))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	expected := []label.Label{label.New("", "java", "util")}
+	if actual := deps.Labels(); len(actual) != 1 || actual[0] != expected[0] {
+		t.Errorf("deps...\nactual:  %v;\nexpected: %v", actual, expected)
+	}
+}
+
+func TestToApparentRepoLabel(t *testing.T) {
+	c := config.New()
+	c.ModuleToApparentName = func(module string) string {
+		if module == "maven_deps" {
+			return "mvn"
+		}
+		return ""
+	}
+
+	tests := []struct {
+		label    label.Label
+		expected label.Label
+	}{
+		{label.New("maven", "", "junit_junit"), label.New("maven", "", "junit_junit")},
+		{label.New("rules_jvm_external~~maven~maven", "", "junit_junit"), label.New("maven", "", "junit_junit")},
+		{label.New("rules_jvm_external++maven+maven", "", "junit_junit"), label.New("maven", "", "junit_junit")},
+		{label.New("maven_deps", "", "junit_junit"), label.New("mvn", "", "junit_junit")},
+		{label.New("", "lib", "lib"), label.New("", "lib", "lib")},
+	}
+
+	for _, test := range tests {
+		if actual := toApparentRepoLabel(c, test.label); actual != test.expected {
+			t.Errorf("toApparentRepoLabel(%s)...\nactual:  %s;\nexpected: %s", test.label, actual, test.expected)
+		}
+	}
+}
+
+func TestResolveAssociatedLibraries(t *testing.T) {
+	c := config.New()
+	(&resolve.Configurer{}).RegisterFlags(flag.NewFlagSet("test", flag.ContinueOnError), "update", c)
+	kt := NewLanguage().(*kotlinLang)
+	kt.Configure(c, "", nil)
+	kt.Configure(c, "app", nil)
+
+	ix := resolve.NewRuleIndex(func(r *rule.Rule, pkgRel string) resolve.Resolver {
+		return kt
+	})
+
+	implTarget := NewKotlinLibTarget()
+	implTarget.Packages.Add("com.example.impl")
+	impl := rule.NewRule(KtJvmLibrary, "impl")
+	impl.SetPrivateAttr(packagesKey, implTarget)
+
+	facade := rule.NewRule(KtJvmLibrary, "facade")
+	facade.SetAttr("associates", []string{":impl"})
+	facade.SetPrivateAttr(packagesKey, NewKotlinLibTarget())
+
+	ix.AddRule(c, impl, rule.EmptyFile("lib/BUILD", "lib"))
+	ix.AddRule(c, facade, rule.EmptyFile("lib/BUILD", "lib"))
+	ix.Finish()
+
+	target := NewKotlinLibTarget()
+	target.Imports.Add(ImportStatement{
+		ImportSpec: resolve.ImportSpec{Lang: LanguageName, Imp: "com.example.impl"},
+		SourcePath: "app.kt",
+	})
+
+	deps, _, errs := kt.resolveImports(c, ix, target.Imports, label.New("", "app", "app"))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	expected := []label.Label{label.New("", "lib", "facade")}
+	if actual := deps.Labels(); len(actual) != 1 || actual[0] != expected[0] {
+		t.Errorf("deps...\nactual:  %v;\nexpected: %v", actual, expected)
+	}
+}
@@ -0,0 +1,64 @@
+package gazelle
+
+import (
+	"os"
+	"path"
+	"sort"
+
+	"aspect.build/cli/gazelle/kotlin/kotlinconfig"
+	"aspect.build/cli/gazelle/kotlin/parser"
+	BazelLog "aspect.build/cli/pkg/logger"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/language"
+)
+
+// The directories, relative to a package, containing the provider configuration
+// files of services provided by the package.
+var serviceConfigDirs = []string{
+	"META-INF/services",
+	"resources/META-INF/services",
+	"src/main/resources/META-INF/services",
+}
+
+// The service providers required by services loaded within the parsed file.
+func serviceProvidersForFile(cfg *kotlinconfig.KotlinConfig, p *parser.ParseResult) []label.Label {
+	providers := make([]label.Label, 0)
+
+	if len(p.ServiceLoaderTypes) == 0 {
+		return providers
+	}
+
+	for _, service := range cfg.Services() {
+		if hasAnyReference(p.ServiceLoaderTypes, p, []string{service}) {
+			providers = append(providers, cfg.ServiceProviders(service)...)
+		}
+	}
+
+	return providers
+}
+
+// The fully qualified names of the services declared in the META-INF/services
+// provider configuration files of the package, sorted by name.
+func declaredServices(args language.GenerateArgs) []string {
+	services := make([]string, 0)
+
+	for _, dir := range serviceConfigDirs {
+		entries, err := os.ReadDir(path.Join(args.Config.RepoRoot, args.Rel, dir))
+		if err != nil {
+			if !os.IsNotExist(err) {
+				BazelLog.Infof("failed to read service configuration directory %q: %v", path.Join(args.Rel, dir), err)
+			}
+			continue
+		}
+
+		for _, e := range entries {
+			if !e.IsDir() {
+				services = append(services, e.Name())
+			}
+		}
+	}
+
+	sort.Strings(services)
+
+	return services
+}
@@ -0,0 +1,127 @@
+// Package symbolindex reads and writes the index of the packages and top-level
+// symbols of a source tree written by the resolvedump tool, which the kotlin
+// extension resolves imports against with the kotlin_symbol_index directive.
+package symbolindex
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+)
+
+// The version of the format of the index files.
+const Version = 1
+
+// The package and top-level symbols declared by a source file.
+type File struct {
+	// The path of the file relative to the repository root
+	Path string `json:"path"`
+
+	Package string `json:"package"`
+
+	// The label of the target providing the file such as `//src/foo`
+	Label string `json:"label"`
+
+	// The names of the top-level classes, interfaces, objects and type aliases
+	Classes []string `json:"classes,omitempty"`
+
+	// The names of the top-level functions and properties
+	Functions []string `json:"functions,omitempty"`
+}
+
+// An index of the symbols of the files of a source tree.
+type Index struct {
+	Version int    `json:"version"`
+	Files   []File `json:"files"`
+
+	// The labels declaring each package and fully qualified symbol, built when loaded
+	packages map[string][]label.Label
+	symbols  map[string][]label.Label
+}
+
+// New returns an index of the files.
+func New(files []File) (*Index, error) {
+	idx := &Index{Version: Version, Files: files}
+	if err := idx.build(); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// Load reads an index file written by Write.
+func Load(indexPath string) (*Index, error) {
+	content, err := os.ReadFile(indexPath)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := &Index{}
+	if err := json.Unmarshal(content, idx); err != nil {
+		return nil, fmt.Errorf("failed to parse %q: %w", indexPath, err)
+	}
+	if idx.Version != Version {
+		return nil, fmt.Errorf("unsupported version %d of %q, expected %d", idx.Version, indexPath, Version)
+	}
+
+	if err := idx.build(); err != nil {
+		return nil, fmt.Errorf("invalid %q: %w", indexPath, err)
+	}
+	return idx, nil
+}
+
+// Write the index as JSON.
+func (idx *Index) Write(indexPath string) error {
+	content, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(indexPath, append(content, '\n'), 0644)
+}
+
+func (idx *Index) build() error {
+	idx.packages = make(map[string][]label.Label)
+	idx.symbols = make(map[string][]label.Label)
+
+	for _, f := range idx.Files {
+		l, err := label.Parse(f.Label)
+		if err != nil {
+			return fmt.Errorf("invalid label of %q: %w", f.Path, err)
+		}
+
+		idx.packages[f.Package] = addLabel(idx.packages[f.Package], l)
+		for _, names := range [][]string{f.Classes, f.Functions} {
+			for _, name := range names {
+				symbol := name
+				if f.Package != "" {
+					symbol = f.Package + "." + name
+				}
+				idx.symbols[symbol] = addLabel(idx.symbols[symbol], l)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ResolveSymbol returns the labels of the targets declaring a fully qualified symbol such
+// as `com.foo.Bar`, or nil if not declared by the indexed files.
+func (idx *Index) ResolveSymbol(symbol string) []label.Label {
+	return idx.symbols[symbol]
+}
+
+// ResolvePackage returns the labels of the targets declaring a package, or nil if not
+// declared by the indexed files.
+func (idx *Index) ResolvePackage(pkg string) []label.Label {
+	return idx.packages[pkg]
+}
+
+func addLabel(labels []label.Label, l label.Label) []label.Label {
+	for _, existing := range labels {
+		if existing.Equal(l) {
+			return labels
+		}
+	}
+	return append(labels, l)
+}
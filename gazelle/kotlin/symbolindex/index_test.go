@@ -0,0 +1,57 @@
+package symbolindex
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/bazelbuild/bazel-gazelle/label"
+)
+
+func TestIndex(t *testing.T) {
+	idx, err := New([]File{
+		{Path: "a/A.kt", Package: "com.foo", Label: "//a", Classes: []string{"A"}, Functions: []string{"helper"}},
+		{Path: "a/B.kt", Package: "com.foo", Label: "//a", Classes: []string{"B"}},
+		{Path: "b/C.java", Package: "com.foo", Label: "//b", Classes: []string{"C"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	indexPath := filepath.Join(t.TempDir(), "index.json")
+	if err := idx.Write(indexPath); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertLabels(t, "com.foo.A", loaded.ResolveSymbol("com.foo.A"), "//a")
+	assertLabels(t, "com.foo.helper", loaded.ResolveSymbol("com.foo.helper"), "//a")
+	assertLabels(t, "com.foo.C", loaded.ResolveSymbol("com.foo.C"), "//b")
+	assertLabels(t, "com.foo.D", loaded.ResolveSymbol("com.foo.D"))
+	assertLabels(t, "com.foo", loaded.ResolvePackage("com.foo"), "//a", "//b")
+	assertLabels(t, "com.bar", loaded.ResolvePackage("com.bar"))
+}
+
+func TestInvalidIndex(t *testing.T) {
+	if _, err := New([]File{{Path: "a/A.kt", Package: "com.foo", Label: "//a:b:c"}}); err == nil {
+		t.Errorf("Expected an error for an invalid label")
+	}
+}
+
+func assertLabels(t *testing.T, what string, actual []label.Label, expected ...string) {
+	t.Helper()
+
+	if len(actual) != len(expected) {
+		t.Errorf("%s: expected %v, got %v", what, expected, actual)
+		return
+	}
+	for i := range expected {
+		if actual[i].String() != expected[i] {
+			t.Errorf("%s: expected %v, got %v", what, expected, actual)
+			return
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package gazelle
+
+import (
+	"fmt"
+	"strings"
+
+	gazelle "aspect.build/cli/gazelle/common"
+	"aspect.build/cli/gazelle/kotlin/kotlinconfig"
+	BazelLog "aspect.build/cli/pkg/logger"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	bzl "github.com/bazelbuild/buildtools/build"
+)
+
+// A rule generated in this run, used to determine which libraries are only
+// depended upon by tests. Rules of other languages have no target, only the
+// labels they depend upon.
+type generatedRule struct {
+	label  label.Label
+	isTest bool
+	target *KotlinTarget
+	deps   []label.Label
+}
+
+// The attributes of rules of other languages depending on libraries.
+var otherRuleDepsAttrs = []string{"deps", "runtime_deps", "exports"}
+
+// Record the kotlin rules of the language.GenerateResult.
+func (kt *kotlinLang) recordGeneratedRules(args language.GenerateArgs, result *language.GenerateResult) {
+	for i, r := range result.Gen {
+		var target *KotlinTarget
+		switch t := result.Imports[i].(type) {
+		case *KotlinLibTarget:
+			target = &t.KotlinTarget
+		case *KotlinBinTarget:
+			target = &t.KotlinTarget
+		case *KotlinTestTarget:
+			target = &t.KotlinTarget
+		default:
+			continue
+		}
+
+		from := label.New("", args.Rel, r.Name())
+
+		// Rules of test sources and testonly directories are only used by tests
+		cfg := args.Config.Exts[LanguageName].(kotlinconfig.Configs)[args.Rel]
+		isTest := r.Kind() == KtJvmTest || cfg.Testonly()
+
+		kt.generatedRules = append(kt.generatedRules, generatedRule{
+			label:  from,
+			isTest: isTest,
+			target: target,
+		})
+
+		// The `testonly` of libraries is replaced when resolving if testonly inference is enabled
+		if isTest || (isTestonly(r) && !cfg.InferTestonly()) {
+			kt.recordTestonlyRule(from)
+		}
+	}
+}
+
+// Record a testonly rule of the visited packages.
+func (kt *kotlinLang) recordTestonlyRule(l label.Label) {
+	if kt.testonlyRules == nil {
+		kt.testonlyRules = make(map[label.Label]bool)
+	}
+	kt.testonlyRules[l] = true
+}
+
+// Record the rules of other languages in the package, existing or generated in this
+// run, so libraries depended upon by those rules are never considered test only.
+func (kt *kotlinLang) recordOtherRules(args language.GenerateArgs) {
+	rules := make([]*rule.Rule, 0, len(args.OtherGen))
+	if args.File != nil {
+		rules = append(rules, args.File.Rules...)
+	}
+	rules = append(rules, args.OtherGen...)
+
+	for _, r := range rules {
+		if isKotlinRuleKind(args, r.Kind()) {
+			continue
+		}
+
+		from := label.New("", args.Rel, r.Name())
+		isTest := strings.HasSuffix(r.Kind(), "_test") || isTestonly(r)
+
+		if isTest {
+			kt.recordTestonlyRule(from)
+		}
+
+		deps := make([]label.Label, 0)
+		for _, attr := range otherRuleDepsAttrs {
+			for _, dep := range r.AttrStrings(attr) {
+				if l, err := label.Parse(dep); err == nil {
+					deps = append(deps, l.Abs("", args.Rel))
+				}
+			}
+		}
+
+		if len(deps) > 0 {
+			kt.generatedRules = append(kt.generatedRules, generatedRule{
+				label:  from,
+				isTest: isTest,
+				deps:   deps,
+			})
+		}
+	}
+}
+
+// Whether the rule sets `testonly = True`.
+func isTestonly(r *rule.Rule) bool {
+	testonly, isIdent := r.Attr("testonly").(*bzl.Ident)
+	return isIdent && testonly.Name == "True"
+}
+
+// Preserve the `testonly` of an existing library, replaced when resolving if
+// testonly inference is enabled.
+func setExistingTestonlyAttr(r *rule.Rule, args language.GenerateArgs) {
+	existing := gazelle.GetFileRuleByName(args, r.Name())
+	if existing != nil && existing.Kind() == gazelle.MapKind(args, r.Kind()) && existing.Attr("testonly") != nil {
+		r.SetAttr("testonly", existing.Attr("testonly"))
+	}
+}
+
+// Set `testonly` on a library depended upon only by tests, or remove it otherwise.
+func (kt *kotlinLang) resolveTestonly(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, from label.Label) {
+	cfg := c.Exts[LanguageName].(kotlinconfig.Configs)[from.Pkg]
+	if !cfg.InferTestonly() || cfg.Testonly() {
+		return
+	}
+
+	// The reverse dependencies of libraries are unknown, preserve the existing `testonly`
+	if kt.partialRun {
+		BazelLog.Debugf("not inferring testonly of %q in a run of only some packages", from.String())
+		return
+	}
+
+	if kt.testonlyLibraries == nil {
+		kt.testonlyLibraries = kt.collectTestonlyLibraries(c, ix)
+	}
+
+	if kt.testonlyLibraries[toMainRepoLabel(c, from)] {
+		r.SetAttr("testonly", true)
+	} else {
+		r.DelAttr("testonly")
+	}
+}
+
+// Report the dependencies of a rule that is not test only upon testonly targets, which bazel rejects.
+func (kt *kotlinLang) validateTestonlyDeps(c *config.Config, ix *resolve.RuleIndex, r *rule.Rule, deps *gazelle.LabelSet, from label.Label) {
+	cfg := c.Exts[LanguageName].(kotlinconfig.Configs)[from.Pkg]
+	if cfg.ValidateTestonly() == kotlinconfig.ValidationOff || r.Kind() == KtJvmTest || isTestonly(r) {
+		return
+	}
+
+	for _, dep := range deps.Labels() {
+		dep = toMainRepoLabel(c, dep.Abs(from.Repo, from.Pkg))
+		if !kt.isTestonlyLabel(c, ix, dep) {
+			continue
+		}
+
+		if cfg.ValidateTestonly() == kotlinconfig.ValidationError {
+			kt.testonlyViolations++
+		}
+
+		fmt.Printf("Testonly error: %q is not testonly but depends upon the testonly target %q. Possible solutions:\n"+
+			"\t1. Set `testonly = True` on %[1]q if it is only used by tests\n"+
+			"\t2. Move the code required by %[1]q out of %[2]q\n",
+			label.New("", from.Pkg, from.Name).String(), dep.String(),
+		)
+	}
+}
+
+// Whether the label is a testonly rule of the visited packages, including libraries
+// inferred to be testonly.
+func (kt *kotlinLang) isTestonlyLabel(c *config.Config, ix *resolve.RuleIndex, l label.Label) bool {
+	if kt.testonlyRules[l] {
+		return true
+	}
+
+	if l.Repo != "" || kt.partialRun {
+		return false
+	}
+
+	cfg, visited := c.Exts[LanguageName].(kotlinconfig.Configs)[l.Pkg]
+	if !visited || !cfg.InferTestonly() {
+		return false
+	}
+
+	if kt.testonlyLibraries == nil {
+		kt.testonlyLibraries = kt.collectTestonlyLibraries(c, ix)
+	}
+
+	return kt.testonlyLibraries[l]
+}
+
+// Determine which generated rules are only depended upon by tests. Rules generated
+// in this run and rules of other languages in the visited packages are considered,
+// testonly inference is skipped when only some packages are visited.
+func (kt *kotlinLang) collectTestonlyLibraries(c *config.Config, ix *resolve.RuleIndex) map[label.Label]bool {
+	usedByTests := make(map[label.Label]bool)
+	usedByOthers := make(map[label.Label]bool)
+
+	for _, g := range kt.generatedRules {
+		used := usedByOthers
+		if g.isTest {
+			used = usedByTests
+		}
+
+		// Rules of other languages
+		if g.target == nil {
+			for _, dep := range g.deps {
+				used[toMainRepoLabel(c, dep)] = true
+			}
+			continue
+		}
+
+		for _, impt := range g.target.Imports.Values() {
+			for _, dep := range kt.resolveImportLabels(c, ix, impt.(ImportStatement), g.label) {
+				dep = toMainRepoLabel(c, dep.Abs("", g.label.Pkg))
+
+				// The library tested by a test of the same package is not test code.
+				if g.isTest && dep.Repo == "" && dep.Pkg == g.label.Pkg {
+					continue
+				}
+
+				used[dep] = true
+			}
+		}
+
+		for _, dep := range g.target.RuntimeDeps.Values() {
+			used[toMainRepoLabel(c, dep.(label.Label).Abs("", g.label.Pkg))] = true
+		}
+	}
+
+	testonly := make(map[label.Label]bool)
+	for l := range usedByTests {
+		if !usedByOthers[l] {
+			BazelLog.Debugf("library %s is only used by tests", l)
+			testonly[l] = true
+		}
+	}
+
+	return testonly
+}
+
+// The labels an import resolves to, ignoring resolution errors.
+func (kt *kotlinLang) resolveImportLabels(c *config.Config, ix *resolve.RuleIndex, impt ImportStatement, from label.Label) []label.Label {
+	if impt.IsStar {
+		if deps := kt.resolveStarImport(c, ix, impt, from); deps != nil {
+			return deps
+		}
+	}
+
+	resolutionType, dep, err := kt.resolveImport(c, ix, impt, from)
+	if err == nil && resolutionType == Resolution_Label && dep != nil {
+		return []label.Label{*dep}
+	}
+
+	return nil
+}
+
+// Remove the repository of labels within the main repository.
+func toMainRepoLabel(c *config.Config, l label.Label) label.Label {
+	if l.Repo == c.RepoName {
+		l.Repo = ""
+	}
+	return l
+}
+
+// Remove the repository of the labels within the main repository.
+func toMainRepoLabels(c *config.Config, labels []label.Label) []label.Label {
+	converted := make([]label.Label, len(labels))
+	for i, l := range labels {
+		converted[i] = toMainRepoLabel(c, l)
+	}
+	return converted
+}
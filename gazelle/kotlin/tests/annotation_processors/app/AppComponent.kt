@@ -0,0 +1,6 @@
+package com.example.app
+
+import dagger.Component
+
+@Component
+interface AppComponent
@@ -0,0 +1,6 @@
+package com.example.other
+
+import dagger.Component
+
+@Component
+interface OtherComponent
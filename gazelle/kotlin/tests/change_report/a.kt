@@ -0,0 +1,5 @@
+package test.report
+
+import test.report.dep.D
+
+class A(val d: D)
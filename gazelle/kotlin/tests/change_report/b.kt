@@ -0,0 +1,3 @@
+package test.report
+
+class B
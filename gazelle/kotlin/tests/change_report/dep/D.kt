@@ -0,0 +1,3 @@
+package test.report.dep
+
+class D
@@ -0,0 +1,5 @@
+package test.report
+
+fun main() {
+    println(A())
+}
@@ -0,0 +1,8 @@
+package test.app
+
+import test.shared.A
+import test.shared.helper
+
+class App(val a: A) {
+    val b = helper()
+}
@@ -0,0 +1,5 @@
+package test.shared
+
+class B
+
+fun helper() = B()
@@ -0,0 +1,3 @@
+package test.cleanup;
+
+class Java {}
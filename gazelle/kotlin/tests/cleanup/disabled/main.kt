@@ -0,0 +1,3 @@
+package test.cleanup
+
+fun main() {}
@@ -0,0 +1,3 @@
+package com.example.annotations
+
+annotation class Generated
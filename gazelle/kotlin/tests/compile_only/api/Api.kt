@@ -0,0 +1,5 @@
+package com.example.api
+
+interface Api {
+    fun call(): String
+}
@@ -0,0 +1,9 @@
+package com.example.app
+
+import com.example.annotations.Generated
+import com.example.api.Api
+
+@Generated
+internal class App(private val api: Api) {
+    fun run() = api.call()
+}
@@ -0,0 +1,6 @@
+package test.plugins
+
+import kotlinx.serialization.Serializable
+
+@Serializable
+data class Data(val a: Int, val b: String)
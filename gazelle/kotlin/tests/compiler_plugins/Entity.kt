@@ -0,0 +1,5 @@
+package test.plugins
+
+// The noarg plugin has no kt_compiler_plugin configured
+@javax.persistence.Entity
+class Person(val name: String)
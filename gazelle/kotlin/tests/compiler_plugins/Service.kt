@@ -0,0 +1,8 @@
+package test.plugins
+
+import org.springframework.stereotype.Service
+
+@Service
+class GreetingService {
+    fun greet() = "Hello"
+}
@@ -0,0 +1,8 @@
+package test.plugins.custom
+
+import kotlinx.parcelize.Parcelize
+import kotlinx.serialization.Serializable
+
+@Parcelize
+@Serializable
+data class Point(val x: Int, val y: Int)
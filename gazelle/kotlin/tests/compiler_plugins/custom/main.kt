@@ -0,0 +1,8 @@
+package test.plugins.custom
+
+@kotlinx.serialization.Serializable
+data class Args(val name: String)
+
+fun main() {
+    println(Args("x"))
+}
@@ -0,0 +1,6 @@
+package test.plugins.relative
+
+import kotlinx.parcelize.Parcelize
+
+@Parcelize
+data class Shape(val sides: Int)
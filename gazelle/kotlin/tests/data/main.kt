@@ -0,0 +1,3 @@
+package test.data
+
+fun main() {}
@@ -0,0 +1,13 @@
+package test.api
+
+import test.model.User
+import test.util.Cache
+import test.util.format
+
+class UserApi {
+    private val cache = Cache()
+
+    fun find(name: String): User = User(format(name))
+
+    internal fun cached(): Cache = cache
+}
@@ -0,0 +1,3 @@
+package test.model
+
+class User(val name: String)
@@ -0,0 +1,5 @@
+package test.util
+
+fun format(s: String): String = s.trim()
+
+class Cache
@@ -0,0 +1,3 @@
+package com.example.lib
+
+fun greet(name: String): String = "Hello $name"
@@ -0,0 +1,5 @@
+package com.example.lib
+
+class LibTest {
+    fun testGreet() = greet("test")
+}
@@ -0,0 +1,3 @@
+package com.example.plain
+
+fun plain() = 1
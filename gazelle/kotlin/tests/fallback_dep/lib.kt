@@ -0,0 +1,5 @@
+package test
+
+import com.legacy.Widget
+
+fun render(w: Widget) = w.toString()
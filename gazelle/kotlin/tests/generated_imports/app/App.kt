@@ -0,0 +1,8 @@
+package com.example.app
+
+import com.example.app.DaggerAppComponent
+import com.example.model.User_Factory
+
+internal fun start() {
+    DaggerAppComponent.create().inject(User_Factory.create())
+}
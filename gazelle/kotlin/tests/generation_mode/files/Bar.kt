@@ -0,0 +1,3 @@
+package test.files
+
+class Bar(val foo: Foo)
@@ -0,0 +1,3 @@
+package test.files
+
+class Foo
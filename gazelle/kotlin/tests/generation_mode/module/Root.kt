@@ -0,0 +1,5 @@
+package test.module
+
+import test.module.sub.Sub
+
+class Root(val sub: Sub)
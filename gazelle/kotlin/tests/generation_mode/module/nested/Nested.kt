@@ -0,0 +1,5 @@
+package test.module.nested
+
+import test.module.Root
+
+class Nested(val root: Root)
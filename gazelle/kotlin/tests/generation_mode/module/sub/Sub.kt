@@ -0,0 +1,3 @@
+package test.module.sub
+
+class Sub
@@ -0,0 +1,3 @@
+package test.alpha
+
+class Alpha
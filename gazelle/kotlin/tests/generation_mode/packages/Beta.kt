@@ -0,0 +1,5 @@
+package test.beta
+
+import test.alpha.Alpha
+
+class Beta(val alpha: Alpha)
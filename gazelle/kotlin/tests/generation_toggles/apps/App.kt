@@ -0,0 +1,7 @@
+package test.apps
+
+import test.tools.Util
+
+fun main() {
+    println(Util())
+}
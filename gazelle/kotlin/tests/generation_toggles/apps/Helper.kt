@@ -0,0 +1,3 @@
+package test.apps
+
+class Helper
@@ -0,0 +1,5 @@
+package test.tools
+
+fun main() {
+    println(Util())
+}
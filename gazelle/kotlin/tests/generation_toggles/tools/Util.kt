@@ -0,0 +1,3 @@
+package test.tools
+
+class Util
@@ -0,0 +1,9 @@
+package test.mixed;
+
+import test.claimed.Util;
+
+public class Helper {
+    public static void greet(String name) {
+        System.out.println("Hello " + name);
+    }
+}
@@ -0,0 +1,3 @@
+package test.claimed
+
+class Other
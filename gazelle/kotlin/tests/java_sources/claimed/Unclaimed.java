@@ -0,0 +1,3 @@
+package test.claimed;
+
+public class Unclaimed {}
@@ -0,0 +1,7 @@
+package test.claimed;
+
+public class Util {
+    public static String name() {
+        return "world";
+    }
+}
@@ -0,0 +1,7 @@
+package test.mixed
+
+import test.claimed.Util
+
+fun hello() {
+    Helper.greet(Util.name())
+}
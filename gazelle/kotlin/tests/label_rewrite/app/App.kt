@@ -0,0 +1,5 @@
+package com.example.app
+
+import com.example.strings.join
+
+internal fun start() = join("a", "b")
@@ -0,0 +1,3 @@
+package com.example.strings
+
+fun join(vararg parts: String) = parts.joinToString(",")
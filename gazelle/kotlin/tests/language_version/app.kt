@@ -0,0 +1,5 @@
+package test
+
+fun main() {
+    println(Lib())
+}
@@ -0,0 +1,3 @@
+package test.lint.detekt
+
+class Detekt
@@ -0,0 +1,3 @@
+package test.lint
+
+fun main() {}
@@ -0,0 +1,7 @@
+@file:JvmName("Launcher")
+
+package com.example
+
+fun main() {
+    println("app")
+}
@@ -0,0 +1,6 @@
+package com.example
+
+import com.google.common.base.Strings
+import com.google.common.collect.ImmutableList
+
+internal fun names(): List<String> = ImmutableList.of(Strings.nullToEmpty(null))
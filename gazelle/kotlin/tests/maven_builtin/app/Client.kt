@@ -0,0 +1,5 @@
+package com.example.app
+
+import okhttp3.OkHttpClient
+
+fun client(): OkHttpClient = OkHttpClient()
@@ -0,0 +1,5 @@
+package com.example
+
+import com.google.common.collect.ImmutableList
+
+fun names(): List<String> = ImmutableList.of("a", "b")
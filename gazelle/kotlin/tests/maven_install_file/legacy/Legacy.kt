@@ -0,0 +1,5 @@
+package com.example.legacy
+
+import org.apache.commons.lang3.StringUtils
+
+fun blank(s: String): Boolean = StringUtils.isBlank(s)
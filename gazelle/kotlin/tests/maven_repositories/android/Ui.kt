@@ -0,0 +1,6 @@
+package com.example.android
+
+import androidx.annotation.NonNull
+import com.google.common.base.Strings
+
+fun title(@NonNull name: String): String = Strings.nullToEmpty(name)
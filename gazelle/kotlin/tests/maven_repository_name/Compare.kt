@@ -0,0 +1,5 @@
+package com.example
+
+import com.google.common.primitives.Ints
+
+fun compare(a: Int, b: Int): Int = Ints.compare(a, b)
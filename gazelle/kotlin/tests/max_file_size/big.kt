@@ -0,0 +1,10 @@
+package foo
+
+// Not parsed as it exceeds the -kotlin-max-file-size, the unknown import is not resolved.
+import com.unknown.Generated
+
+class Big {
+    val generated: Generated = Generated()
+
+    fun describe(): String = "A kotlin file larger than the maximum file size"
+}
@@ -0,0 +1,3 @@
+package test.app
+
+internal fun greeting() = "hello"
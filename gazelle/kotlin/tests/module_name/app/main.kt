@@ -0,0 +1,5 @@
+package test.app
+
+fun main() {
+    println(greeting())
+}
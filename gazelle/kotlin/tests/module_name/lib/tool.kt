@@ -0,0 +1,5 @@
+package test.lib
+
+fun main() {
+    println(Lib())
+}
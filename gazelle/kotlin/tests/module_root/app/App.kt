@@ -0,0 +1,5 @@
+package test.app
+
+import test.app.util.Strings
+
+class App(val s: Strings)
@@ -0,0 +1,3 @@
+package test.app.util
+
+class Strings
@@ -0,0 +1,3 @@
+package test.lib.core
+
+class Core
@@ -0,0 +1,3 @@
+package test.collision
+
+class A
@@ -0,0 +1,7 @@
+package com.example
+
+import com.mycorp.provided.Platform
+import kotlinx.coroutines.flow.flowOf
+import kotlinx.serialization.Serializable
+
+internal fun platforms() = flowOf(Platform.current())
@@ -0,0 +1,6 @@
+package com.example.app
+
+import com.example.core.a.b.Deep
+import com.example.core.internal.Impl
+
+internal fun start() = listOf(Impl(), Deep())
@@ -0,0 +1,3 @@
+package com.example.core
+
+class Core
@@ -0,0 +1,5 @@
+package com.example.app
+
+import com.example.shared.Foo
+
+internal fun start() = Foo()
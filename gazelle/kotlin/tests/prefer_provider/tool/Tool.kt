@@ -0,0 +1,5 @@
+package com.example.tool
+
+import com.example.shared.Foo
+
+internal fun run() = Foo()
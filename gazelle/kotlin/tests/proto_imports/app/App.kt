@@ -0,0 +1,9 @@
+package com.example.app
+
+import com.example.common.Common.Money
+import com.example.events.Event
+import com.example.users.UserService.User
+
+fun describe(user: User, event: Event): String = "${user.name} ${event.id}"
+
+private fun format(money: Money): String = "${money.cents}"
@@ -0,0 +1,6 @@
+package com.example.app
+
+internal fun start(state: State) {
+    println(com.example.util.Strings.join("a", "b"))
+    println(state.Idle)
+}
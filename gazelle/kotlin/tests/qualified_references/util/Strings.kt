@@ -0,0 +1,5 @@
+package com.example.util
+
+object Strings {
+    fun join(vararg parts: String) = parts.joinToString(",")
+}
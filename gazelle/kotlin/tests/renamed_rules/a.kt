@@ -0,0 +1,3 @@
+package test.renamed
+
+class A
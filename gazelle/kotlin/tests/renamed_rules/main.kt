@@ -0,0 +1,5 @@
+package test.renamed
+
+fun main() {
+    println(A())
+}
@@ -0,0 +1,5 @@
+package test.renamed.sub
+
+import test.renamed.A
+
+class Use(val a: A)
@@ -0,0 +1,7 @@
+package com.example.app
+
+import com.mycorp.legacy.auth.Session
+import com.mycorp.legacy.billing.Invoice
+import com.mycorp.legacy.util.Strings
+
+internal fun describe(session: Session, invoice: Invoice): String = Strings.join(session, invoice)
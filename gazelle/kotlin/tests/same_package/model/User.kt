@@ -0,0 +1,5 @@
+package com.example.shared
+
+data class User(val name: String)
+
+fun anonymous(): User = User("anonymous")
@@ -0,0 +1,11 @@
+package com.example.shared
+
+class UserService {
+    private val users = mutableListOf<User>()
+
+    fun add(name: String) {
+        users.add(User(name))
+    }
+
+    fun fallback() = anonymous()
+}
@@ -0,0 +1,5 @@
+package test.codec
+
+interface Codec {
+    fun encode(value: Any): String
+}
@@ -0,0 +1,3 @@
+package test.db
+
+class Database(val url: String)
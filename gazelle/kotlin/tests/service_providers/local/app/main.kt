@@ -0,0 +1,8 @@
+package test.local.app
+
+import java.util.ServiceLoader
+import test.local.Plugin
+
+fun main() {
+    ServiceLoader.load(Plugin::class.java).forEach { println(it) }
+}
@@ -0,0 +1,8 @@
+package test.app
+
+import java.util.ServiceLoader
+import test.codec.Codec
+
+fun main() {
+    ServiceLoader.load(Codec::class.java).forEach { println(it) }
+}
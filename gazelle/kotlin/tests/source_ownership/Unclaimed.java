@@ -0,0 +1,3 @@
+package test.ownership;
+
+class Unclaimed {}
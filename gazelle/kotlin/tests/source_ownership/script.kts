@@ -0,0 +1 @@
+println("hello")
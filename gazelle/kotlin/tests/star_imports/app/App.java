@@ -0,0 +1,8 @@
+package test.app;
+
+import test.shared.*;
+
+class App {
+    A a;
+    B b;
+}
@@ -0,0 +1,3 @@
+package test.shared
+
+class B
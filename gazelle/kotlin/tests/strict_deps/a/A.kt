@@ -0,0 +1,3 @@
+package test.a
+
+class A
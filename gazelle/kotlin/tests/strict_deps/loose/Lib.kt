@@ -0,0 +1,5 @@
+package test.loose
+
+import test.a.A
+
+fun a() = A()
@@ -0,0 +1,5 @@
+package test.loose
+
+fun main() {
+    a()
+}
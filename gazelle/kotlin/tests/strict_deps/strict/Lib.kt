@@ -0,0 +1,5 @@
+package test.strict
+
+import test.a.A
+
+fun a() = A()
@@ -0,0 +1,5 @@
+package test.strict
+
+fun main() {
+    a()
+}
@@ -0,0 +1,7 @@
+package com.example.app
+
+import com.mycorp.legacy.auth.login
+import com.mycorp.legacy.billing.Invoice
+import com.mycorp.legacy.util.*
+
+internal fun describe(invoice: Invoice): String = login(join(invoice))
@@ -0,0 +1,3 @@
+package test.tags.cleared
+
+class C
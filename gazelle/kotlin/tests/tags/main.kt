@@ -0,0 +1,3 @@
+package test.tags
+
+fun main() {}
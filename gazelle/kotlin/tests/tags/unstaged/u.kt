@@ -0,0 +1,3 @@
+package test.tags.unstaged
+
+class U
@@ -0,0 +1,8 @@
+package test.specs
+
+class CalculatorSpec {
+    fun testCalculator() {
+        Calculator()
+        FixtureTest()
+    }
+}
@@ -0,0 +1,3 @@
+package test.specs
+
+class FixtureTest
@@ -0,0 +1,9 @@
+package test.specs.unit
+
+import test.specs.Calculator
+
+class UnitSpec {
+    fun testUnit() {
+        Calculator()
+    }
+}
@@ -0,0 +1,11 @@
+package test.unit
+
+import kotlin.test.assertEquals
+import org.junit.jupiter.api.Test
+
+class MathTest {
+    @Test
+    fun testAdd() {
+        assertEquals(2, 1 + 1)
+    }
+}
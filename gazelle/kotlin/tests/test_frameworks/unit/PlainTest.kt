@@ -0,0 +1,6 @@
+package test.unit
+
+class PlainTest {
+    fun testNothing() {
+    }
+}
@@ -0,0 +1,9 @@
+package test.it
+
+import test.it.support.Fixtures
+
+class Scenario {
+    fun testApp() {
+        Fixtures(test.lib.Lib())
+    }
+}
@@ -0,0 +1,5 @@
+package test.it.support
+
+import test.lib.Lib
+
+class Fixtures(val lib: Lib)
@@ -0,0 +1,5 @@
+package test.app
+
+import test.util.Util
+
+class App(val util: Util)
@@ -0,0 +1,13 @@
+package test.app
+
+import test.fixtures.Fixtures
+import test.shared.Shared
+import test.util.Util
+
+class AppTest {
+    fun testApp() {
+        App(Util())
+        Fixtures()
+        Shared()
+    }
+}
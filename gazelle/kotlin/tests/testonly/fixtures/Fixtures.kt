@@ -0,0 +1,3 @@
+package test.fixtures
+
+class Fixtures
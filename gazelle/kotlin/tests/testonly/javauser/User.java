@@ -0,0 +1,3 @@
+package test.javauser;
+
+class User {}
@@ -0,0 +1,3 @@
+package test.stale
+
+class Stale
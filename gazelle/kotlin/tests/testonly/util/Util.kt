@@ -0,0 +1,3 @@
+package test.util
+
+class Util
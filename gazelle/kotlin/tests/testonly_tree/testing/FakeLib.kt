@@ -0,0 +1,5 @@
+package test.testing
+
+import test.lib.Lib
+
+class FakeLib(val lib: Lib)
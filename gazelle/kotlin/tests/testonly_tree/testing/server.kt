@@ -0,0 +1,5 @@
+package test.testing
+
+fun main() {
+    println("fake server")
+}
@@ -0,0 +1,5 @@
+package com.example.app
+
+import com.example.fakes.FakeClock
+
+internal fun start() = FakeClock().now()
@@ -0,0 +1,5 @@
+package com.example.fakes
+
+class FakeClock {
+    fun now(): Long = 0
+}
@@ -0,0 +1,5 @@
+package test.ignored
+
+import also.missing.Other
+
+fun f() = Other()
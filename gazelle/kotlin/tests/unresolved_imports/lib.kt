@@ -0,0 +1,5 @@
+package test
+
+import missing.Thing
+
+class Lib(val t: Thing)
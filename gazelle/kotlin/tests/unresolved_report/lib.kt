@@ -0,0 +1,6 @@
+package test
+
+import missing.Thing
+import test.sub.Helper
+
+fun f(t: Thing) = Helper()
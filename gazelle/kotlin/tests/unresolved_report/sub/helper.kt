@@ -0,0 +1,5 @@
+package test.sub
+
+import org.unknown.Util
+
+class Helper : Util()
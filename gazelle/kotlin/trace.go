@@ -0,0 +1,87 @@
+package gazelle
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"aspect.build/cli/gazelle/kotlin/kotlinconfig"
+	"github.com/bazelbuild/bazel-gazelle/label"
+)
+
+// The resolution steps of an import, printed if the kotlin_resolution_trace
+// directive is enabled for the importing target.
+type resolutionTrace struct {
+	impt ImportStatement
+	from label.Label
+
+	start time.Time
+	last  time.Time
+	steps []string
+}
+
+// Start tracing the resolution of an import if enabled for the importing target.
+func (kt *kotlinLang) startTrace(cfg *kotlinconfig.KotlinConfig, impt ImportStatement, from label.Label) {
+	if !cfg.ResolutionTrace() {
+		kt.trace = nil
+		return
+	}
+
+	now := time.Now()
+	kt.trace = &resolutionTrace{
+		impt:  impt,
+		from:  from,
+		start: now,
+		last:  now,
+		steps: make([]string, 0),
+	}
+}
+
+// Record a resolution step of the traced import, with the time since the previous step.
+func (kt *kotlinLang) traceStep(format string, args ...interface{}) {
+	if kt.trace == nil {
+		return
+	}
+
+	now := time.Now()
+	kt.trace.steps = append(kt.trace.steps, fmt.Sprintf("%s (%s)", fmt.Sprintf(format, args...), now.Sub(kt.trace.last)))
+	kt.trace.last = now
+}
+
+// Print the resolution steps and result of the traced import.
+func (kt *kotlinLang) endTrace(resolutionType ResolutionType, deps []label.Label, err error) {
+	if kt.trace == nil {
+		return
+	}
+
+	trace := kt.trace
+	kt.trace = nil
+
+	imp := trace.impt.Imp
+	if trace.impt.Symbol != "" {
+		imp = trace.impt.Symbol
+	}
+
+	var result string
+	switch {
+	case err != nil:
+		result = fmt.Sprintf("failed: %v", err)
+	case len(deps) > 0:
+		result = fmt.Sprintf("resolved to %s", labelListString(deps))
+	case resolutionType == Resolution_NativeKotlin:
+		result = "provided by the standard libraries"
+	case resolutionType == Resolution_NotFound:
+		result = "not found"
+	default:
+		result = "no dependency required"
+	}
+
+	var s strings.Builder
+	fmt.Fprintf(&s, "Resolution trace of %q from %q in %s:\n", imp, trace.impt.SourcePath, label.New("", trace.from.Pkg, trace.from.Name).String())
+	for _, step := range trace.steps {
+		fmt.Fprintf(&s, "\t%s\n", step)
+	}
+	fmt.Fprintf(&s, "\t%s in %s\n", result, time.Since(trace.start))
+
+	fmt.Print(s.String())
+}
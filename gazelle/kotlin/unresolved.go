@@ -0,0 +1,134 @@
+package gazelle
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"sort"
+
+	BazelLog "aspect.build/cli/pkg/logger"
+	"github.com/bazelbuild/bazel-gazelle/label"
+)
+
+// UnresolvedImport is an import Resolve could not resolve to a dependency.
+type UnresolvedImport struct {
+	// The imported package, and the imported symbol if not a package or star import.
+	Import string `json:"import"`
+	Symbol string `json:"symbol,omitempty"`
+
+	// The workspace relative path of the importing file and the target it belongs to.
+	File   string `json:"file"`
+	Target string `json:"target"`
+
+	// The resolution steps attempted, in order.
+	Attempted []string `json:"attempted"`
+
+	// The labels of the maven artifacts all providing the import, if ambiguous.
+	Candidates []string `json:"candidates,omitempty"`
+
+	// The kotlin_fallback_dep the import was resolved to, if any.
+	Fallback string `json:"fallback,omitempty"`
+}
+
+// Record an import that could not be resolved for the unresolved import report.
+func (kt *kotlinLang) recordUnresolvedImport(impt ImportStatement, from label.Label, candidates []label.Label) {
+	if kt.unresolvedReportFile == "" {
+		return
+	}
+
+	var candidateLabels []string
+	for _, candidate := range candidates {
+		candidateLabels = append(candidateLabels, candidate.String())
+	}
+
+	kt.unresolved = append(kt.unresolved, UnresolvedImport{
+		Import:     impt.Imp,
+		Symbol:     impt.Symbol,
+		File:       path.Join(from.Pkg, impt.SourcePath),
+		Target:     label.New("", from.Pkg, from.Name).String(),
+		Attempted:  kt.resolutionSteps(impt),
+		Candidates: candidateLabels,
+	})
+}
+
+// Record an import not found and resolved to the kotlin_fallback_dep, listed in the
+// unresolved import report.
+func (kt *kotlinLang) recordFallbackImport(impt ImportStatement, from label.Label, fallback label.Label) {
+	kt.fallbackImports++
+
+	if kt.unresolvedReportFile == "" {
+		return
+	}
+
+	kt.unresolved = append(kt.unresolved, UnresolvedImport{
+		Import:    impt.Imp,
+		Symbol:    impt.Symbol,
+		File:      path.Join(from.Pkg, impt.SourcePath),
+		Target:    label.New("", from.Pkg, from.Name).String(),
+		Attempted: kt.resolutionSteps(impt),
+		Fallback:  fallback.String(),
+	})
+}
+
+// The steps attempted by resolveImport and resolveStarImport, in order.
+func (kt *kotlinLang) resolutionSteps(impt ImportStatement) []string {
+	steps := make([]string, 0)
+
+	if impt.IsStar {
+		steps = append(steps, "resolve directive "+LanguageName+" "+impt.Imp)
+		for _, lang := range indexedLanguages {
+			steps = append(steps, lang+" rules providing "+impt.Imp+".*")
+		}
+	}
+
+	for _, lang := range indexedLanguages {
+		if impt.Symbol != "" {
+			steps = append(steps, "resolve directive "+lang+" "+impt.Symbol, lang+" rules providing "+impt.Symbol)
+		}
+		steps = append(steps, "resolve directive "+lang+" "+impt.Imp, lang+" rules providing "+impt.Imp)
+	}
+
+	if len(kt.symbolIndexes) > 0 {
+		steps = append(steps, "symbol index files")
+	}
+
+	steps = append(steps, "kotlin and java standard libraries")
+
+	if len(kt.mavenResolvers) > 0 {
+		steps = append(steps, "maven artifacts")
+	}
+
+	return steps
+}
+
+// Write the unresolved imports to the unresolved import report file, if enabled.
+func (kt *kotlinLang) writeUnresolvedReport() {
+	if kt.unresolvedReportFile == "" {
+		return
+	}
+
+	sort.SliceStable(kt.unresolved, func(i, j int) bool {
+		a, b := kt.unresolved[i], kt.unresolved[j]
+		if a.Target != b.Target {
+			return a.Target < b.Target
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Import < b.Import
+	})
+
+	unresolved := kt.unresolved
+	if unresolved == nil {
+		unresolved = []UnresolvedImport{}
+	}
+
+	content, err := json.MarshalIndent(unresolved, "", "  ")
+	if err != nil {
+		BazelLog.Fatalf("failed to encode kotlin unresolved import report: %v", err)
+	}
+
+	if err := os.WriteFile(kt.unresolvedReportFile, append(content, '\n'), 0644); err != nil {
+		BazelLog.Fatalf("failed to write kotlin unresolved import report %q: %v", kt.unresolvedReportFile, err)
+	}
+}
@@ -0,0 +1,108 @@
+package gazelle
+
+import (
+	"strings"
+
+	BazelLog "aspect.build/cli/pkg/logger"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/label"
+	"github.com/bazelbuild/bazel-gazelle/language"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+// The visibility of all targets.
+const publicVisibility = "//visibility:public"
+
+// The visibility of targets only visible within their package.
+const privateVisibility = "//visibility:private"
+
+// Record the `visibility` of the existing and generated rules of the package and the
+// `default_visibility` of the package, to skip providers not visible from importing targets.
+func (kt *kotlinLang) recordVisibility(args language.GenerateArgs) {
+	if args.File == nil {
+		return
+	}
+
+	if kt.ruleVisibility == nil {
+		kt.ruleVisibility = make(map[label.Label][]label.Label)
+		kt.defaultVisibility = make(map[string][]label.Label)
+	}
+
+	rules := make([]*rule.Rule, 0, len(args.File.Rules)+len(args.OtherGen))
+	rules = append(rules, args.File.Rules...)
+	rules = append(rules, args.OtherGen...)
+
+	for _, r := range rules {
+		if r.Kind() == "package" {
+			if visibility := parseVisibility(r.AttrStrings("default_visibility"), args.Rel); len(visibility) > 0 {
+				kt.defaultVisibility[args.Rel] = visibility
+			}
+			continue
+		}
+
+		if visibility := parseVisibility(r.AttrStrings("visibility"), args.Rel); len(visibility) > 0 {
+			kt.ruleVisibility[label.New("", args.Rel, r.Name())] = visibility
+		}
+	}
+}
+
+// Parse the labels of a visibility attribute relative to the package.
+func parseVisibility(visibility []string, rel string) []label.Label {
+	labels := make([]label.Label, 0, len(visibility))
+	for _, v := range visibility {
+		l, err := label.Parse(v)
+		if err != nil {
+			BazelLog.Warnf("Failed to parse visibility %q in %q: %v", v, rel, err)
+			continue
+		}
+		labels = append(labels, l.Abs("", rel))
+	}
+	return labels
+}
+
+// Whether the target is visible from the importing target. Targets of other repositories
+// and targets without a known `visibility` or `default_visibility`, such as rules
+// generated without a visibility, are considered visible.
+func (kt *kotlinLang) isVisible(c *config.Config, target, from label.Label) bool {
+	target, from = toMainRepoLabel(c, target), toMainRepoLabel(c, from)
+	if target.Repo != "" || target.Pkg == from.Pkg {
+		return true
+	}
+
+	visibility, known := kt.ruleVisibility[target]
+	if !known {
+		visibility, known = kt.defaultVisibility[target.Pkg]
+	}
+	if !known {
+		return true
+	}
+
+	for _, v := range visibility {
+		if isVisibleTo(v, from) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Whether a visibility label grants visibility to the importing target of another package.
+// Package groups can not be evaluated and are considered visible.
+func isVisibleTo(v, from label.Label) bool {
+	if v.Repo != "" {
+		return false
+	}
+
+	switch {
+	case v.String() == publicVisibility:
+		return true
+	case v.String() == privateVisibility:
+		return false
+	case v.Name == "__pkg__":
+		return from.Pkg == v.Pkg
+	case v.Name == "__subpackages__":
+		return v.Pkg == "" || from.Pkg == v.Pkg || strings.HasPrefix(from.Pkg, v.Pkg+"/")
+	}
+
+	return true
+}
@@ -0,0 +1,134 @@
+package gazelle
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	BazelLog "aspect.build/cli/pkg/logger"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+	"sigs.k8s.io/yaml"
+)
+
+// The workspace configuration files of the extension, relative to the repository root,
+// read unless another file is specified with the -kotlin-config flag. JSON files are
+// valid YAML files.
+var workspaceConfigFiles = []string{
+	".aspect/gazelle-kotlin.yaml",
+	".aspect/gazelle-kotlin.yml",
+	".aspect/gazelle-kotlin.json",
+}
+
+// The environment variable of kotlin directives applied to all packages, separated by
+// newlines or semicolons, such as "kotlin_generate_tests enabled;kotlin_lint enabled".
+const DirectivesEnv = "GAZELLE_KOTLIN_DIRECTIVES"
+
+// The directives of the repeatable -kotlin-directive flag.
+type directiveFlags []string
+
+var _ flag.Value = (*directiveFlags)(nil)
+
+func (f *directiveFlags) String() string {
+	return strings.Join(*f, ";")
+}
+
+func (f *directiveFlags) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// The workspace configuration of the extension, providing defaults for the kotlin
+// directives of all packages without editing the root BUILD file.
+type workspaceConfig struct {
+	// The directives such as "kotlin_generate_tests enabled", applied in order before
+	// the directives of the root BUILD file.
+	Directives []string `json:"directives"`
+}
+
+// The directives of the workspace configuration file, if any.
+func (kt *kotlinLang) workspaceDirectives(repoRoot string) []rule.Directive {
+	file := kt.configFile
+	if file == "" {
+		for _, f := range workspaceConfigFiles {
+			if _, err := os.Stat(filepath.Join(repoRoot, f)); err == nil {
+				file = f
+				break
+			}
+		}
+	}
+	if file == "" {
+		return nil
+	}
+
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(repoRoot, file)
+	}
+
+	directives, err := readWorkspaceDirectives(file, kt.KnownDirectives())
+	if err != nil {
+		BazelLog.Fatalf("invalid kotlin configuration file %q: %v", file, err)
+	}
+
+	BazelLog.Debugf("Read %d kotlin directives from %q", len(directives), file)
+
+	return directives
+}
+
+// Read the directives of a workspace configuration file, which must be known directives.
+func readWorkspaceDirectives(file string, knownDirectives []string) ([]rule.Directive, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg workspaceConfig
+	if err := yaml.UnmarshalStrict(content, &cfg); err != nil {
+		return nil, err
+	}
+
+	return parseDirectives(cfg.Directives, knownDirectives)
+}
+
+// Read the directives of the environment variable and the -kotlin-directive flags, applied
+// in this order after the directives of the workspace configuration file.
+func readFlagDirectives(env string, flags []string, knownDirectives []string) ([]rule.Directive, error) {
+	envDirectives := strings.FieldsFunc(env, func(r rune) bool {
+		return r == '\n' || r == ';'
+	})
+
+	directives, err := parseDirectives(envDirectives, knownDirectives)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for environment variable %s: %w", DirectivesEnv, err)
+	}
+
+	flagDirectives, err := parseDirectives(flags, knownDirectives)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for flag -kotlin-directive: %w", err)
+	}
+
+	return append(directives, flagDirectives...), nil
+}
+
+// Parse directives such as "kotlin_generate_tests enabled", which must be known directives.
+// Blank directives are ignored.
+func parseDirectives(lines []string, knownDirectives []string) ([]rule.Directive, error) {
+	directives := make([]rule.Directive, 0, len(lines))
+	for _, d := range lines {
+		d = strings.TrimSpace(d)
+		if d == "" {
+			continue
+		}
+
+		key, value, _ := strings.Cut(d, " ")
+		if !slices.Contains(knownDirectives, key) {
+			return nil, fmt.Errorf("unknown directive %q", key)
+		}
+
+		directives = append(directives, rule.Directive{Key: key, Value: strings.TrimSpace(value)})
+	}
+
+	return directives, nil
+}
@@ -0,0 +1,91 @@
+package gazelle
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"aspect.build/cli/gazelle/kotlin/kotlinconfig"
+	"github.com/bazelbuild/bazel-gazelle/config"
+	"github.com/bazelbuild/bazel-gazelle/resolve"
+	"github.com/bazelbuild/bazel-gazelle/rule"
+)
+
+func TestWorkspaceDirectives(t *testing.T) {
+	repoRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repoRoot, ".aspect"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	configFile := filepath.Join(repoRoot, ".aspect/gazelle-kotlin.yaml")
+	if err := os.WriteFile(configFile, []byte(`directives:
+  - kotlin_generate_tests enabled
+  - kotlin_tags manual
+  - kotlin_strict_deps enabled
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := config.New()
+	c.RepoRoot = repoRoot
+	(&resolve.Configurer{}).RegisterFlags(flag.NewFlagSet("test", flag.ContinueOnError), "update", c)
+	kt := NewLanguage().(*kotlinLang)
+
+	// Directives of the root BUILD file take precedence.
+	root, err := rule.LoadData(filepath.Join(repoRoot, "BUILD.bazel"), "", []byte("# gazelle:kotlin_tags other\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	kt.Configure(c, "", root)
+	kt.Configure(c, "app", nil)
+
+	cfg := c.Exts[LanguageName].(kotlinconfig.Configs)["app"]
+	if !cfg.GenerateTests() || !cfg.StrictDeps() {
+		t.Errorf("expected the directives of the configuration file to be inherited")
+	}
+	if tags := cfg.Tags(); len(tags) != 1 || tags[0] != "other" {
+		t.Errorf("expected the tags of the root BUILD file, got %v", tags)
+	}
+}
+
+func TestReadWorkspaceDirectives(t *testing.T) {
+	dir := t.TempDir()
+
+	jsonFile := filepath.Join(dir, "gazelle-kotlin.json")
+	if err := os.WriteFile(jsonFile, []byte(`{"directives": ["kotlin_generate_tests enabled", "kotlin_extra_deps kt_jvm_test //testing"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	directives, err := readWorkspaceDirectives(jsonFile, []string{"kotlin_generate_tests", "kotlin_extra_deps"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(directives) != 2 || directives[1].Key != "kotlin_extra_deps" || directives[1].Value != "kt_jvm_test //testing" {
+		t.Errorf("unexpected directives: %v", directives)
+	}
+
+	unknownFile := filepath.Join(dir, "unknown.yaml")
+	if err := os.WriteFile(unknownFile, []byte("directives:\n  - kotlin_unknown enabled\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readWorkspaceDirectives(unknownFile, []string{"kotlin_generate_tests"}); err == nil {
+		t.Errorf("expected an error for unknown directives")
+	}
+}
+
+func TestReadFlagDirectives(t *testing.T) {
+	known := []string{"kotlin_generate_tests", "kotlin_tags"}
+
+	directives, err := readFlagDirectives("kotlin_generate_tests enabled; kotlin_tags manual\n", []string{"kotlin_tags other"}, known)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(directives) != 3 || directives[1].Value != "manual" || directives[2].Value != "other" {
+		t.Errorf("expected the directives of the environment before the flags, got %v", directives)
+	}
+
+	if _, err := readFlagDirectives("", []string{"kotlin_unknown enabled"}, known); err == nil {
+		t.Errorf("expected an error for unknown directives")
+	}
+}
@@ -62,6 +62,12 @@ type updateConfig struct {
 // NOTE: addition aspect-cli "changed" result
 var resultFileChanged = fmt.Errorf("changes detected")
 
+// NOTE: addition aspect-cli languages failing the run once the BUILD files are emitted
+type failingLanguage interface {
+	// Failure returns the error failing the run, nil if the run succeeded.
+	Failure() error
+}
+
 type emitFunc func(c *config.Config, f *rule.File) error
 
 var modeFromName = map[string]emitFunc{
@@ -540,6 +546,15 @@ func runFixUpdate(wd string, languages []language.Language, cmd command, args []
 		}
 	}
 
+	// NOTE: aspect-cli languages failing the run, such as on imports failing to resolve
+	for _, lang := range languages {
+		if failing, ok := lang.(failingLanguage); ok {
+			if err := failing.Failure(); err != nil {
+				return &stats, err
+			}
+		}
+	}
+
 	return &stats, exit
 }
 